@@ -3,8 +3,8 @@ package main
 import (
 	"fmt"
 	"log"
-	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"time"
@@ -16,25 +16,41 @@ import (
 	"github.com/go-redis/redis/v8"
 
 	"apron.network/gateway/internal/handlers"
+	"apron.network/gateway/internal/handlers/breaker"
+	"apron.network/gateway/internal/handlers/health"
 	"apron.network/gateway/internal/handlers/ratelimiter"
 	"apron.network/gateway/internal/models"
 )
 
 var (
-	corsAllowHeaders     = "authorization, Origin, X-Requested-With, Content-Type, Accept"
+	corsAllowHeaders     = "authorization, Origin, X-Requested-With, Content-Type, Accept, X-Apron-Key"
 	corsAllowMethods     = "HEAD,GET,POST,PUT,DELETE,OPTIONS"
 	corsAllowOrigin      = "*"
 	corsAllowCredentials = "true"
 )
 
+// healthCheckSyncInterval is how often services are reloaded to update targets watched by health checker
+const healthCheckSyncInterval = 30 * time.Second
+
 func CORS(next fasthttp.RequestHandler) fasthttp.RequestHandler {
 	return func(ctx *fasthttp.RequestCtx) {
-		ctx.Response.Header.Set("Access-Control-Allow-Credentials", corsAllowCredentials)
 		ctx.Response.Header.Set("Access-Control-Allow-Headers", corsAllowHeaders)
 		ctx.Response.Header.Set("Access-Control-Allow-Methods", corsAllowMethods)
 		ctx.Response.Header.Set("Access-Control-Allow-Origin", corsAllowOrigin)
 
 		next(ctx)
+
+		// Only origins allowed by the api key are echoed if restricted, and credentials are only allowed with
+		// the echoed origin. Requests not tied to a key, such as preflight, never get wildcard origin with credentials.
+		if allowOrigin, restricted := handlers.CorsAllowOrigin(ctx); restricted {
+			ctx.Response.Header.Add("Vary", "Origin")
+			if allowOrigin == "" {
+				ctx.Response.Header.Del("Access-Control-Allow-Origin")
+			} else {
+				ctx.Response.Header.Set("Access-Control-Allow-Origin", allowOrigin)
+				ctx.Response.Header.Set("Access-Control-Allow-Credentials", corsAllowCredentials)
+			}
+		}
 	}
 }
 
@@ -46,15 +62,19 @@ func getEnv(key, defaultVal string) string {
 	}
 }
 
-func startAdminService(addr string, wg *sync.WaitGroup, redisClient *redis.Client, manager models.AggregatedAccessRecordManager, accessLogChannel chan string) {
+func startAdminService(addr string, wg *sync.WaitGroup, storageManager models.StorageManager, invalidationBus models.InvalidationBus, manager *models.AggregatedAccessRecordManager, accessLogChannel chan string, expiredKeyRetention time.Duration, bootstrapToken string, jwksDir string, healthChecker *health.Checker, breakers *breaker.Breakers) {
 	h := handlers.ManagerHandler{
 		AggrAccessRecordManager: manager,
+		InvalidationBus:         invalidationBus,
 		AccessLogChannel:        accessLogChannel,
+		BootstrapToken:          bootstrapToken,
+		JwksDir:                 jwksDir,
+		HealthChecker:           healthChecker,
+		Breakers:                breakers,
 	}
-	h.InitStore(&models.StorageManager{
-		RedisClient: redisClient,
-	})
+	h.InitStore(storageManager)
 	h.InitRouters()
+	h.StartExpiredKeySweeper(time.Hour, expiredKeyRetention)
 
 	if err := fasthttp.ListenAndServe(addr, CORS(h.Handler())); err != nil {
 		log.Fatalf("Error in Admin service: %s", err)
@@ -63,34 +83,37 @@ func startAdminService(addr string, wg *sync.WaitGroup, redisClient *redis.Clien
 	wg.Done()
 }
 
-func startProxyService(addr string, wg *sync.WaitGroup, redisClient *redis.Client, manager models.AggregatedAccessRecordManager, accessLogChannel chan string) {
-	// TODO: Load from configurations
-	t := http.DefaultTransport.(*http.Transport).Clone()
-	t.MaxIdleConns = 100
-	t.MaxConnsPerHost = 100
-	t.MaxIdleConnsPerHost = 100
-
+func startProxyService(addr string, wg *sync.WaitGroup, storageManager models.StorageManager, invalidationBus models.InvalidationBus, manager *models.AggregatedAccessRecordManager, accessLogChannel chan string, accessTokenTTL time.Duration, healthChecker *health.Checker, breakers *breaker.Breakers) {
 	proxyLogger := internal.GatewayLogger{
 		LogFile: "logs/proxy_log.txt",
 	}
 	proxyLogger.Init()
 
+	recordCache := models.NewRecordCache(storageManager)
+	invalidationBus.Subscribe(recordCache.Invalidate)
+
+	// Share rate limit records across gateway nodes if redis is used
+	rateLimiterOptions := ratelimiter.Options{
+		Max:      60,
+		Duration: time.Minute,
+	}
+	if redisStorageManager, ok := storageManager.(*models.RedisStorageManager); ok {
+		rateLimiterOptions.Client = redisStorageManager.RedisClient
+	}
+
 	h := handlers.ProxyHandler{
-		HttpClient: &http.Client{
-			Timeout:   10 * time.Second,
-			Transport: t,
-		},
-		StorageManager: &models.StorageManager{
-			RedisClient: redisClient,
-		},
-		RateLimiter: ratelimiter.New(ratelimiter.Options{
-			Max:      60,
-			Duration: time.Minute,
-		}),
+		StorageManager:          storageManager,
+		RecordCache:             recordCache,
+		RateLimiter:             ratelimiter.New(rateLimiterOptions),
 		Logger:                  &proxyLogger,
 		AggrAccessRecordManager: manager,
 		AccessLogChannel:        accessLogChannel,
+		AccessTokenTTL:          accessTokenTTL,
+		HealthChecker:           healthChecker,
+		Breakers:                breakers,
 	}
+	h.Init()
+	h.StartHealthChecks(healthCheckSyncInterval)
 
 	if err := fasthttp.ListenAndServe(addr, CORS(h.InternalHandler)); err != nil {
 		log.Fatalf("Error in Proxy service: %s", err)
@@ -107,29 +130,78 @@ func main() {
 	proxyPort, err := strconv.ParseInt(getEnv("PROXY_PORT", "8080"), 10, 32)
 	internal.CheckError(err)
 	adminAddrStr := getEnv("ADMIN_ADDR", "127.0.0.1:8082")
+	storageBackend := getEnv("STORAGE_BACKEND", "redis")
 	redisServer := getEnv("REDIS_SERVER", "localhost:6379")
+	boltDbPath := getEnv("BOLT_DB_PATH", "data/gateway.db")
+	expiredKeyRetention, err := time.ParseDuration(getEnv("EXPIRED_KEY_RETENTION", "720h"))
+	internal.CheckError(err)
+	accessTokenTTL, err := time.ParseDuration(getEnv("ACCESS_TOKEN_TTL", "15m"))
+	internal.CheckError(err)
+	adminBootstrapToken := getEnv("ADMIN_BOOTSTRAP_TOKEN", "")
+	jwksDir := getEnv("JWKS_DIR", "")
+	if adminBootstrapToken != "" && len(adminBootstrapToken) < 32 {
+		log.Fatalf("ADMIN_BOOTSTRAP_TOKEN should have at least 32 chars")
+	}
 
 	proxyServerAddr := fmt.Sprintf(":%d", proxyPort)
 
 	fmt.Println("Service info:")
 	fmt.Printf("\tProxy addr: %s\n", proxyServerAddr)
 	fmt.Printf("\tAdmin service addr: %s\n", adminAddrStr)
-	fmt.Printf("\tRedis server: %s\n", redisServer)
+	fmt.Printf("\tStorage backend: %s\n", storageBackend)
+	if adminBootstrapToken == "" {
+		fmt.Println("\tADMIN_BOOTSTRAP_TOKEN not set, admin API only accepts admin tokens saved in storage")
+	}
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     redisServer,
-		Password: "", // no password set
-		DB:       0,  // use default DB
-	})
+	var storageManager models.StorageManager
+	var invalidationBus models.InvalidationBus
+	switch storageBackend {
+	case "redis":
+		fmt.Printf("\tRedis server: %s\n", redisServer)
+		rdb := redis.NewClient(&redis.Options{
+			Addr:     redisServer,
+			Password: "", // no password set
+			DB:       0,  // use default DB
+		})
+		storageManager = models.NewRedisStorageManager(rdb)
+		invalidationBus = models.NewRedisInvalidationBus(rdb)
+	case "memory":
+		storageManager = models.NewMemoryStorageManager()
+		invalidationBus = models.NewLocalInvalidationBus()
+	case "bolt":
+		fmt.Printf("\tBolt db path: %s\n", boltDbPath)
+		err = os.MkdirAll(filepath.Dir(boltDbPath), 0755)
+		internal.CheckError(err)
+		storageManager, err = models.NewBoltStorageManager(boltDbPath)
+		internal.CheckError(err)
+		invalidationBus = models.NewLocalInvalidationBus()
+	default:
+		log.Fatalf("Unsupported storage backend: %s", storageBackend)
+	}
 
-	aggrAccessRecordManager := models.AggregatedAccessRecordManager{}
+	aggrAccessRecordManager := &models.AggregatedAccessRecordManager{}
 	aggrAccessRecordManager.Init()
 
 	accessLogChannel := make(chan string, 4096)
 	defer close(accessLogChannel)
 
-	go startProxyService(proxyServerAddr, wg, rdb, aggrAccessRecordManager, accessLogChannel)
-	go startAdminService(adminAddrStr, wg, rdb, aggrAccessRecordManager, accessLogChannel)
+	// Keys issued by previous versions are migrated before serving, since only hashed keys are accepted
+	migrator := handlers.ManagerHandler{InvalidationBus: invalidationBus}
+	migrator.InitStore(storageManager)
+	migratedKeys, err := migrator.MigrateLegacyApiKeys()
+	if err != nil {
+		log.Fatalf("Failed to migrate legacy api keys: %v", err)
+	}
+	if migratedKeys > 0 {
+		fmt.Printf("\tMigrated %d legacy api keys to hashed keys\n", migratedKeys)
+	}
+
+	// Health and circuit state of upstream targets are shared by proxy and admin service
+	healthChecker := health.New()
+	breakers := breaker.New()
+
+	go startProxyService(proxyServerAddr, wg, storageManager, invalidationBus, aggrAccessRecordManager, accessLogChannel, accessTokenTTL, healthChecker, breakers)
+	go startAdminService(adminAddrStr, wg, storageManager, invalidationBus, aggrAccessRecordManager, accessLogChannel, expiredKeyRetention, adminBootstrapToken, jwksDir, healthChecker, breakers)
 
 	wg.Wait()
 }
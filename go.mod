@@ -3,11 +3,16 @@ module apron.network/gateway
 go 1.14
 
 require (
+	github.com/alicebob/miniredis/v2 v2.30.0
+	github.com/dchest/blake2b v1.0.0
+	github.com/decred/base58 v1.0.3
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1
 	github.com/fasthttp/router v1.3.7
 	github.com/fasthttp/websocket v1.4.3
 	github.com/go-redis/redis/v8 v8.6.0
 	github.com/golang/protobuf v1.4.3
 	github.com/google/uuid v1.2.0
 	github.com/valyala/fasthttp v1.21.0
+	go.etcd.io/bbolt v1.3.6
 	google.golang.org/protobuf v1.25.0
 )
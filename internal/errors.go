@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Error codes responded to client, those values should be kept stable since clients may depend on them
+const (
+	ErrCodeNotFound            = "not_found"
+	ErrCodeUnauthorized        = "unauthorized"
+	ErrCodeKeyExpired          = "key_expired"
+	ErrCodeForbidden           = "forbidden"
+	ErrCodeBadRequest          = "bad_request"
+	ErrCodeRateLimited         = "rate_limited"
+	ErrCodeQuotaExceeded       = "quota_exceeded"
+	ErrCodeConcurrencyLimited  = "concurrency_limited"
+	ErrCodeUpstreamFailure     = "upstream_failure"
+	ErrCodeUpstreamUnavailable = "upstream_unavailable"
+	ErrCodeCircuitOpen         = "circuit_open"
+	ErrCodeUpstreamTimeout     = "upstream_timeout"
+	ErrCodeStorageUnavailable  = "storage_unavailable"
+	ErrCodeInternalError       = "internal_error"
+)
+
+// GatewayError is the error with http status and error code, which will be responded to client as JSON
+type GatewayError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Err     error  `json:"-"`
+}
+
+func (e *GatewayError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *GatewayError) Unwrap() error {
+	return e.Err
+}
+
+// NewGatewayError creates error with status, code and formatted message
+func NewGatewayError(status int, code string, format string, args ...interface{}) *GatewayError {
+	return &GatewayError{
+		Status:  status,
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func NotFoundError(format string, args ...interface{}) *GatewayError {
+	return NewGatewayError(fasthttp.StatusNotFound, ErrCodeNotFound, format, args...)
+}
+
+func UnauthorizedError(format string, args ...interface{}) *GatewayError {
+	return NewGatewayError(fasthttp.StatusUnauthorized, ErrCodeUnauthorized, format, args...)
+}
+
+// KeyExpiredError is responded if api key is valid but expired, so clients can tell it from invalid key
+func KeyExpiredError(format string, args ...interface{}) *GatewayError {
+	return NewGatewayError(fasthttp.StatusUnauthorized, ErrCodeKeyExpired, format, args...)
+}
+
+// ForbiddenError is responded if api key is valid but not allowed to access the requested resource
+func ForbiddenError(format string, args ...interface{}) *GatewayError {
+	return NewGatewayError(fasthttp.StatusForbidden, ErrCodeForbidden, format, args...)
+}
+
+func BadRequestError(format string, args ...interface{}) *GatewayError {
+	return NewGatewayError(fasthttp.StatusBadRequest, ErrCodeBadRequest, format, args...)
+}
+
+func TooManyRequestsError(format string, args ...interface{}) *GatewayError {
+	return NewGatewayError(fasthttp.StatusTooManyRequests, ErrCodeRateLimited, format, args...)
+}
+
+// QuotaExceededError is responded with status 429 or 402 declared in quota policy
+func QuotaExceededError(status int, format string, args ...interface{}) *GatewayError {
+	return NewGatewayError(status, ErrCodeQuotaExceeded, format, args...)
+}
+
+// ConcurrencyLimitedError is responded if max in flight requests reached
+func ConcurrencyLimitedError(format string, args ...interface{}) *GatewayError {
+	return NewGatewayError(fasthttp.StatusServiceUnavailable, ErrCodeConcurrencyLimited, format, args...)
+}
+
+// UpstreamError wraps errors occurred while communicating with services
+func UpstreamError(err error) *GatewayError {
+	e := NewGatewayError(fasthttp.StatusBadGateway, ErrCodeUpstreamFailure, "failed to access upstream service")
+	e.Err = err
+	return e
+}
+
+// UpstreamTimeoutError wraps timeout errors occurred while communicating with services, cause is the timeout exceeded
+func UpstreamTimeoutError(cause string, err error) *GatewayError {
+	e := NewGatewayError(fasthttp.StatusGatewayTimeout, ErrCodeUpstreamTimeout, "upstream service %s timeout", cause)
+	e.Err = err
+	return e
+}
+
+// UpstreamUnavailableError is responded if no upstream target is available for the request
+func UpstreamUnavailableError(format string, args ...interface{}) *GatewayError {
+	return NewGatewayError(fasthttp.StatusServiceUnavailable, ErrCodeUpstreamUnavailable, format, args...)
+}
+
+// CircuitOpenError is responded if circuits of all available upstream targets are open, so the request fails fast
+func CircuitOpenError(format string, args ...interface{}) *GatewayError {
+	return NewGatewayError(fasthttp.StatusServiceUnavailable, ErrCodeCircuitOpen, format, args...)
+}
+
+// StorageUnavailableError wraps errors returned from storage backend
+func StorageUnavailableError(err error) *GatewayError {
+	e := NewGatewayError(fasthttp.StatusServiceUnavailable, ErrCodeStorageUnavailable, "storage is unavailable")
+	e.Err = err
+	return e
+}
+
+func InternalError(err error) *GatewayError {
+	e := NewGatewayError(fasthttp.StatusInternalServerError, ErrCodeInternalError, "internal error")
+	e.Err = err
+	return e
+}
+
+// ToGatewayError converts err to GatewayError, errors without type info are treated as internal error
+func ToGatewayError(err error) *GatewayError {
+	var gatewayErr *GatewayError
+	if errors.As(err, &gatewayErr) {
+		return gatewayErr
+	}
+	return InternalError(err)
+}
+
+// WriteErrorResponse writes err as JSON body with status and code defined in GatewayError
+func WriteErrorResponse(ctx *fasthttp.RequestCtx, err error) {
+	gatewayErr := ToGatewayError(err)
+	respBody, _ := json.Marshal(gatewayErr)
+
+	ctx.ResetBody()
+	ctx.SetStatusCode(gatewayErr.Status)
+	ctx.SetContentType("application/json")
+	ctx.SetBody(respBody)
+}
@@ -37,11 +37,13 @@ func (l *GatewayLogger) Init() {
 	go func() {
 		for msg := range l.msgChannel {
 			fmt.Printf("Received message from channel: %s\n", msg)
+			l.logLock.Lock()
 			bytesWritten, err := l.writer.WriteString(msg)
 			CheckError(err)
 
 			l.writtenSize += bytesWritten
 			l.lastLogTime = time.Now()
+			l.logLock.Unlock()
 
 		}
 	}()
@@ -50,7 +52,9 @@ func (l *GatewayLogger) Init() {
 	go func() {
 		for {
 			time.Sleep(time.Minute)
+			l.logLock.Lock()
 			l.writer.Flush()
+			l.logLock.Unlock()
 
 			// Rotate file every day at midnight
 			if l.rotationRequired() {
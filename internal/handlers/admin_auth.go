@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/uuid"
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/models"
+)
+
+// adminTokenUserValue is the user value name of admin token authenticated the request
+const adminTokenUserValue = "apron_admin_token"
+
+// bootstrapAdminTokenId is the id of superadmin token configured with ManagerHandler.BootstrapToken
+const bootstrapAdminTokenId = "bootstrap"
+
+// adminPermission is the permission required by admin route
+type adminPermission int
+
+const (
+	// adminPermRead allows all roles, and provider tokens are limited to own services if route has service param
+	adminPermRead adminPermission = iota
+	// adminPermReadAll allows superadmin and auditor to read records across all services
+	adminPermReadAll
+	// adminPermWrite allows superadmin, and provider tokens owning the service in route
+	adminPermWrite
+	// adminPermSuperadmin allows superadmin only
+	adminPermSuperadmin
+)
+
+// authorize authenticates admin token of request, and checks whether role of the token has the permission.
+// The token is saved in ctx user value for handlers to restrict provider tokens further.
+func (h *ManagerHandler) authorize(perm adminPermission, next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		token, err := h.authenticateAdmin(ctx)
+		if err != nil {
+			internal.WriteErrorResponse(ctx, err)
+			return
+		}
+		if err := h.checkAdminPermission(ctx, token, perm); err != nil {
+			internal.WriteErrorResponse(ctx, err)
+			return
+		}
+		ctx.SetUserValue(adminTokenUserValue, token)
+		next(ctx)
+	}
+}
+
+// authenticateAdmin loads admin token sent with Authorization: Bearer <token>
+func (h *ManagerHandler) authenticateAdmin(ctx *fasthttp.RequestCtx) (*models.ApronAdminToken, error) {
+	auth := string(ctx.Request.Header.Peek("Authorization"))
+	if len(auth) <= 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return nil, internal.UnauthorizedError("missing admin token")
+	}
+	tokenStr := strings.TrimSpace(auth[7:])
+
+	if h.BootstrapToken != "" && subtle.ConstantTimeCompare([]byte(tokenStr), []byte(h.BootstrapToken)) == 1 {
+		return &models.ApronAdminToken{Id: bootstrapAdminTokenId, Name: bootstrapAdminTokenId, Role: models.AdminRoleSuperadmin}, nil
+	}
+
+	invalidTokenErr := internal.UnauthorizedError("invalid admin token")
+	if !models.IsWellFormedAdminToken(tokenStr) {
+		return nil, invalidTokenErr
+	}
+	binaryToken, err := h.storageManager.GetRecord(internal.AdminTokenBucketName, models.HashApiKey(tokenStr))
+	if err != nil {
+		if internal.ToGatewayError(err).Code == internal.ErrCodeNotFound {
+			return nil, invalidTokenErr
+		}
+		return nil, err
+	}
+	token := &models.ApronAdminToken{}
+	if err := proto.Unmarshal([]byte(binaryToken), token); err != nil {
+		return nil, internal.InternalError(err)
+	}
+	if token.ExpiredAt != 0 && time.Now().Unix() >= token.ExpiredAt {
+		return nil, internal.KeyExpiredError("admin token expired at %s", time.Unix(token.ExpiredAt, 0).UTC().Format(time.RFC3339))
+	}
+	return token, nil
+}
+
+func (h *ManagerHandler) checkAdminPermission(ctx *fasthttp.RequestCtx, token *models.ApronAdminToken, perm adminPermission) error {
+	deniedErr := internal.ForbiddenError("role %s is not allowed to access %s %s", token.Role, ctx.Method(), ctx.Path())
+
+	switch token.Role {
+	case models.AdminRoleSuperadmin:
+		return nil
+	case models.AdminRoleAuditor:
+		if perm == adminPermRead || perm == adminPermReadAll {
+			return nil
+		}
+		return deniedErr
+	case models.AdminRoleProvider:
+		if perm != adminPermRead && perm != adminPermWrite {
+			return deniedErr
+		}
+		serviceId, ok := routeServiceId(ctx)
+		if !ok {
+			// Routes without service param filter records or assign ownership by themselves
+			return nil
+		}
+		return h.checkServiceOwner(token, serviceId)
+	}
+	return deniedErr
+}
+
+// checkServiceOwner checks whether the service is owned by account of provider token
+func (h *ManagerHandler) checkServiceOwner(token *models.ApronAdminToken, serviceId string) error {
+	notOwnedErr := internal.ForbiddenError("service %s is not owned by account %s", serviceId, token.Account)
+
+	binaryService, err := h.storageManager.GetRecord(internal.ServiceBucketName, serviceId)
+	if err != nil {
+		if internal.ToGatewayError(err).Code == internal.ErrCodeNotFound {
+			return notOwnedErr
+		}
+		return err
+	}
+	service := models.ApronService{}
+	if err := proto.Unmarshal([]byte(binaryService), &service); err != nil {
+		return internal.InternalError(err)
+	}
+	if service.ServiceProviderAccount != token.Account {
+		return notOwnedErr
+	}
+	return nil
+}
+
+// routeServiceId returns service param of route, which is named service_name or service_id in different routes
+func routeServiceId(ctx *fasthttp.RequestCtx) (string, bool) {
+	for _, name := range []string{"service_name", "service_id"} {
+		if serviceId, ok := ctx.UserValue(name).(string); ok {
+			return serviceId, true
+		}
+	}
+	return "", false
+}
+
+// adminToken returns admin token authenticated the request
+func adminToken(ctx *fasthttp.RequestCtx) *models.ApronAdminToken {
+	token, _ := ctx.UserValue(adminTokenUserValue).(*models.ApronAdminToken)
+	return token
+}
+
+// providerAccount returns account of provider token, or empty string for other roles
+func providerAccount(ctx *fasthttp.RequestCtx) string {
+	if token := adminToken(ctx); token != nil && token.Role == models.AdminRoleProvider {
+		return token.Account
+	}
+	return ""
+}
+
+func writeAdminTokenResponse(ctx *fasthttp.RequestCtx, token *models.ApronAdminToken) {
+	token.TokenHash = ""
+	m := jsonpb.Marshaler{}
+	respBody, _ := m.MarshalToString(token)
+	ctx.WriteString(respBody)
+}
+
+// listAdminTokensHandler responds all admin tokens saved in storage, the bootstrap token is not included
+func (h *ManagerHandler) listAdminTokensHandler(ctx *fasthttp.RequestCtx) {
+	tokenHashes, err := h.fetchAllRecordKeys(internal.AdminTokenBucketName)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+
+	rslt := make([]*models.ApronAdminToken, 0, len(tokenHashes))
+	for _, tokenHash := range tokenHashes {
+		binaryToken, err := h.storageManager.GetRecord(internal.AdminTokenBucketName, tokenHash)
+		if err != nil {
+			internal.WriteErrorResponse(ctx, err)
+			return
+		}
+		token := &models.ApronAdminToken{}
+		if err := proto.Unmarshal([]byte(binaryToken), token); err != nil {
+			internal.WriteErrorResponse(ctx, internal.InternalError(err))
+			return
+		}
+		token.TokenHash = ""
+		rslt = append(rslt, token)
+	}
+
+	respBody, err := json.Marshal(rslt)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	ctx.Write(respBody)
+}
+
+// newAdminTokenHandler creates admin token with role, the plaintext token is only responded in this request
+func (h *ManagerHandler) newAdminTokenHandler(ctx *fasthttp.RequestCtx) {
+	req := NewAdminTokenRequest{}
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError("invalid post body: %v", err))
+		return
+	}
+	if err := models.ValidateAdminRole(req.Role, req.Account); err != nil {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError(err.Error()))
+		return
+	}
+	if req.ExpiresIn < 0 {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError("expires_in should not be negative"))
+		return
+	}
+
+	tokenStr, err := models.GenerateAdminToken()
+	if err != nil {
+		internal.WriteErrorResponse(ctx, internal.InternalError(err))
+		return
+	}
+	token := &models.ApronAdminToken{
+		Id:        uuid.NewString(),
+		Name:      req.Name,
+		Role:      req.Role,
+		Account:   req.Account,
+		TokenHash: models.HashApiKey(tokenStr),
+		TokenHint: models.AdminTokenHint(tokenStr),
+		CreatedAt: time.Now().Unix(),
+		CreatedBy: adminToken(ctx).Id,
+	}
+	if req.ExpiresIn > 0 {
+		token.ExpiredAt = token.CreatedAt + req.ExpiresIn
+	}
+
+	binaryToken, err := proto.Marshal(token)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, internal.InternalError(err))
+		return
+	}
+	if err := h.storageManager.SaveBinaryKeyData(internal.AdminTokenBucketName, token.TokenHash, binaryToken); err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	if err := h.storageManager.SaveBinaryKeyData(internal.AdminTokenIdBucketName, token.Id, []byte(token.TokenHash)); err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+
+	h.recordAudit(ctx, "admin_token.create", auditAdminTokenTarget(token.Id), "", auditSnapshot(token))
+
+	ctx.SetStatusCode(fasthttp.StatusCreated)
+	token.Token = tokenStr
+	writeAdminTokenResponse(ctx, token)
+}
+
+// deleteAdminTokenHandler revokes admin token with id
+func (h *ManagerHandler) deleteAdminTokenHandler(ctx *fasthttp.RequestCtx) {
+	tokenId := ctx.UserValue("token_id").(string)
+	tokenHash, err := h.storageManager.GetRecord(internal.AdminTokenIdBucketName, tokenId)
+	if err != nil {
+		if internal.ToGatewayError(err).Code == internal.ErrCodeNotFound {
+			err = internal.NotFoundError("admin token %s not found", tokenId)
+		}
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	before := ""
+	if binaryToken, err := h.storageManager.GetRecord(internal.AdminTokenBucketName, tokenHash); err == nil {
+		token := &models.ApronAdminToken{}
+		if proto.Unmarshal([]byte(binaryToken), token) == nil {
+			before = auditSnapshot(token)
+		}
+	}
+
+	if err := h.storageManager.DeleteKey(internal.AdminTokenBucketName, tokenHash); err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	if err := h.storageManager.DeleteKey(internal.AdminTokenIdBucketName, tokenId); err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	h.recordAudit(ctx, "admin_token.delete", auditAdminTokenTarget(tokenId), before, "")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
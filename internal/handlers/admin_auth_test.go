@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/models"
+)
+
+func TestAdminAuthorization(t *testing.T) {
+	storageManager := models.NewMemoryStorageManager()
+	proxy := newTestProxyHandler(t, storageManager)
+	manager := newTestManagerHandler(storageManager, proxy)
+
+	newToken := func(body string) *models.ApronAdminToken {
+		ctx := serveAdmin(manager, "POST", "/admin/tokens/", body)
+		if ctx.Response.StatusCode() != fasthttp.StatusCreated {
+			t.Fatalf("expected admin token created, got %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+		token := &models.ApronAdminToken{}
+		if err := jsonpb.UnmarshalString(string(ctx.Response.Body()), token); err != nil || !models.IsWellFormedAdminToken(token.Token) {
+			t.Fatalf("unexpected admin token response %q", ctx.Response.Body())
+		}
+		return token
+	}
+	provider := newToken(`{"name": "alice", "role": "provider", "account": "alice"}`)
+	auditor := newToken(`{"name": "audit", "role": "auditor"}`)
+
+	if ctx := serveAdmin(manager, "POST", "/admin/tokens/", `{"role": "provider"}`); ctx.Response.StatusCode() != fasthttp.StatusBadRequest {
+		t.Errorf("expected provider token without account rejected, got %d", ctx.Response.StatusCode())
+	}
+	if ctx := serveAdmin(manager, "POST", "/service/", `{"id": "other_service", "base_url": "httpbin/", "schema": "http", "service_provider_account": "bob"}`); ctx.Response.StatusCode() != fasthttp.StatusCreated {
+		t.Fatalf("expected service created, got %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	// Services created by provider are owned by the account of token
+	if ctx := serveAdminAs(manager, provider.Token, "POST", "/service/", `{"id": "alice_service", "base_url": "httpbin/", "schema": "http", "service_provider_account": "bob"}`); ctx.Response.StatusCode() != fasthttp.StatusCreated {
+		t.Fatalf("expected service created by provider, got %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	testCases := []struct {
+		desc   string
+		token  string
+		method string
+		uri    string
+		body   string
+		status int
+		code   string
+	}{
+		{"missing token", "", "GET", "/service/", "", fasthttp.StatusUnauthorized, internal.ErrCodeUnauthorized},
+		{"invalid token", "apron_adm_invalid", "GET", "/service/", "", fasthttp.StatusUnauthorized, internal.ErrCodeUnauthorized},
+		{"truncated bootstrap token", testBootstrapToken[1:], "GET", "/service/", "", fasthttp.StatusUnauthorized, internal.ErrCodeUnauthorized},
+		{"provider updates own service", provider.Token, "PUT", "/service/alice_service", `{"desc": "updated"}`, fasthttp.StatusOK, ""},
+		{"provider issues key of own service", provider.Token, "POST", "/service/alice_service/keys/", `{"account_id": "user"}`, fasthttp.StatusOK, ""},
+		{"provider updates other service", provider.Token, "PUT", "/service/other_service", `{"desc": "updated"}`, fasthttp.StatusForbidden, internal.ErrCodeForbidden},
+		{"provider lists keys of other service", provider.Token, "GET", "/service/other_service/keys/", "", fasthttp.StatusForbidden, internal.ErrCodeForbidden},
+		{"provider reads all users", provider.Token, "GET", "/users/", "", fasthttp.StatusForbidden, internal.ErrCodeForbidden},
+		{"provider manages tokens", provider.Token, "GET", "/admin/tokens/", "", fasthttp.StatusForbidden, internal.ErrCodeForbidden},
+		{"auditor lists keys", auditor.Token, "GET", "/service/other_service/keys/", "", fasthttp.StatusOK, ""},
+		{"auditor reads usage report", auditor.Token, "GET", "/service/report/", "", fasthttp.StatusOK, ""},
+		{"auditor creates service", auditor.Token, "POST", "/service/", `{"id": "audit_service"}`, fasthttp.StatusForbidden, internal.ErrCodeForbidden},
+		{"auditor issues key", auditor.Token, "POST", "/service/other_service/keys/", `{"account_id": "user"}`, fasthttp.StatusForbidden, internal.ErrCodeForbidden},
+	}
+	for _, tc := range testCases {
+		ctx := serveAdminAs(manager, tc.token, tc.method, tc.uri, tc.body)
+		if ctx.Response.StatusCode() != tc.status {
+			t.Errorf("%s: expected status %d, got %d %q", tc.desc, tc.status, ctx.Response.StatusCode(), ctx.Response.Body())
+			continue
+		}
+		if tc.code != "" {
+			gatewayErr := internal.GatewayError{}
+			json.Unmarshal(ctx.Response.Body(), &gatewayErr)
+			if gatewayErr.Code != tc.code {
+				t.Errorf("%s: expected code %s, got %q", tc.desc, tc.code, ctx.Response.Body())
+			}
+		}
+	}
+
+	// Provider only sees and keeps own services
+	ctx := serveAdminAs(manager, provider.Token, "GET", "/service/", "")
+	services := []*models.ApronService{}
+	json.Unmarshal(ctx.Response.Body(), &services)
+	if len(services) != 1 || services[0].Id != "alice_service" || services[0].ServiceProviderAccount != "alice" {
+		t.Errorf("expected only alice_service listed for provider, got %q", ctx.Response.Body())
+	}
+
+	// Revoked token is rejected
+	if ctx := serveAdmin(manager, "DELETE", "/admin/tokens/"+provider.Id, ""); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("expected admin token deleted, got %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	if ctx := serveAdminAs(manager, provider.Token, "GET", "/service/", ""); ctx.Response.StatusCode() != fasthttp.StatusUnauthorized {
+		t.Errorf("expected revoked token rejected, got %d", ctx.Response.StatusCode())
+	}
+}
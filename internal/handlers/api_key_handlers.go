@@ -6,6 +6,7 @@ import (
 
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
+	"github.com/google/uuid"
 	"github.com/valyala/fasthttp"
 
 	"apron.network/gateway/internal"
@@ -21,19 +22,24 @@ func (h *ManagerHandler) listApiKeysHandler(ctx *fasthttp.RequestCtx) {
 		"",
 		internal.ExtractQueryIntValue(ctx, "count", 10),
 	)
-	internal.CheckError(err)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
 
 	// Build response
-	rslt := make([]models.ApronApiKey, resultCount)
+	rslt := make([]*models.ApronApiKey, resultCount)
 	idx := 0
 	for _, v := range scanResultMap {
-		tmpRcd := models.ApronApiKey{}
-		err := proto.Unmarshal([]byte(v), &tmpRcd)
-		internal.CheckError(err)
+		tmpRcd := &models.ApronApiKey{}
+		if err := proto.Unmarshal([]byte(v), tmpRcd); err != nil {
+			internal.WriteErrorResponse(ctx, err)
+			return
+		}
+		tmpRcd.HmacSecret = ""
 		rslt[idx] = tmpRcd
 		idx++
 	}
-	internal.CheckError(err)
 
 	resp := ListApiKeysResponse{
 		ServiceId:  serviceId,
@@ -43,102 +49,429 @@ func (h *ManagerHandler) listApiKeysHandler(ctx *fasthttp.RequestCtx) {
 	}
 
 	respBody, err := json.Marshal(resp)
-	internal.CheckError(err)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
 	ctx.Write(respBody)
 }
 
+// loadApiKey loads key record by key id, and returns the record key in ApronApiKey:<service_id> as well
+func (h *ManagerHandler) loadApiKey(serviceId, keyId string) (string, *models.ApronApiKey, error) {
+	notFoundErr := internal.NotFoundError("key %s not found in service %s", keyId, serviceId)
+
+	recordKey, err := h.storageManager.GetRecord(internal.ServiceApiKeyIdStorageBucketName(serviceId), keyId)
+	if err != nil {
+		if internal.ToGatewayError(err).Code == internal.ErrCodeNotFound {
+			return "", nil, notFoundErr
+		}
+		return "", nil, err
+	}
+
+	binaryKeyData, err := h.storageManager.GetRecord(internal.ServiceApiKeyStorageBucketName(serviceId), recordKey)
+	if err != nil {
+		if internal.ToGatewayError(err).Code == internal.ErrCodeNotFound {
+			return "", nil, notFoundErr
+		}
+		return "", nil, err
+	}
+	apiKey := &models.ApronApiKey{}
+	if err = proto.Unmarshal([]byte(binaryKeyData), apiKey); err != nil {
+		return "", nil, internal.InternalError(err)
+	}
+	return recordKey, apiKey, nil
+}
+
 // newApiKeyHandler create a new key and relationship between key and service.
-// The new key will be saved in table/bucket ApronApiKey:<service_id>,
-// and uses its Key value as store key,
-// while a protobuf serialized ApronApiKey object will be saved as its content.
+// A random key is generated, and only its sha256 hash is saved in table/bucket ApronApiKey:<service_id>
+// as store key, while a protobuf serialized ApronApiKey object without plaintext key will be saved as its content.
+// The plaintext key is only responded in this request, and the key id is used to manage the key afterwards.
 func (h *ManagerHandler) newApiKeyHandler(ctx *fasthttp.RequestCtx) {
 	postBody := ctx.PostBody()
 	if len(postBody) == 0 {
-		ctx.SetStatusCode(fasthttp.StatusBadRequest)
-		ctx.SetBodyString("missing field account_id in post body")
+		internal.WriteErrorResponse(ctx, internal.BadRequestError("missing field account_id in post body"))
 		return
 	}
 
-	var parsedRslt map[string]string
-	err := json.Unmarshal(postBody, &parsedRslt)
-	internal.CheckError(err)
+	req := NewApiKeyRequest{}
+	if err := json.Unmarshal(postBody, &req); err != nil {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError("invalid post body: %v", err))
+		return
+	}
 
-	accountId, ok := parsedRslt["account_id"]
-	if !ok {
-		ctx.SetStatusCode(fasthttp.StatusBadRequest)
-		ctx.SetBodyString("missing field account_id in post body")
+	accountId := req.AccountId
+	if accountId == "" {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError("missing field account_id in post body"))
+		return
+	}
+	if err := validateRateLimitPolicies(req.RateLimitPolicies); err != nil {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError(err.Error()))
+		return
+	}
+	if err := models.ValidateQuotaPolicy(req.QuotaPolicy); err != nil {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError(err.Error()))
+		return
+	}
+	if err := models.ValidateConcurrencyPolicy(req.ConcurrencyPolicy); err != nil {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError(err.Error()))
+		return
+	}
+	if err := models.ValidateApiKeyScopes(req.Scopes); err != nil {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError(err.Error()))
 		return
 	}
 
-	// Build key object and save to redis
-	newApiKeyMessage := models.ApronApiKey{
-		Key:       accountId,
-		ServiceId: ctx.UserValue("service_id").(string),
-		IssuedAt:  time.Now().Unix(),
-		AccountId: accountId,
+	if req.AuthMode != "" && req.AuthMode != models.ApiKeyAuthModeBearer && req.AuthMode != models.ApiKeyAuthModeHmac {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError("auth_mode should be bearer or hmac"))
+		return
 	}
 
-	binaryNewApiKey, err := proto.Marshal(&newApiKeyMessage)
-	internal.CheckError(err)
-	err = h.storageManager.SaveBinaryKeyData(
-		internal.ServiceApiKeyStorageBucketName(newApiKeyMessage.ServiceId),
-		newApiKeyMessage.Key,
-		binaryNewApiKey,
-	)
-	internal.CheckError(err)
+	if req.ExpiredAt < 0 || req.ExpiresIn < 0 {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError("expired_at and expires_in should not be negative"))
+		return
+	}
 
-	// Append generated key to user bucket
-	// Currently accountId is used as key in the bucket, so there is only one record in the user key bucket.
-	var userKeyArray []string
-	if !h.storageManager.IsKeyExistingInBucket(internal.UserBucketName, accountId) {
-		userKeyArray = append(userKeyArray, newApiKeyMessage.Key)
-		userKeyBytes, err := json.Marshal(userKeyArray)
-		internal.CheckError(err)
-		h.storageManager.SaveBinaryKeyData(internal.UserBucketName, accountId, userKeyBytes)
+	newApiKeyMessage := &models.ApronApiKey{
+		ServiceId:         ctx.UserValue("service_id").(string),
+		ExpiredAt:         req.ExpiredAt,
+		AccountId:         accountId,
+		RateLimitPolicies: req.RateLimitPolicies,
+		QuotaPolicy:       req.QuotaPolicy,
+		ConcurrencyPolicy: req.ConcurrencyPolicy,
+		Scopes:            req.Scopes,
+		AllowedIps:        req.AllowedIps,
+		AllowedOrigins:    req.AllowedOrigins,
+		AllowedReferers:   req.AllowedReferers,
+		AuthMode:          req.AuthMode,
+	}
+	if req.ExpiresIn > 0 {
+		newApiKeyMessage.ExpiredAt = time.Now().Unix() + req.ExpiresIn
+	}
+	if err := models.ValidateClientRestrictions(newApiKeyMessage); err != nil {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError(err.Error()))
+		return
 	}
 
-	// Build response
+	key, err := h.issueApiKey(newApiKeyMessage)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	h.recordAudit(ctx, "key.create", auditKeyTarget(newApiKeyMessage.ServiceId, newApiKeyMessage), "", auditSnapshot(newApiKeyMessage))
+
+	// Build response, the plaintext key is responded only once
+	newApiKeyMessage.Key = key
+	writeApiKeyResponse(ctx, newApiKeyMessage)
+}
+
+// issueApiKey generates a random key for apiKey, and saves apiKey to storage without the plaintext key,
+// which is returned and should be responded to user.
+func (h *ManagerHandler) issueApiKey(apiKey *models.ApronApiKey) (string, error) {
+	key, err := models.GenerateApiKey()
+	if err != nil {
+		return "", internal.InternalError(err)
+	}
+	apiKey.Id = uuid.NewString()
+	apiKey.KeyHash = models.HashApiKey(key)
+	apiKey.KeyHint = models.ApiKeyHint(key)
+	apiKey.IssuedAt = time.Now().Unix()
+	if apiKey.AuthMode == models.ApiKeyAuthModeHmac {
+		// The key is used as signing secret, which has to be saved for verifying signatures
+		apiKey.HmacSecret = key
+	}
+
+	if err := h.saveApiKey(apiKey.KeyHash, apiKey); err != nil {
+		return "", err
+	}
+	if err := h.saveApiKeyId(apiKey); err != nil {
+		return "", err
+	}
+
+	// Append generated key id to user bucket, accountId is used as key in the bucket
+	if err := h.appendUserKeyId(apiKey.AccountId, apiKey.Id); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// writeApiKeyResponse responds the key as JSON, the hmac secret is never responded since the plaintext key is the secret
+func writeApiKeyResponse(ctx *fasthttp.RequestCtx, apiKey *models.ApronApiKey) {
+	apiKey.HmacSecret = ""
 	m := jsonpb.Marshaler{}
-	respBody, _ := m.MarshalToString(&newApiKeyMessage)
+	respBody, _ := m.MarshalToString(apiKey)
 	ctx.WriteString(respBody)
 }
 
-func (h *ManagerHandler) apiKeyDetailHandler(ctx *fasthttp.RequestCtx) {
-	serviceId := ctx.UserValue("service_id").(string)
-	key := ctx.UserValue("key_id").(string)
-	storageBucketName := internal.ServiceApiKeyStorageBucketName(serviceId)
+// saveApiKey saves apiKey with recordKey in ApronApiKey:<service_id>, and notifies proxies to reload the key
+func (h *ManagerHandler) saveApiKey(recordKey string, apiKey *models.ApronApiKey) error {
+	binaryKey, err := proto.Marshal(apiKey)
+	if err != nil {
+		return internal.InternalError(err)
+	}
+	storageBucketName := internal.ServiceApiKeyStorageBucketName(apiKey.ServiceId)
+	if err := h.storageManager.SaveBinaryKeyData(storageBucketName, recordKey, binaryKey); err != nil {
+		return err
+	}
+	h.notifyRecordChanged(storageBucketName, recordKey)
+	return nil
+}
+
+// saveApiKeyId saves mapping from id to hash of apiKey in ApronApiKeyId:<service_id>, and notifies proxies to reload it,
+// since ids unknown to proxies are cached as not found
+func (h *ManagerHandler) saveApiKeyId(apiKey *models.ApronApiKey) error {
+	idBucketName := internal.ServiceApiKeyIdStorageBucketName(apiKey.ServiceId)
+	if err := h.storageManager.SaveBinaryKeyData(idBucketName, apiKey.Id, []byte(apiKey.KeyHash)); err != nil {
+		return err
+	}
+	h.notifyRecordChanged(idBucketName, apiKey.Id)
+	return nil
+}
 
-	if h.storageManager.IsKeyExistingInBucket(storageBucketName, key) {
-		binaryKeyData, err := h.storageManager.GetRecord(storageBucketName, key)
-		internal.CheckError(err)
+// removeApiKey deletes key record, id index and the key id in user bucket
+func (h *ManagerHandler) removeApiKey(recordKey string, apiKey *models.ApronApiKey) error {
+	storageBucketName := internal.ServiceApiKeyStorageBucketName(apiKey.ServiceId)
+	if err := h.storageManager.DeleteKey(storageBucketName, recordKey); err != nil {
+		return err
+	}
+	idBucketName := internal.ServiceApiKeyIdStorageBucketName(apiKey.ServiceId)
+	if err := h.storageManager.DeleteKey(idBucketName, models.ApiKeyId(apiKey)); err != nil {
+		return err
+	}
+	h.notifyRecordChanged(storageBucketName, recordKey)
+	h.notifyRecordChanged(idBucketName, models.ApiKeyId(apiKey))
+	return h.removeUserKeyId(apiKey.AccountId, models.ApiKeyId(apiKey))
+}
+
+// appendUserKeyId adds key id to the key id list of account saved in user bucket
+func (h *ManagerHandler) appendUserKeyId(accountId, keyId string) error {
+	keyIds := []string{}
+	userKeys, err := h.storageManager.GetRecord(internal.UserBucketName, accountId)
+	if err == nil {
+		if err := json.Unmarshal([]byte(userKeys), &keyIds); err != nil {
+			return internal.InternalError(err)
+		}
+	} else if internal.ToGatewayError(err).Code != internal.ErrCodeNotFound {
+		return err
+	}
+
+	userKeyBytes, _ := json.Marshal(append(keyIds, keyId))
+	return h.storageManager.SaveBinaryKeyData(internal.UserBucketName, accountId, userKeyBytes)
+}
+
+// removeUserKeyId removes key id from the key id list of account saved in user bucket
+func (h *ManagerHandler) removeUserKeyId(accountId, keyId string) error {
+	userKeys, err := h.storageManager.GetRecord(internal.UserBucketName, accountId)
+	if err != nil {
+		if internal.ToGatewayError(err).Code == internal.ErrCodeNotFound {
+			return nil
+		}
+		return err
+	}
+	keyIds := []string{}
+	if err := json.Unmarshal([]byte(userKeys), &keyIds); err != nil {
+		return internal.InternalError(err)
+	}
 
-		keyDetail := models.ApronApiKey{}
-		err = proto.Unmarshal([]byte(binaryKeyData), &keyDetail)
-		internal.CheckError(err)
+	remainingKeyIds := make([]string, 0, len(keyIds))
+	for _, id := range keyIds {
+		if id != keyId {
+			remainingKeyIds = append(remainingKeyIds, id)
+		}
+	}
+	if len(remainingKeyIds) == 0 {
+		return h.storageManager.DeleteKey(internal.UserBucketName, accountId)
+	}
+	userKeyBytes, _ := json.Marshal(remainingKeyIds)
+	return h.storageManager.SaveBinaryKeyData(internal.UserBucketName, accountId, userKeyBytes)
+}
 
-		// Build response
-		m := jsonpb.Marshaler{}
-		respBody, _ := m.MarshalToString(&keyDetail)
-		ctx.WriteString(respBody)
-	} else {
-		ctx.SetStatusCode(fasthttp.StatusNotFound)
+func (h *ManagerHandler) apiKeyDetailHandler(ctx *fasthttp.RequestCtx) {
+	_, keyDetail, err := h.loadApiKey(ctx.UserValue("service_id").(string), ctx.UserValue("key_id").(string))
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
 	}
+
+	// Build response
+	writeApiKeyResponse(ctx, keyDetail)
 }
 
+// updateApiKeyHandler updates mutable fields of the key, currently expiry time, rate limit, quota and concurrency policies
+// can be updated. Fields missing in request body are kept unchanged.
 func (h *ManagerHandler) updateApiKeyHandler(ctx *fasthttp.RequestCtx) {
-	ctx.SetStatusCode(fasthttp.StatusNotImplemented)
+	serviceId := ctx.UserValue("service_id").(string)
+
+	req := UpdateApiKeyRequest{}
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError("invalid post body: %v", err))
+		return
+	}
+	if req.ExpiredAt != nil && *req.ExpiredAt < 0 {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError("expired_at should not be negative"))
+		return
+	}
+	if err := validateRateLimitPolicies(req.RateLimitPolicies); err != nil {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError(err.Error()))
+		return
+	}
+	if err := models.ValidateApiKeyScopes(req.Scopes); err != nil {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError(err.Error()))
+		return
+	}
+	var quotaPolicy *models.QuotaPolicy
+	if len(req.QuotaPolicy) > 0 {
+		if err := json.Unmarshal(req.QuotaPolicy, &quotaPolicy); err != nil {
+			internal.WriteErrorResponse(ctx, internal.BadRequestError("invalid quota_policy: %v", err))
+			return
+		}
+		if err := models.ValidateQuotaPolicy(quotaPolicy); err != nil {
+			internal.WriteErrorResponse(ctx, internal.BadRequestError(err.Error()))
+			return
+		}
+	}
+	var concurrencyPolicy *models.ConcurrencyPolicy
+	if len(req.ConcurrencyPolicy) > 0 {
+		if err := json.Unmarshal(req.ConcurrencyPolicy, &concurrencyPolicy); err != nil {
+			internal.WriteErrorResponse(ctx, internal.BadRequestError("invalid concurrency_policy: %v", err))
+			return
+		}
+		if err := models.ValidateConcurrencyPolicy(concurrencyPolicy); err != nil {
+			internal.WriteErrorResponse(ctx, internal.BadRequestError(err.Error()))
+			return
+		}
+	}
+
+	recordKey, keyDetail, err := h.loadApiKey(serviceId, ctx.UserValue("key_id").(string))
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	before := auditSnapshot(keyDetail)
+
+	if req.ExpiredAt != nil {
+		keyDetail.ExpiredAt = *req.ExpiredAt
+	}
+	if req.RateLimitPolicies != nil {
+		keyDetail.RateLimitPolicies = req.RateLimitPolicies
+	}
+	if req.Scopes != nil {
+		keyDetail.Scopes = req.Scopes
+	}
+	if req.AllowedIps != nil {
+		keyDetail.AllowedIps = req.AllowedIps
+	}
+	if req.AllowedOrigins != nil {
+		keyDetail.AllowedOrigins = req.AllowedOrigins
+	}
+	if req.AllowedReferers != nil {
+		keyDetail.AllowedReferers = req.AllowedReferers
+	}
+	if err := models.ValidateClientRestrictions(keyDetail); err != nil {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError(err.Error()))
+		return
+	}
+	if len(req.QuotaPolicy) > 0 {
+		keyDetail.QuotaPolicy = quotaPolicy
+	}
+	if len(req.ConcurrencyPolicy) > 0 {
+		keyDetail.ConcurrencyPolicy = concurrencyPolicy
+	}
+
+	if err := h.saveApiKey(recordKey, keyDetail); err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	h.recordAudit(ctx, "key.update", auditKeyTarget(serviceId, keyDetail), before, auditSnapshot(keyDetail))
+
+	writeApiKeyResponse(ctx, keyDetail)
 }
 
 func (h *ManagerHandler) deleteApiKeyHandler(ctx *fasthttp.RequestCtx) {
-	serviceId := ctx.UserValue("service_id").(string)
-	key := ctx.UserValue("key_id").(string)
-	storageBucketName := internal.ServiceApiKeyStorageBucketName(serviceId)
+	recordKey, apiKey, err := h.loadApiKey(ctx.UserValue("service_id").(string), ctx.UserValue("key_id").(string))
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+
+	if err := h.removeApiKey(recordKey, apiKey); err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	h.recordAudit(ctx, "key.delete", auditKeyTarget(ctx.UserValue("service_id").(string), apiKey), auditSnapshot(apiKey), "")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// rotateApiKeyHandler issues a replacement key with the same account and policies,
+// while the old key keeps working for grace period and expires afterwards.
+func (h *ManagerHandler) rotateApiKeyHandler(ctx *fasthttp.RequestCtx) {
+	req := RotateApiKeyRequest{}
+	if body := ctx.PostBody(); len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			internal.WriteErrorResponse(ctx, internal.BadRequestError("invalid post body: %v", err))
+			return
+		}
+	}
+	gracePeriod := defaultKeyRotationGracePeriod
+	if req.GracePeriodSeconds != nil {
+		if *req.GracePeriodSeconds < 0 {
+			internal.WriteErrorResponse(ctx, internal.BadRequestError("grace_period_seconds should not be negative"))
+			return
+		}
+		gracePeriod = time.Duration(*req.GracePeriodSeconds) * time.Second
+	}
+
+	recordKey, oldKey, err := h.loadApiKey(ctx.UserValue("service_id").(string), ctx.UserValue("key_id").(string))
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	if oldKey.RotatedTo != "" {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError("key %s is already rotated to %s", models.ApiKeyId(oldKey), oldKey.RotatedTo))
+		return
+	}
+	before := auditSnapshot(oldKey)
 
-	if h.storageManager.IsKeyExistingInBucket(storageBucketName, key) {
-		err := h.storageManager.DeleteKey(storageBucketName, key)
-		internal.CheckError(err)
-		ctx.SetStatusCode(fasthttp.StatusOK)
-	} else {
-		ctx.SetStatusCode(fasthttp.StatusNotFound)
+	// Quota of key scope is counted with the first key, so rotation doesn't reset used quota
+	rootKeyId := oldKey.RootKeyId
+	if rootKeyId == "" {
+		rootKeyId = models.ApiKeyId(oldKey)
+	}
+	newKey := &models.ApronApiKey{
+		ServiceId:         oldKey.ServiceId,
+		AccountId:         oldKey.AccountId,
+		RateLimitPolicies: oldKey.RateLimitPolicies,
+		QuotaPolicy:       oldKey.QuotaPolicy,
+		ConcurrencyPolicy: oldKey.ConcurrencyPolicy,
+		Scopes:            oldKey.Scopes,
+		AllowedIps:        oldKey.AllowedIps,
+		AllowedOrigins:    oldKey.AllowedOrigins,
+		AllowedReferers:   oldKey.AllowedReferers,
+		AuthMode:          oldKey.AuthMode,
+		RotatedFrom:       models.ApiKeyId(oldKey),
+		RootKeyId:         rootKeyId,
+	}
+	if req.ExpiresIn > 0 {
+		newKey.ExpiredAt = time.Now().Unix() + req.ExpiresIn
 	}
+	key, err := h.issueApiKey(newKey)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	h.recordAudit(ctx, "key.create", auditKeyTarget(ctx.UserValue("service_id").(string), newKey), "", auditSnapshot(newKey))
+
+	// The old key expires after grace period, unless it expires earlier
+	graceExpiredAt := time.Now().Add(gracePeriod).Unix()
+	if oldKey.ExpiredAt == 0 || oldKey.ExpiredAt > graceExpiredAt {
+		oldKey.ExpiredAt = graceExpiredAt
+	}
+	oldKey.RotatedTo = newKey.Id
+	if err := h.saveApiKey(recordKey, oldKey); err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	h.recordAudit(ctx, "key.rotate", auditKeyTarget(ctx.UserValue("service_id").(string), oldKey), before, auditSnapshot(oldKey))
+
+	newKey.Key = key
+	writeApiKeyResponse(ctx, newKey)
 }
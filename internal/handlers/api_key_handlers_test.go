@@ -0,0 +1,385 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/models"
+)
+
+const testBootstrapToken = "test-bootstrap-token-with-32-chars"
+
+// newTestManagerHandler creates manager handler with storage shared with proxy handler
+func newTestManagerHandler(storageManager models.StorageManager, proxy *ProxyHandler) *ManagerHandler {
+	manager := &ManagerHandler{
+		AggrAccessRecordManager: proxy.AggrAccessRecordManager,
+		InvalidationBus:         models.NewLocalInvalidationBus(),
+		BootstrapToken:          testBootstrapToken,
+		HealthChecker:           proxy.HealthChecker,
+		Breakers:                proxy.Breakers,
+	}
+	manager.InvalidationBus.Subscribe(proxy.RecordCache.Invalidate)
+	manager.InitStore(storageManager)
+	manager.InitRouters()
+	return manager
+}
+
+func serveAdmin(manager *ManagerHandler, method, uri, body string) *fasthttp.RequestCtx {
+	return serveAdminAs(manager, testBootstrapToken, method, uri, body)
+}
+
+func serveAdminAs(manager *ManagerHandler, token, method, uri, body string) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(method)
+	ctx.Request.SetRequestURI(uri)
+	ctx.Request.SetBodyString(body)
+	if token != "" {
+		ctx.Request.Header.Set("Authorization", "Bearer "+token)
+	}
+	manager.Handler()(ctx)
+	return ctx
+}
+
+func serveProxy(proxy *ProxyHandler, serviceId, key, path string) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI(fmt.Sprintf("/v1/%s/%s%s", serviceId, key, path))
+	proxy.InternalHandler(ctx)
+	return ctx
+}
+
+func TestApiKeyHashedAtRest(t *testing.T) {
+	storageManager := models.NewMemoryStorageManager()
+	service := &models.ApronService{Id: "test_service", BaseUrl: startEchoUpstream(t) + "/", Schema: "http"}
+	binaryService, _ := proto.Marshal(service)
+	storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+
+	proxy := newTestProxyHandler(t, storageManager)
+	manager := newTestManagerHandler(storageManager, proxy)
+
+	// Plaintext key is only responded while creating
+	ctx := serveAdmin(manager, "POST", "/service/test_service/keys/", `{"account_id": "test_account"}`)
+	created := models.ApronApiKey{}
+	if err := jsonpb.UnmarshalString(string(ctx.Response.Body()), &created); err != nil {
+		t.Fatalf("failed to parse created key %q: %v", ctx.Response.Body(), err)
+	}
+	if !models.IsWellFormedApiKey(created.Key) || created.Id == "" || created.KeyHash != models.HashApiKey(created.Key) {
+		t.Fatalf("unexpected created key %q", ctx.Response.Body())
+	}
+
+	rcds, _, _, _ := storageManager.FetchRecords(internal.ServiceApiKeyStorageBucketName(service.Id), 0, "", 100)
+	for k, v := range rcds {
+		if k != created.KeyHash || strings.Contains(v, created.Key) {
+			t.Errorf("expected only key hash saved, got %s", k)
+		}
+	}
+
+	ctx = serveAdmin(manager, "GET", "/service/test_service/keys/"+created.Id, "")
+	detail := models.ApronApiKey{}
+	jsonpb.UnmarshalString(string(ctx.Response.Body()), &detail)
+	if detail.Id != created.Id || detail.Key != "" {
+		t.Errorf("expected key detail without plaintext key, got %q", ctx.Response.Body())
+	}
+
+	ctx = serveAdmin(manager, "GET", "/users/keys?account_id=test_account", "")
+	keyIds := []string{}
+	json.Unmarshal(ctx.Response.Body(), &keyIds)
+	if len(keyIds) != 1 || keyIds[0] != created.Id {
+		t.Errorf("expected key id in user keys, got %q", ctx.Response.Body())
+	}
+
+	// Key is accepted by proxy, while hash or id can not be used as key
+	if ctx := serveProxy(proxy, service.Id, created.Key, "/anything"); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Errorf("expected key accepted, got %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	for _, key := range []string{created.KeyHash, created.Id} {
+		if ctx := serveProxy(proxy, service.Id, key, "/anything"); ctx.Response.StatusCode() != fasthttp.StatusUnauthorized {
+			t.Errorf("expected %s rejected, got %d", key, ctx.Response.StatusCode())
+		}
+	}
+
+	// Mistyped key is rejected by checksum, even if a record is saved with its hash
+	mistyped := created.Key[:len(created.Key)-1] + "0"
+	if mistyped == created.Key {
+		mistyped = created.Key[:len(created.Key)-1] + "1"
+	}
+	binaryKey, _ := proto.Marshal(&models.ApronApiKey{Id: "forged", ServiceId: service.Id})
+	storageManager.SaveBinaryKeyData(internal.ServiceApiKeyStorageBucketName(service.Id), models.HashApiKey(mistyped), binaryKey)
+	if ctx := serveProxy(proxy, service.Id, mistyped, "/anything"); ctx.Response.StatusCode() != fasthttp.StatusUnauthorized {
+		t.Errorf("expected mistyped key rejected, got %d", ctx.Response.StatusCode())
+	}
+	storageManager.DeleteKey(internal.ServiceApiKeyStorageBucketName(service.Id), models.HashApiKey(mistyped))
+
+	// Usage is reported with key id
+	usages, _ := proxy.AggrAccessRecordManager.ExportAllUsage()
+	if len(usages) != 1 || usages[0].UserKey != created.Id {
+		t.Errorf("expected usage reported with key id, got %+v", usages)
+	}
+
+	ctx = serveAdmin(manager, "DELETE", "/service/test_service/keys/"+created.Id, "")
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("failed to delete key: %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	if ctx := serveProxy(proxy, service.Id, created.Key, "/anything"); ctx.Response.StatusCode() != fasthttp.StatusUnauthorized {
+		t.Errorf("expected deleted key rejected, got %d", ctx.Response.StatusCode())
+	}
+}
+
+func TestMigrateLegacyApiKeys(t *testing.T) {
+	storageManager := models.NewMemoryStorageManager()
+	service := &models.ApronService{Id: "test_service", BaseUrl: startEchoUpstream(t) + "/", Schema: "http"}
+	binaryService, _ := proto.Marshal(service)
+	storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+
+	// Legacy key saved by previous versions, and a key rotated from it
+	legacyKey := &models.ApronApiKey{Key: "legacy-plaintext-key", ServiceId: service.Id, AccountId: "test_account"}
+	binaryKey, _ := proto.Marshal(legacyKey)
+	storageManager.SaveBinaryKeyData(internal.ServiceApiKeyStorageBucketName(service.Id), legacyKey.Key, binaryKey)
+	storageManager.SaveBinaryKeyData(internal.UserBucketName, "test_account", []byte(`["legacy-plaintext-key"]`))
+	rotatedKey := &models.ApronApiKey{Id: "rotated-key-id", KeyHash: models.HashApiKey("rotated-key"), ServiceId: service.Id, RotatedFrom: legacyKey.Key}
+	binaryKey, _ = proto.Marshal(rotatedKey)
+	storageManager.SaveBinaryKeyData(internal.ServiceApiKeyStorageBucketName(service.Id), rotatedKey.KeyHash, binaryKey)
+	storageManager.SaveBinaryKeyData(internal.ServiceApiKeyIdStorageBucketName(service.Id), rotatedKey.Id, []byte(rotatedKey.KeyHash))
+
+	proxy := newTestProxyHandler(t, storageManager)
+	manager := newTestManagerHandler(storageManager, proxy)
+
+	// Plaintext records are not accepted without migration
+	if ctx := serveProxy(proxy, service.Id, legacyKey.Key, "/anything"); ctx.Response.StatusCode() != fasthttp.StatusUnauthorized {
+		t.Errorf("expected legacy key rejected before migrated, got %d", ctx.Response.StatusCode())
+	}
+
+	migrated, err := manager.MigrateLegacyApiKeys()
+	if err != nil || migrated != 1 {
+		t.Fatalf("expected 1 key migrated, got %d, %v", migrated, err)
+	}
+	if ctx := serveProxy(proxy, service.Id, legacyKey.Key, "/anything"); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Errorf("expected migrated key accepted, got %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	rcds, _, _, _ := storageManager.FetchRecords(internal.ServiceApiKeyStorageBucketName(service.Id), 0, "", 100)
+	for k, v := range rcds {
+		if k == legacyKey.Key || strings.Contains(v, legacyKey.Key) {
+			t.Errorf("expected plaintext key removed, got record %s", k)
+		}
+	}
+
+	ctx := serveAdmin(manager, "GET", "/users/keys?account_id=test_account", "")
+	keyIds := []string{}
+	json.Unmarshal(ctx.Response.Body(), &keyIds)
+	if len(keyIds) != 1 || keyIds[0] == legacyKey.Key {
+		t.Fatalf("expected plaintext key replaced with new id in user keys, got %q", ctx.Response.Body())
+	}
+	ctx = serveAdmin(manager, "GET", "/service/test_service/keys/"+keyIds[0], "")
+	detail := models.ApronApiKey{}
+	jsonpb.UnmarshalString(string(ctx.Response.Body()), &detail)
+	if detail.Id != keyIds[0] || detail.KeyHash != models.HashApiKey(legacyKey.Key) || detail.Key != "" {
+		t.Errorf("expected migrated key detail with new id, got %q", ctx.Response.Body())
+	}
+	if ctx := serveAdmin(manager, "GET", "/service/test_service/keys/"+legacyKey.Key, ""); ctx.Response.StatusCode() != fasthttp.StatusNotFound {
+		t.Errorf("expected plaintext key not accepted as id, got %d", ctx.Response.StatusCode())
+	}
+	ctx = serveAdmin(manager, "GET", "/service/test_service/keys/"+rotatedKey.Id, "")
+	jsonpb.UnmarshalString(string(ctx.Response.Body()), &detail)
+	if detail.RotatedFrom != keyIds[0] {
+		t.Errorf("expected rotated_from replaced with new id, got %q", ctx.Response.Body())
+	}
+
+	if migrated, err := manager.MigrateLegacyApiKeys(); err != nil || migrated != 0 {
+		t.Errorf("expected nothing migrated again, got %d, %v", migrated, err)
+	}
+}
+
+func TestApiKeyExpiryAndRotation(t *testing.T) {
+	storageManager := models.NewMemoryStorageManager()
+	service := &models.ApronService{Id: "test_service", BaseUrl: startEchoUpstream(t) + "/", Schema: "http"}
+	binaryService, _ := proto.Marshal(service)
+	storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+
+	proxy := newTestProxyHandler(t, storageManager)
+	manager := newTestManagerHandler(storageManager, proxy)
+
+	createKey := func(method, uri, body string) *models.ApronApiKey {
+		t.Helper()
+		ctx := serveAdmin(manager, method, uri, body)
+		apiKey := &models.ApronApiKey{}
+		if err := jsonpb.UnmarshalString(string(ctx.Response.Body()), apiKey); err != nil || apiKey.Key == "" {
+			t.Fatalf("failed to create key: %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+		return apiKey
+	}
+	assertProxyError := func(key string, status int, code string) {
+		t.Helper()
+		ctx := serveProxy(proxy, service.Id, key, "/anything")
+		gatewayErr := internal.GatewayError{}
+		json.Unmarshal(ctx.Response.Body(), &gatewayErr)
+		if ctx.Response.StatusCode() != status || (code != "" && gatewayErr.Code != code) {
+			t.Errorf("expected %d %s, got %d %q", status, code, ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+	}
+
+	oldKey := createKey("POST", "/service/test_service/keys/", `{"account_id": "test_account", "rate_limit_policies": [{"max": 100, "duration_ms": 1000}]}`)
+
+	// Both keys work in grace period
+	newKey := createKey("POST", "/service/test_service/keys/"+oldKey.Id+"/rotate", `{"grace_period_seconds": 3600}`)
+	if newKey.RotatedFrom != oldKey.Id || newKey.AccountId != "test_account" || len(newKey.RateLimitPolicies) != 1 {
+		t.Errorf("expected account and policies copied to new key, got %q", newKey.String())
+	}
+	assertProxyError(oldKey.Key, fasthttp.StatusOK, "")
+	assertProxyError(newKey.Key, fasthttp.StatusOK, "")
+
+	ctx := serveAdmin(manager, "POST", "/service/test_service/keys/"+oldKey.Id+"/rotate", "")
+	if ctx.Response.StatusCode() != fasthttp.StatusBadRequest {
+		t.Errorf("expected rotated key can not be rotated again, got %d", ctx.Response.StatusCode())
+	}
+
+	// Old key expires immediately without grace period
+	latestKey := createKey("POST", "/service/test_service/keys/"+newKey.Id+"/rotate", `{"grace_period_seconds": 0}`)
+	assertProxyError(newKey.Key, fasthttp.StatusUnauthorized, internal.ErrCodeKeyExpired)
+	assertProxyError(latestKey.Key, fasthttp.StatusOK, "")
+
+	// Expiry can be updated
+	serveAdmin(manager, "PUT", "/service/test_service/keys/"+latestKey.Id, `{"expired_at": 1}`)
+	assertProxyError(latestKey.Key, fasthttp.StatusUnauthorized, internal.ErrCodeKeyExpired)
+	serveAdmin(manager, "PUT", "/service/test_service/keys/"+latestKey.Id, `{"expired_at": 0}`)
+	assertProxyError(latestKey.Key, fasthttp.StatusOK, "")
+
+	// Sweeper removes expired keys only
+	removed, err := manager.SweepExpiredKeys(time.Hour)
+	if err != nil || removed != 0 {
+		t.Errorf("expected no key removed in retention, got %d, %v", removed, err)
+	}
+	removed, err = manager.SweepExpiredKeys(0)
+	if err != nil || removed != 1 {
+		t.Errorf("expected 1 expired key removed, got %d, %v", removed, err)
+	}
+	assertProxyError(newKey.Key, fasthttp.StatusUnauthorized, internal.ErrCodeUnauthorized)
+	assertProxyError(oldKey.Key, fasthttp.StatusOK, "")
+
+	ctx = serveAdmin(manager, "GET", "/users/keys?account_id=test_account", "")
+	keyIds := []string{}
+	json.Unmarshal(ctx.Response.Body(), &keyIds)
+	if len(keyIds) != 2 {
+		t.Errorf("expected removed key id removed from user keys, got %q", ctx.Response.Body())
+	}
+}
+
+func TestApiKeyRotationKeepsQuota(t *testing.T) {
+	storageManager := models.NewMemoryStorageManager()
+	service := &models.ApronService{Id: "test_service", BaseUrl: startEchoUpstream(t) + "/", Schema: "http"}
+	binaryService, _ := proto.Marshal(service)
+	storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+
+	proxy := newTestProxyHandler(t, storageManager)
+	manager := newTestManagerHandler(storageManager, proxy)
+
+	createKey := func(uri, body string) *models.ApronApiKey {
+		t.Helper()
+		ctx := serveAdmin(manager, "POST", uri, body)
+		apiKey := &models.ApronApiKey{}
+		if err := jsonpb.UnmarshalString(string(ctx.Response.Body()), apiKey); err != nil || apiKey.Key == "" {
+			t.Fatalf("failed to create key: %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+		return apiKey
+	}
+	usedQuota := func(keyId string) int64 {
+		t.Helper()
+		ctx := serveAdmin(manager, "GET", "/service/test_service/keys/"+keyId+"/quota", "")
+		status := models.QuotaStatus{}
+		if err := json.Unmarshal(ctx.Response.Body(), &status); err != nil {
+			t.Fatalf("failed to read quota: %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+		return status.Used
+	}
+
+	oldKey := createKey("/service/test_service/keys/", `{"account_id": "test_account", "quota_policy": {"period": "monthly", "limit": 3}}`)
+	for i := 0; i < 2; i++ {
+		serveProxy(proxy, service.Id, oldKey.Key, "/anything")
+	}
+
+	newKey := createKey("/service/test_service/keys/"+oldKey.Id+"/rotate", "")
+	latestKey := createKey("/service/test_service/keys/"+newKey.Id+"/rotate", "")
+	if newKey.RootKeyId != oldKey.Id || latestKey.RootKeyId != oldKey.Id {
+		t.Errorf("expected root key id %s, got %s and %s", oldKey.Id, newKey.RootKeyId, latestKey.RootKeyId)
+	}
+	if used := usedQuota(latestKey.Id); used != 2 {
+		t.Errorf("expected used quota carried over to rotated key, got %d", used)
+	}
+
+	if ctx := serveProxy(proxy, service.Id, latestKey.Key, "/anything"); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Errorf("expected request in quota accepted, got %d", ctx.Response.StatusCode())
+	}
+	for _, key := range []string{oldKey.Key, latestKey.Key} {
+		if ctx := serveProxy(proxy, service.Id, key, "/anything"); ctx.Response.StatusCode() != fasthttp.StatusTooManyRequests {
+			t.Errorf("expected quota shared by rotated keys exhausted, got %d", ctx.Response.StatusCode())
+		}
+	}
+}
+
+func TestHmacSignedRequest(t *testing.T) {
+	storageManager := models.NewMemoryStorageManager()
+	service := &models.ApronService{Id: "test_service", BaseUrl: startEchoUpstream(t) + "/", Schema: "http"}
+	binaryService, _ := proto.Marshal(service)
+	storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+
+	proxy := newTestProxyHandler(t, storageManager)
+	manager := newTestManagerHandler(storageManager, proxy)
+
+	ctx := serveAdmin(manager, "POST", "/service/test_service/keys/", `{"account_id": "test_account", "auth_mode": "hmac"}`)
+	created := models.ApronApiKey{}
+	if err := jsonpb.UnmarshalString(string(ctx.Response.Body()), &created); err != nil || created.Key == "" {
+		t.Fatalf("failed to create hmac key %q: %v", ctx.Response.Body(), err)
+	}
+	ctx = serveAdmin(manager, "GET", "/service/test_service/keys/"+created.Id, "")
+	if strings.Contains(string(ctx.Response.Body()), created.Key) || created.HmacSecret != "" {
+		t.Errorf("expected secret not responded, got %q", ctx.Response.Body())
+	}
+
+	signedRequest := func(secret string, timestamp int64, nonce, body string) *fasthttp.RequestCtx {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.Header.SetMethod("POST")
+		ctx.Request.SetRequestURI("/v1/test_service/anything?b=2&a=1")
+		ctx.Request.SetBodyString(body)
+
+		query := map[string][]string{"a": {"1"}, "b": {"2"}}
+		stringToSign := models.HmacStringToSign("POST", "/v1/test_service/anything", query, []byte("signed body"), timestamp, nonce)
+		auth := models.HmacAuthorization{KeyId: created.Id, Timestamp: timestamp, Nonce: nonce, Signature: models.SignHmac(secret, stringToSign)}
+		ctx.Request.Header.Set("Authorization", auth.String())
+		proxy.InternalHandler(ctx)
+		return ctx
+	}
+
+	now := time.Now().Unix()
+	testCases := []struct {
+		desc   string
+		ctx    *fasthttp.RequestCtx
+		status int
+	}{
+		{"signed", signedRequest(created.Key, now, "nonce-0001", "signed body"), fasthttp.StatusOK},
+		{"replayed", signedRequest(created.Key, now, "nonce-0001", "signed body"), fasthttp.StatusUnauthorized},
+		{"stale", signedRequest(created.Key, now-3600, "nonce-0002", "signed body"), fasthttp.StatusUnauthorized},
+		{"tampered", signedRequest(created.Key, now, "nonce-0003", "tampered body"), fasthttp.StatusUnauthorized},
+		{"wrong secret", signedRequest("wrong", now, "nonce-0004", "signed body"), fasthttp.StatusUnauthorized},
+		{"secret sent", serveProxy(proxy, service.Id, created.Key, "/anything"), fasthttp.StatusUnauthorized},
+	}
+	for _, tc := range testCases {
+		if tc.ctx.Response.StatusCode() != tc.status {
+			t.Errorf("%s: expected status %d, got %d %q", tc.desc, tc.status, tc.ctx.Response.StatusCode(), tc.ctx.Response.Body())
+		}
+	}
+	if body := string(testCases[0].ctx.Response.Body()); body != "/anything|signed body" {
+		t.Errorf("unexpected upstream response %q", body)
+	}
+
+	// Cached key id is invalidated once the key is deleted
+	serveAdmin(manager, "DELETE", "/service/test_service/keys/"+created.Id, "")
+	if ctx := signedRequest(created.Key, now, "nonce-0005", "signed body"); ctx.Response.StatusCode() != fasthttp.StatusUnauthorized {
+		t.Errorf("expected deleted key rejected, got %d", ctx.Response.StatusCode())
+	}
+}
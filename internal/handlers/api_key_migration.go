@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/google/uuid"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/models"
+)
+
+// MigrateLegacyApiKeys re-saves keys issued before hashed keys under sha256 hash of the key, and removes the
+// plaintext records. Migrated keys get a random id in place of the plaintext key, which is also replaced in
+// user bucket and RotatedFrom of keys rotated from them. It is safe to run again after interrupted,
+// and returns count of migrated keys.
+func (h *ManagerHandler) MigrateLegacyApiKeys() (int, error) {
+	serviceIds, err := h.fetchAllRecordKeys(internal.ServiceBucketName)
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, serviceId := range serviceIds {
+		// Records are changed after all pages fetched, since changing records changes cursor of some storage backends
+		keys := make(map[string]*models.ApronApiKey)
+		cursor := 0
+		for {
+			rcds, nextCursor, _, err := h.storageManager.FetchRecords(internal.ServiceApiKeyStorageBucketName(serviceId), cursor, "", 100)
+			if err != nil {
+				return migrated, err
+			}
+			for recordKey, content := range rcds {
+				apiKey := &models.ApronApiKey{}
+				if err := proto.Unmarshal([]byte(content), apiKey); err != nil {
+					return migrated, internal.InternalError(err)
+				}
+				apiKey.ServiceId = serviceId
+				keys[recordKey] = apiKey
+			}
+			if nextCursor == 0 {
+				break
+			}
+			cursor = int(nextCursor)
+		}
+
+		for recordKey, apiKey := range keys {
+			if apiKey.KeyHash != "" {
+				continue
+			}
+			if err := h.migrateLegacyApiKey(recordKey, apiKey); err != nil {
+				return migrated, err
+			}
+			migrated++
+		}
+
+		// Legacy id in RotatedFrom is the plaintext key, so it is found by hash
+		byHash := make(map[string]*models.ApronApiKey, len(keys))
+		for _, apiKey := range keys {
+			byHash[apiKey.KeyHash] = apiKey
+		}
+		for _, apiKey := range keys {
+			if apiKey.RotatedFrom == "" {
+				continue
+			}
+			rotatedFrom, ok := byHash[models.HashApiKey(apiKey.RotatedFrom)]
+			if !ok || rotatedFrom.Id == apiKey.RotatedFrom {
+				continue
+			}
+			apiKey.RotatedFrom = rotatedFrom.Id
+			if err := h.saveApiKey(apiKey.KeyHash, apiKey); err != nil {
+				return migrated, err
+			}
+		}
+	}
+	return migrated, nil
+}
+
+// migrateLegacyApiKey saves legacy key with hashed record key and a new id.
+// The plaintext record is removed at last, so the key keeps working if migration is interrupted.
+func (h *ManagerHandler) migrateLegacyApiKey(plaintextKey string, apiKey *models.ApronApiKey) error {
+	oldId := models.ApiKeyId(apiKey)
+	keyHash := models.HashApiKey(plaintextKey)
+
+	// Id of a key saved by an interrupted migration is reused
+	newId := uuid.NewString()
+	if content, err := h.storageManager.GetRecord(internal.ServiceApiKeyStorageBucketName(apiKey.ServiceId), keyHash); err == nil {
+		saved := &models.ApronApiKey{}
+		if err := proto.Unmarshal([]byte(content), saved); err != nil {
+			return internal.InternalError(err)
+		}
+		newId = saved.Id
+	} else if internal.ToGatewayError(err).Code != internal.ErrCodeNotFound {
+		return err
+	}
+
+	apiKey.Id = newId
+	apiKey.Key = ""
+	apiKey.KeyHash = keyHash
+	apiKey.KeyHint = models.ApiKeyHint(plaintextKey)
+	if err := h.saveApiKey(keyHash, apiKey); err != nil {
+		return err
+	}
+	if err := h.saveApiKeyId(apiKey); err != nil {
+		return err
+	}
+	if err := h.replaceUserKeyId(apiKey.AccountId, oldId, newId); err != nil {
+		return err
+	}
+
+	storageBucketName := internal.ServiceApiKeyStorageBucketName(apiKey.ServiceId)
+	if err := h.storageManager.DeleteKey(storageBucketName, plaintextKey); err != nil {
+		return err
+	}
+	h.notifyRecordChanged(storageBucketName, plaintextKey)
+	h.recordAudit(nil, "key.migrate", auditKeyTarget(apiKey.ServiceId, apiKey), "", auditSnapshot(apiKey))
+	return nil
+}
+
+// replaceUserKeyId replaces oldId with newId in the key id list of account, newId is not added twice
+func (h *ManagerHandler) replaceUserKeyId(accountId, oldId, newId string) error {
+	if err := h.removeUserKeyId(accountId, newId); err != nil {
+		return err
+	}
+	if err := h.removeUserKeyId(accountId, oldId); err != nil {
+		return err
+	}
+	return h.appendUserKeyId(accountId, newId)
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"log"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/models"
+)
+
+// defaultKeyRotationGracePeriod is the duration old key keeps working after rotated
+const defaultKeyRotationGracePeriod = 24 * time.Hour
+
+// StartExpiredKeySweeper removes keys expired longer than retention every interval.
+// Expired keys are kept for retention, so requests with them are still responded with key_expired error.
+func (h *ManagerHandler) StartExpiredKeySweeper(interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if count, err := h.SweepExpiredKeys(retention); err != nil {
+				log.Printf("Failed to sweep expired keys: %v", err)
+			} else if count > 0 {
+				log.Printf("Removed %d expired keys", count)
+			}
+		}
+	}()
+}
+
+// SweepExpiredKeys removes keys of all services expired before now - retention and expired access tokens,
+// and returns count of removed keys
+func (h *ManagerHandler) SweepExpiredKeys(retention time.Duration) (int, error) {
+	deadline := time.Now().Add(-retention).Unix()
+
+	serviceIds, err := h.fetchAllRecordKeys(internal.ServiceBucketName)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, serviceId := range serviceIds {
+		// Keys are removed after all pages fetched, since removing changes cursor of some storage backends
+		expiredKeys := make(map[string]*models.ApronApiKey)
+		cursor := 0
+		for {
+			rcds, nextCursor, _, err := h.storageManager.FetchRecords(internal.ServiceApiKeyStorageBucketName(serviceId), cursor, "", 100)
+			if err != nil {
+				return removed, err
+			}
+			for recordKey, content := range rcds {
+				apiKey := &models.ApronApiKey{}
+				if err := proto.Unmarshal([]byte(content), apiKey); err != nil {
+					return removed, internal.InternalError(err)
+				}
+				if apiKey.ExpiredAt != 0 && apiKey.ExpiredAt <= deadline {
+					apiKey.ServiceId = serviceId
+					expiredKeys[recordKey] = apiKey
+				}
+			}
+			if nextCursor == 0 {
+				break
+			}
+			cursor = int(nextCursor)
+		}
+
+		for recordKey, apiKey := range expiredKeys {
+			if err := h.removeApiKey(recordKey, apiKey); err != nil {
+				return removed, err
+			}
+			h.recordAudit(nil, "key.sweep", auditKeyTarget(serviceId, apiKey), auditSnapshot(apiKey), "")
+			removed++
+		}
+	}
+
+	count, err := h.sweepExpiredAccessTokens()
+	return removed + count, err
+}
+
+// sweepExpiredAccessTokens removes access tokens issued by wallet login once expired,
+// they are not retained since tokens are short-lived and reissued by login.
+func (h *ManagerHandler) sweepExpiredAccessTokens() (int, error) {
+	now := time.Now().Unix()
+	tokenHashes, err := h.fetchAllRecordKeys(internal.AccessTokenBucketName)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, tokenHash := range tokenHashes {
+		content, err := h.storageManager.GetRecord(internal.AccessTokenBucketName, tokenHash)
+		if err != nil {
+			return removed, err
+		}
+		token := &models.ApronApiKey{}
+		if err := proto.Unmarshal([]byte(content), token); err != nil {
+			return removed, internal.InternalError(err)
+		}
+		if token.ExpiredAt > now {
+			continue
+		}
+		if err := h.storageManager.DeleteKey(internal.AccessTokenBucketName, tokenHash); err != nil {
+			return removed, err
+		}
+		h.notifyRecordChanged(internal.AccessTokenBucketName, tokenHash)
+		removed++
+	}
+	return removed, nil
+}
+
+// fetchAllRecordKeys returns keys of all records in table
+func (h *ManagerHandler) fetchAllRecordKeys(table string) ([]string, error) {
+	keys := []string{}
+	cursor := 0
+	for {
+		rcds, nextCursor, _, err := h.storageManager.FetchRecords(table, cursor, "", 100)
+		if err != nil {
+			return nil, err
+		}
+		for k := range rcds {
+			keys = append(keys, k)
+		}
+		if nextCursor == 0 {
+			return keys, nil
+		}
+		cursor = int(nextCursor)
+	}
+}
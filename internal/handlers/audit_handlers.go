@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/models"
+)
+
+// auditActorSystem is the actor of audit entries appended by background jobs
+const auditActorSystem = "system"
+
+// maxAuditPageSize is the max count of entries responded in one page
+const maxAuditPageSize = 1000
+
+// auditSnapshot returns JSON of record saved in audit entry with secrets removed, empty string for nil record
+func auditSnapshot(rcd proto.Message) string {
+	if rcd == nil {
+		return ""
+	}
+	rcd = proto.Clone(rcd)
+	switch r := rcd.(type) {
+	case *models.ApronApiKey:
+		r.Key = ""
+		r.HmacSecret = ""
+	case *models.ApronAdminToken:
+		r.Token = ""
+	}
+	m := jsonpb.Marshaler{}
+	snapshot, _ := m.MarshalToString(rcd)
+	return snapshot
+}
+
+// recordAudit appends audit entry of mutating admin action with snapshots from auditSnapshot,
+// ctx is nil for background jobs. The action is already done, so failures are only logged.
+func (h *ManagerHandler) recordAudit(ctx *fasthttp.RequestCtx, action, target, before, after string) {
+	entry := &models.AuditEntry{
+		Actor:  auditActorSystem,
+		Action: action,
+		Target: target,
+		Before: before,
+		After:  after,
+	}
+	if ctx != nil {
+		entry.ActorIp = ctx.RemoteIP().String()
+		if token := adminToken(ctx); token != nil {
+			entry.Actor = token.Id
+			entry.ActorRole = token.Role
+		}
+	}
+	if err := h.auditLog.Append(entry); err != nil {
+		log.Printf("Failed to append audit entry %s of %s by %s: %v\n", action, target, entry.Actor, err)
+	}
+}
+
+func auditServiceTarget(serviceId string) string {
+	return "service/" + serviceId
+}
+
+func auditKeyTarget(serviceId string, apiKey *models.ApronApiKey) string {
+	return auditServiceTarget(serviceId) + "/key/" + models.ApiKeyId(apiKey)
+}
+
+func auditAdminTokenTarget(tokenId string) string {
+	return "admin_token/" + tokenId
+}
+
+// listAuditEntriesHandler responds audit entries from sequence number start, which is 1 by default
+func (h *ManagerHandler) listAuditEntriesHandler(ctx *fasthttp.RequestCtx) {
+	count := internal.ExtractQueryIntValue(ctx, "count", 100)
+	if count <= 0 || count > maxAuditPageSize {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError("count should be between 1 and %d", maxAuditPageSize))
+		return
+	}
+	start := uint64(internal.ExtractQueryIntValue(ctx, "start", 1))
+
+	entries, err := h.auditLog.Page(start, count)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	resp := ListAuditEntriesResponse{
+		Records: entries,
+		Count:   uint(len(entries)),
+	}
+	if len(entries) == count {
+		resp.NextSeq = entries[len(entries)-1].Seq + 1
+	}
+
+	respBody, err := json.Marshal(resp)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	ctx.SetContentType("application/json")
+	ctx.Write(respBody)
+}
+
+// verifyAuditLogHandler verifies hash chain of all audit entries, and responds the first broken entry if any
+func (h *ManagerHandler) verifyAuditLogHandler(ctx *fasthttp.RequestCtx) {
+	result, err := h.auditLog.Verify()
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+
+	respBody, _ := json.Marshal(result)
+	ctx.SetContentType("application/json")
+	ctx.Write(respBody)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal/models"
+)
+
+func TestAdminActionsAudited(t *testing.T) {
+	storageManager := models.NewMemoryStorageManager()
+	proxy := newTestProxyHandler(t, storageManager)
+	manager := newTestManagerHandler(storageManager, proxy)
+
+	serveAdmin(manager, "POST", "/service/", `{"id": "test_service", "base_url": "httpbin/", "schema": "http"}`)
+	serveAdmin(manager, "PUT", "/service/test_service", `{"desc": "updated"}`)
+	ctx := serveAdmin(manager, "POST", "/service/test_service/keys/", `{"account_id": "test_account", "auth_mode": "hmac"}`)
+	apiKey := &models.ApronApiKey{}
+	jsonpb.UnmarshalString(string(ctx.Response.Body()), apiKey)
+	serveAdmin(manager, "DELETE", "/service/test_service/keys/"+apiKey.Id, "")
+
+	ctx = serveAdmin(manager, "GET", "/audit/?start=1&count=3", "")
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("expected audit entries, got %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	resp := ListAuditEntriesResponse{}
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if resp.Count != 3 || resp.NextSeq != 4 {
+		t.Fatalf("expected first page with 3 entries, got %q", ctx.Response.Body())
+	}
+	update := resp.Records[1]
+	if update.Action != "service.update" || update.Target != "service/test_service" || update.Actor != bootstrapAdminTokenId ||
+		strings.Contains(update.Before, "updated") || !strings.Contains(update.After, "updated") {
+		t.Errorf("unexpected service update entry %+v", update)
+	}
+	if create := resp.Records[2]; create.Action != "key.create" || strings.Contains(create.After, apiKey.Key) {
+		t.Errorf("expected key created without secret, got %+v", create)
+	}
+
+	ctx = serveAdmin(manager, "GET", "/audit/?start=4", "")
+	resp = ListAuditEntriesResponse{}
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if resp.Count != 1 || resp.NextSeq != 0 || resp.Records[0].Action != "key.delete" || resp.Records[0].After != "" {
+		t.Errorf("expected last page with key deletion, got %q", ctx.Response.Body())
+	}
+
+	ctx = serveAdmin(manager, "GET", "/audit/verify", "")
+	result := models.AuditVerifyResult{}
+	json.Unmarshal(ctx.Response.Body(), &result)
+	if !result.Valid || result.HeadSeq != 4 || result.HeadHash != resp.Records[0].Hash {
+		t.Errorf("expected valid audit log, got %q", ctx.Response.Body())
+	}
+
+	// Provider can't read audit log of all services
+	provider := &models.ApronAdminToken{}
+	ctx = serveAdmin(manager, "POST", "/admin/tokens/", `{"role": "provider", "account": "alice"}`)
+	jsonpb.UnmarshalString(string(ctx.Response.Body()), provider)
+	if ctx := serveAdminAs(manager, provider.Token, "GET", "/audit/", ""); ctx.Response.StatusCode() != fasthttp.StatusForbidden {
+		t.Errorf("expected provider forbidden, got %d", ctx.Response.StatusCode())
+	}
+	if ctx := serveAdmin(manager, "GET", "/audit/?count=0", ""); ctx.Response.StatusCode() != fasthttp.StatusBadRequest {
+		t.Errorf("expected invalid count rejected, got %d", ctx.Response.StatusCode())
+	}
+}
+
+func TestQuotaResetAudited(t *testing.T) {
+	storageManager := models.NewMemoryStorageManager()
+	proxy := newTestProxyHandler(t, storageManager)
+	manager := newTestManagerHandler(storageManager, proxy)
+
+	serveAdmin(manager, "POST", "/service/", fmt.Sprintf(`{"id": "test_service", "base_url": %q, "schema": "http", "quota_policy": {"period": "monthly", "limit": 10}}`, startEchoUpstream(t)+"/"))
+	ctx := serveAdmin(manager, "POST", "/service/test_service/keys/", `{"account_id": "test_account"}`)
+	apiKey := &models.ApronApiKey{}
+	jsonpb.UnmarshalString(string(ctx.Response.Body()), apiKey)
+	for i := 0; i < 2; i++ {
+		serveProxy(proxy, "test_service", apiKey.Key, "/anything")
+	}
+	if ctx := serveAdmin(manager, "DELETE", "/service/test_service/keys/"+apiKey.Id+"/quota", ""); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("failed to reset quota: %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	ctx = serveAdmin(manager, "GET", "/audit/?start=3", "")
+	resp := ListAuditEntriesResponse{}
+	json.Unmarshal(ctx.Response.Body(), &resp)
+	if resp.Count != 1 || resp.Records[0].Action != "key.quota_reset" {
+		t.Fatalf("expected quota reset entry, got %q", ctx.Response.Body())
+	}
+	before, after := models.QuotaStatus{}, models.QuotaStatus{}
+	if err := json.Unmarshal([]byte(resp.Records[0].Before), &before); err != nil || before.Used != 2 || before.Limit != 10 {
+		t.Errorf("expected usage before reset audited, got %q", resp.Records[0].Before)
+	}
+	if err := json.Unmarshal([]byte(resp.Records[0].After), &after); err != nil || after.Used != 0 || after.Remaining != 10 {
+		t.Errorf("expected usage after reset audited, got %q", resp.Records[0].After)
+	}
+}
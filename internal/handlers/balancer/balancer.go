@@ -0,0 +1,238 @@
+package balancer
+
+import (
+	"errors"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrNoTarget is returned if all targets are unavailable
+var ErrNoTarget = errors.New("balancer: no available target")
+
+// Strategy decides how a target is picked from targets of a key
+type Strategy int
+
+const (
+	// RoundRobin picks targets in turn, weights are ignored
+	RoundRobin Strategy = iota
+	// Weighted picks targets in turn proportional to weights, with smooth weighted round robin
+	Weighted
+	// LeastOutstanding picks target with least in flight requests relative to weight
+	LeastOutstanding
+	// ConsistentHash picks the same target for the same hash key, while only keys of changed targets are remapped
+	ConsistentHash
+)
+
+const (
+	// ringReplicas is count of virtual nodes of each weight unit on hash ring
+	ringReplicas = 64
+	// maxRingPoints caps virtual nodes of a ring, replicas of each weight unit are reduced for large weights
+	maxRingPoints = 1 << 16
+	// maxRings is count of rings kept for each key, since candidates change as targets become unavailable or tried
+	maxRings = 8
+)
+
+// Target is an upstream node identified by address
+type Target struct {
+	Addr   string
+	Weight int // Relative weight, should be positive
+}
+
+// Balancer picks targets for keys, such as services, and keeps state of each key between picks
+type Balancer struct {
+	lock  sync.Mutex
+	pools map[string]*pool
+}
+
+type pool struct {
+	next        uint64
+	current     map[string]int // Current weights of smooth weighted round robin
+	outstanding map[string]int
+	rings       map[string]*hashRing // Rings of candidate sets by signature
+	ringOrder   []string             // Signatures of rings in created order, the oldest ring is dropped first
+}
+
+func New() *Balancer {
+	return &Balancer{pools: make(map[string]*pool)}
+}
+
+/*
+Pick selects a target of key with strategy, targets rejected by available are skipped, and nil available accepts all targets.
+The hash key is only used by ConsistentHash. The returned release func should be called after the request finished:
+
+	target, release, err := b.Pick("service-1", balancer.RoundRobin, targets, "", nil)
+	if err != nil {
+	    return err
+	}
+	defer release()
+*/
+func (b *Balancer) Pick(key string, strategy Strategy, targets []Target, hashKey string, available func(Target) bool) (Target, func(), error) {
+	candidates := make([]Target, 0, len(targets))
+	for _, t := range targets {
+		if t.Weight > 0 && (available == nil || available(t)) {
+			candidates = append(candidates, t)
+		}
+	}
+	if len(candidates) == 0 {
+		return Target{}, nil, ErrNoTarget
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	p, ok := b.pools[key]
+	if !ok {
+		p = &pool{current: make(map[string]int), outstanding: make(map[string]int)}
+		b.pools[key] = p
+	}
+
+	var picked Target
+	switch strategy {
+	case Weighted:
+		picked = p.pickWeighted(candidates)
+	case LeastOutstanding:
+		picked = p.pickLeastOutstanding(candidates)
+	case ConsistentHash:
+		picked = p.pickConsistentHash(candidates, hashKey)
+	default:
+		picked = candidates[p.next%uint64(len(candidates))]
+		p.next++
+	}
+
+	p.outstanding[picked.Addr]++
+	released := false
+	release := func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		if released {
+			return
+		}
+		released = true
+		if p.outstanding[picked.Addr]--; p.outstanding[picked.Addr] <= 0 {
+			delete(p.outstanding, picked.Addr)
+		}
+	}
+	return picked, release, nil
+}
+
+// Outstanding returns in flight requests of the target of key
+func (b *Balancer) Outstanding(key, addr string) int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if p, ok := b.pools[key]; ok {
+		return p.outstanding[addr]
+	}
+	return 0
+}
+
+func (p *pool) pickWeighted(candidates []Target) Target {
+	total := 0
+	best := -1
+	for idx, t := range candidates {
+		p.current[t.Addr] += t.Weight
+		total += t.Weight
+		if best < 0 || p.current[t.Addr] > p.current[candidates[best].Addr] {
+			best = idx
+		}
+	}
+	p.current[candidates[best].Addr] -= total
+	return candidates[best]
+}
+
+func (p *pool) pickLeastOutstanding(candidates []Target) Target {
+	// Scanning starts from a rotating offset, so ties are spread across targets
+	offset := int(p.next % uint64(len(candidates)))
+	p.next++
+
+	best := candidates[offset]
+	for i := 1; i < len(candidates); i++ {
+		t := candidates[(offset+i)%len(candidates)]
+		// Compare outstanding/weight without division
+		if p.outstanding[t.Addr]*best.Weight < p.outstanding[best.Addr]*t.Weight {
+			best = t
+		}
+	}
+	return best
+}
+
+func (p *pool) pickConsistentHash(candidates []Target, hashKey string) Target {
+	signature := ringSignature(candidates)
+	ring, ok := p.rings[signature]
+	if !ok {
+		if p.rings == nil {
+			p.rings = make(map[string]*hashRing)
+		}
+		if len(p.ringOrder) >= maxRings {
+			delete(p.rings, p.ringOrder[0])
+			p.ringOrder = p.ringOrder[1:]
+		}
+		ring = newHashRing(candidates)
+		p.rings[signature] = ring
+		p.ringOrder = append(p.ringOrder, signature)
+	}
+	return ring.get(hashKey)
+}
+
+type hashRing struct {
+	points  []uint64
+	targets map[uint64]Target
+}
+
+func ringSignature(targets []Target) string {
+	parts := make([]string, len(targets))
+	for idx, t := range targets {
+		parts[idx] = t.Addr + "=" + strconv.Itoa(t.Weight)
+	}
+	return strings.Join(parts, ",")
+}
+
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	// fnv of similar strings differs in low bits only, so the bits are mixed to spread points on ring
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	return x
+}
+
+func newHashRing(targets []Target) *hashRing {
+	totalWeight := 0
+	for _, t := range targets {
+		totalWeight += t.Weight
+	}
+
+	r := &hashRing{targets: make(map[uint64]Target)}
+	for _, t := range targets {
+		// Points are scaled down proportional to weights if there are too many, and each target has at least one
+		points := t.Weight * ringReplicas
+		if totalWeight*ringReplicas > maxRingPoints {
+			if points = t.Weight * maxRingPoints / totalWeight; points < 1 {
+				points = 1
+			}
+		}
+		for i := 0; i < points; i++ {
+			point := hashString(t.Addr + "#" + strconv.Itoa(i))
+			if _, ok := r.targets[point]; ok {
+				continue
+			}
+			r.targets[point] = t
+			r.points = append(r.points, point)
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+func (r *hashRing) get(key string) Target {
+	h := hashString(key)
+	idx := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if idx == len(r.points) {
+		idx = 0
+	}
+	return r.targets[r.points[idx]]
+}
@@ -0,0 +1,148 @@
+package balancer
+
+import (
+	"fmt"
+	"testing"
+)
+
+func pickCounts(t *testing.T, b *Balancer, strategy Strategy, targets []Target, n int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		target, release, err := b.Pick("service", strategy, targets, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+		counts[target.Addr]++
+	}
+	return counts
+}
+
+func TestRoundRobinAndWeighted(t *testing.T) {
+	targets := []Target{{Addr: "a", Weight: 3}, {Addr: "b", Weight: 1}}
+
+	if counts := pickCounts(t, New(), RoundRobin, targets, 8); counts["a"] != 4 || counts["b"] != 4 {
+		t.Errorf("expected even round robin, got %v", counts)
+	}
+	if counts := pickCounts(t, New(), Weighted, targets, 8); counts["a"] != 6 || counts["b"] != 2 {
+		t.Errorf("expected picks proportional to weights, got %v", counts)
+	}
+
+	// Smooth weighted round robin does not pick the heavy target in a burst
+	b := New()
+	sequence := ""
+	for i := 0; i < 4; i++ {
+		target, release, _ := b.Pick("service", Weighted, targets, "", nil)
+		release()
+		sequence += target.Addr
+	}
+	if sequence != "aaba" {
+		t.Errorf("expected smooth sequence aaba, got %s", sequence)
+	}
+}
+
+func TestLeastOutstanding(t *testing.T) {
+	b := New()
+	targets := []Target{{Addr: "a", Weight: 1}, {Addr: "b", Weight: 1}}
+
+	first, releaseFirst, _ := b.Pick("service", LeastOutstanding, targets, "", nil)
+	second, releaseSecond, _ := b.Pick("service", LeastOutstanding, targets, "", nil)
+	if first.Addr == second.Addr {
+		t.Fatalf("expected second request picks idle target, got %s twice", first.Addr)
+	}
+
+	// The busy target is avoided until released
+	releaseFirst()
+	third, _, _ := b.Pick("service", LeastOutstanding, targets, "", nil)
+	if third.Addr != first.Addr {
+		t.Errorf("expected released target %s picked, got %s", first.Addr, third.Addr)
+	}
+	releaseSecond()
+	releaseSecond()
+	if n := b.Outstanding("service", second.Addr); n != 0 {
+		t.Errorf("expected release only takes effect once, got %d outstanding", n)
+	}
+}
+
+func TestConsistentHash(t *testing.T) {
+	b := New()
+	targets := []Target{{Addr: "a", Weight: 1}, {Addr: "b", Weight: 1}, {Addr: "c", Weight: 1}}
+
+	picked := make(map[string]string)
+	for i := 0; i < 300; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		target, release, _ := b.Pick("service", ConsistentHash, targets, key, nil)
+		release()
+		picked[key] = target.Addr
+		if again, release, _ := b.Pick("service", ConsistentHash, targets, key, nil); again.Addr != target.Addr {
+			t.Fatalf("expected %s sticky to %s, got %s", key, target.Addr, again.Addr)
+		} else {
+			release()
+		}
+	}
+
+	// Only keys of unavailable target are remapped
+	withoutC := func(t Target) bool { return t.Addr != "c" }
+	moved := 0
+	for key, addr := range picked {
+		target, release, _ := b.Pick("service", ConsistentHash, targets, key, withoutC)
+		release()
+		if target.Addr == "c" {
+			t.Fatalf("expected unavailable target skipped")
+		}
+		if addr != "c" && target.Addr != addr {
+			moved++
+		}
+	}
+	if moved != 0 {
+		t.Errorf("expected keys of available targets unchanged, got %d moved", moved)
+	}
+}
+
+func TestConsistentHashRings(t *testing.T) {
+	b := New()
+	targets := []Target{{Addr: "a", Weight: 1 << 20}, {Addr: "b", Weight: 1}, {Addr: "c", Weight: 1}}
+
+	// Points of large weights are bounded, while light targets still get a point
+	b.Pick("service", ConsistentHash, targets, "key", nil)
+	ring := b.pools["service"].rings[ringSignature(targets)]
+	if len(ring.points) > maxRingPoints+len(targets) {
+		t.Errorf("expected at most %d points, got %d", maxRingPoints+len(targets), len(ring.points))
+	}
+	seen := map[string]bool{}
+	for _, target := range ring.targets {
+		seen[target.Addr] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("expected all targets on ring, got %v", seen)
+	}
+
+	// Rings of candidate sets are reused, such as retries skipping tried targets
+	skipA := func(t Target) bool { return t.Addr != "a" }
+	for i := 0; i < 4; i++ {
+		b.Pick("service", ConsistentHash, targets, "key", nil)
+		b.Pick("service", ConsistentHash, targets, "key", skipA)
+	}
+	if p := b.pools["service"]; len(p.rings) != 2 || p.rings[ringSignature(targets)] != ring {
+		t.Errorf("expected 2 rings reused, got %d", len(p.rings))
+	}
+
+	// Rings are bounded as well
+	for i := 0; i < maxRings*2; i++ {
+		b.Pick("service", ConsistentHash, []Target{{Addr: fmt.Sprintf("t%d", i), Weight: 1}}, "key", nil)
+	}
+	if p := b.pools["service"]; len(p.rings) != maxRings || len(p.ringOrder) != maxRings {
+		t.Errorf("expected %d rings kept, got %d", maxRings, len(p.rings))
+	}
+}
+
+func TestNoAvailableTarget(t *testing.T) {
+	targets := []Target{{Addr: "a", Weight: 1}, {Addr: "b", Weight: 0}}
+	none := func(t Target) bool { return false }
+	if _, _, err := New().Pick("service", RoundRobin, targets, "", none); err != ErrNoTarget {
+		t.Errorf("expected ErrNoTarget, got %v", err)
+	}
+	if _, _, err := New().Pick("service", RoundRobin, targets[1:], "", nil); err != ErrNoTarget {
+		t.Errorf("expected target with zero weight skipped, got %v", err)
+	}
+}
@@ -0,0 +1,336 @@
+package breaker
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned if circuit of target is open, or all half-open probes are in flight
+var ErrOpen = errors.New("breaker: circuit is open")
+
+// State is the state of a circuit
+type State int
+
+const (
+	// Closed passes requests, and failures are counted to trip the circuit
+	Closed State = iota
+	// Open rejects requests until open duration passed
+	Open
+	// HalfOpen passes limited probe requests, the circuit is closed after all probes succeeded
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half_open"
+	}
+	return "closed"
+}
+
+// windowBuckets is count of buckets of error rate window, old buckets are dropped as window slides
+const windowBuckets = 10
+
+// Config decides when circuits are tripped and how long they are kept open
+type Config struct {
+	ConsecutiveFailures int           // Trips after consecutive failures, 0 disables
+	ErrorRate           float64       // Trips if ratio of failures in window reached, 0 disables
+	Window              time.Duration // Length of error rate window
+	MinRequests         int           // Min requests in window before error rate is checked
+	OpenDuration        time.Duration // Open duration after first trip, which is multiplied by count of trips in a row
+	MaxOpenDuration     time.Duration // Cap of open duration, 0 means no cap
+	HalfOpenRequests    int           // Probes allowed in half-open state, all of them should succeed to close
+	// Targets whose error rate exceeds mean of other targets by OutlierErrorRate are ejected, 0 disables
+	OutlierErrorRate float64
+	// Max percentage of targets of a key ejected as outliers, circuits tripped by thresholds are not limited
+	MaxEjectionPercent int
+}
+
+// Status is the state and counters of a circuit
+type Status struct {
+	Target              string    `json:"target"`
+	State               string    `json:"state"`
+	Reason              string    `json:"reason,omitempty"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	WindowRequests      int       `json:"window_requests"`
+	WindowFailures      int       `json:"window_failures"`
+	OpenUntil           time.Time `json:"open_until,omitempty"`
+	TimesOpened         int64     `json:"times_opened"`
+	Rejected            int64     `json:"rejected"`
+}
+
+// StateChange is passed to Breakers.OnStateChange after state of a circuit changed
+type StateChange struct {
+	Key, Target string
+	From, To    State
+	Reason      string
+}
+
+// Breakers keeps a circuit for each target of keys, such as services
+type Breakers struct {
+	// OnStateChange is called with lock held, so it should not call methods of Breakers
+	OnStateChange func(change StateChange)
+
+	lock   sync.Mutex
+	groups map[string]map[string]*circuit
+	now    func() time.Time
+}
+
+type bucket struct {
+	start              time.Time
+	requests, failures int
+}
+
+type circuit struct {
+	state               State
+	reason              string
+	consecutiveFailures int
+	buckets             [windowBuckets]bucket
+	openUntil           time.Time
+	trips               int // Trips in a row, reset after closed
+	probes, successes   int // Probes in flight and succeeded in half-open state
+	generation          int // Incremented on state change, so results of requests allowed in previous state are ignored
+	timesOpened         int64
+	rejected            int64
+}
+
+func New() *Breakers {
+	return &Breakers{groups: make(map[string]map[string]*circuit), now: time.Now}
+}
+
+/*
+Allow checks circuit of target, and the returned done func should be called with result of the request:
+
+	done, err := b.Allow("service-1", "10.0.0.1:8080", config)
+	if err != nil {
+	    return err
+	}
+	err = forward()
+	done(err == nil)
+*/
+func (b *Breakers) Allow(key, target string, config Config) (func(success bool), error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	c := b.circuit(key, target)
+	if c.state == Open && !b.now().Before(c.openUntil) {
+		b.transit(key, target, c, HalfOpen, c.reason)
+	}
+
+	isProbe := false
+	switch c.state {
+	case Open:
+		c.rejected++
+		return nil, ErrOpen
+	case HalfOpen:
+		if c.probes >= halfOpenRequests(config) {
+			c.rejected++
+			return nil, ErrOpen
+		}
+		c.probes++
+		isProbe = true
+	}
+
+	reported := false
+	generation := c.generation
+	return func(success bool) {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		if reported || c.generation != generation {
+			return
+		}
+		reported = true
+		if isProbe {
+			c.probes--
+		}
+		b.record(key, target, c, config, success)
+	}, nil
+}
+
+// Available checks whether a request to target would be allowed, without taking a half-open probe
+func (b *Breakers) Available(key, target string, config Config) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	c, ok := b.groups[key][target]
+	if !ok {
+		return true
+	}
+	switch c.state {
+	case Open:
+		return !b.now().Before(c.openUntil)
+	case HalfOpen:
+		return c.probes < halfOpenRequests(config)
+	}
+	return true
+}
+
+// Status returns circuits of key sorted by target, targets never requested are not included
+func (b *Breakers) Status(key string, config Config) []Status {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	now := b.now()
+	rslt := make([]Status, 0, len(b.groups[key]))
+	for target, c := range b.groups[key] {
+		requests, failures := c.windowCounts(now, now.Add(-config.Window))
+		status := Status{
+			Target:              target,
+			State:               c.state.String(),
+			Reason:              c.reason,
+			ConsecutiveFailures: c.consecutiveFailures,
+			WindowRequests:      requests,
+			WindowFailures:      failures,
+			TimesOpened:         c.timesOpened,
+			Rejected:            c.rejected,
+		}
+		if c.state == Open {
+			status.OpenUntil = c.openUntil
+		}
+		rslt = append(rslt, status)
+	}
+	sort.Slice(rslt, func(i, j int) bool { return rslt[i].Target < rslt[j].Target })
+	return rslt
+}
+
+func halfOpenRequests(config Config) int {
+	if config.HalfOpenRequests <= 0 {
+		return 1
+	}
+	return config.HalfOpenRequests
+}
+
+func (b *Breakers) circuit(key, target string) *circuit {
+	group, ok := b.groups[key]
+	if !ok {
+		group = make(map[string]*circuit)
+		b.groups[key] = group
+	}
+	c, ok := group[target]
+	if !ok {
+		c = &circuit{}
+		group[target] = c
+	}
+	return c
+}
+
+func (b *Breakers) record(key, target string, c *circuit, config Config, success bool) {
+	now := b.now()
+
+	if c.state == HalfOpen {
+		if !success {
+			b.trip(key, target, c, config, "half-open probe failed")
+			return
+		}
+		if c.successes++; c.successes >= halfOpenRequests(config) {
+			c.trips = 0
+			b.transit(key, target, c, Closed, "")
+		}
+		return
+	}
+
+	c.add(now, config.Window, success)
+	if success {
+		c.consecutiveFailures = 0
+		return
+	}
+	c.consecutiveFailures++
+
+	if config.ConsecutiveFailures > 0 && c.consecutiveFailures >= config.ConsecutiveFailures {
+		b.trip(key, target, c, config, "consecutive failures")
+		return
+	}
+	requests, failures := c.windowCounts(now, now.Add(-config.Window))
+	if requests < config.MinRequests || requests == 0 {
+		return
+	}
+	rate := float64(failures) / float64(requests)
+	if config.ErrorRate > 0 && rate >= config.ErrorRate {
+		b.trip(key, target, c, config, "error rate")
+		return
+	}
+	if config.OutlierErrorRate > 0 && b.isOutlier(key, target, config, now, rate) {
+		b.trip(key, target, c, config, "outlier")
+	}
+}
+
+// isOutlier compares error rate of target with mean error rate of other closed targets, and checks max ejection percent
+func (b *Breakers) isOutlier(key, target string, config Config, now time.Time, rate float64) bool {
+	group := b.groups[key]
+	ejected, others := 0, 0
+	sum := 0.0
+	for t, c := range group {
+		if c.state != Closed {
+			ejected++
+			continue
+		}
+		if t == target {
+			continue
+		}
+		requests, failures := c.windowCounts(now, now.Add(-config.Window))
+		if requests >= config.MinRequests && requests > 0 {
+			sum += float64(failures) / float64(requests)
+			others++
+		}
+	}
+	if others == 0 || rate-sum/float64(others) < config.OutlierErrorRate {
+		return false
+	}
+	return (ejected+1)*100 <= config.MaxEjectionPercent*len(group)
+}
+
+func (b *Breakers) trip(key, target string, c *circuit, config Config, reason string) {
+	c.trips++
+	openDuration := config.OpenDuration * time.Duration(c.trips)
+	if config.MaxOpenDuration > 0 && openDuration > config.MaxOpenDuration {
+		openDuration = config.MaxOpenDuration
+	}
+	c.openUntil = b.now().Add(openDuration)
+	c.timesOpened++
+	b.transit(key, target, c, Open, reason)
+}
+
+// transit changes state of circuit, and counters of previous state are reset
+func (b *Breakers) transit(key, target string, c *circuit, to State, reason string) {
+	from := c.state
+	c.state = to
+	c.reason = reason
+	c.consecutiveFailures = 0
+	c.probes, c.successes = 0, 0
+	c.generation++
+	c.buckets = [windowBuckets]bucket{}
+	if b.OnStateChange != nil {
+		b.OnStateChange(StateChange{Key: key, Target: target, From: from, To: to, Reason: reason})
+	}
+}
+
+// add counts result in bucket of now, the window is split into windowBuckets buckets
+func (c *circuit) add(now time.Time, window time.Duration, success bool) {
+	size := window / windowBuckets
+	if size <= 0 {
+		size = time.Millisecond
+	}
+	start := now.Truncate(size)
+	bkt := &c.buckets[(start.UnixNano()/int64(size))%windowBuckets]
+	if !bkt.start.Equal(start) {
+		*bkt = bucket{start: start}
+	}
+	bkt.requests++
+	if !success {
+		bkt.failures++
+	}
+}
+
+// windowCounts sums buckets started in (since, now]
+func (c *circuit) windowCounts(now, since time.Time) (requests, failures int) {
+	for _, bkt := range c.buckets {
+		if bkt.start.IsZero() || !bkt.start.After(since) || bkt.start.After(now) {
+			continue
+		}
+		requests += bkt.requests
+		failures += bkt.failures
+	}
+	return requests, failures
+}
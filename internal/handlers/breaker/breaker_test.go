@@ -0,0 +1,155 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is advanced by test instead of sleeping
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newTestBreakers() (*Breakers, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1600000000, 0)}
+	b := New()
+	b.now = clock.now
+	return b, clock
+}
+
+func request(t *testing.T, b *Breakers, target string, config Config, success bool) {
+	done, err := b.Allow("service", target, config)
+	if err != nil {
+		t.Fatalf("expected request to %s allowed, got %v", target, err)
+	}
+	done(success)
+}
+
+func TestConsecutiveFailuresAndHalfOpen(t *testing.T) {
+	b, clock := newTestBreakers()
+	changes := []StateChange{}
+	b.OnStateChange = func(change StateChange) { changes = append(changes, change) }
+	config := Config{ConsecutiveFailures: 3, Window: 10 * time.Second, OpenDuration: time.Second, MaxOpenDuration: 3 * time.Second}
+
+	request(t, b, "a", config, false)
+	request(t, b, "a", config, false)
+	request(t, b, "a", config, true)
+	request(t, b, "a", config, false)
+	request(t, b, "a", config, false)
+	if !b.Available("service", "a", config) {
+		t.Fatal("expected success resets consecutive failures")
+	}
+	request(t, b, "a", config, false)
+	if b.Available("service", "a", config) {
+		t.Fatal("expected circuit open after 3 consecutive failures")
+	}
+	if _, err := b.Allow("service", "a", config); err != ErrOpen {
+		t.Fatalf("expected ErrOpen, got %v", err)
+	}
+
+	// Only one probe allowed in half-open state, and failed probe opens circuit for longer
+	clock.t = clock.t.Add(time.Second)
+	probe, err := b.Allow("service", "a", config)
+	if err != nil {
+		t.Fatalf("expected probe allowed, got %v", err)
+	}
+	if _, err := b.Allow("service", "a", config); err != ErrOpen {
+		t.Fatalf("expected second probe rejected, got %v", err)
+	}
+	probe(false)
+	clock.t = clock.t.Add(time.Second)
+	if b.Available("service", "a", config) {
+		t.Fatal("expected open duration doubled after failed probe")
+	}
+
+	clock.t = clock.t.Add(time.Second)
+	request(t, b, "a", config, true)
+	status := b.Status("service", config)
+	if len(status) != 1 || status[0].State != "closed" || status[0].TimesOpened != 2 || status[0].Rejected != 2 {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	states := ""
+	for _, change := range changes {
+		states += change.To.String() + ","
+	}
+	if states != "open,half_open,open,half_open,closed," {
+		t.Errorf("unexpected state changes %s", states)
+	}
+}
+
+func TestErrorRateWindow(t *testing.T) {
+	b, clock := newTestBreakers()
+	config := Config{ErrorRate: 0.5, Window: 10 * time.Second, MinRequests: 10, OpenDuration: time.Second}
+
+	// Failures alternate with successes, so only error rate trips the circuit
+	for i := 0; i < 9; i++ {
+		request(t, b, "a", config, i%2 == 0)
+	}
+	if !b.Available("service", "a", config) {
+		t.Fatal("expected error rate not checked before min requests")
+	}
+
+	// Failures slid out of window are not counted
+	clock.t = clock.t.Add(11 * time.Second)
+	for i := 0; i < 9; i++ {
+		request(t, b, "a", config, true)
+	}
+	request(t, b, "a", config, false)
+	if !b.Available("service", "a", config) {
+		t.Fatal("expected old failures dropped from window")
+	}
+
+	// 9 failures of 18 requests reach the error rate
+	for i := 0; i < 7; i++ {
+		request(t, b, "a", config, false)
+	}
+	if !b.Available("service", "a", config) {
+		t.Fatal("expected circuit closed below error rate")
+	}
+	request(t, b, "a", config, false)
+	if b.Available("service", "a", config) {
+		t.Fatal("expected circuit open after error rate reached")
+	}
+}
+
+func TestOutlierEjection(t *testing.T) {
+	b, _ := newTestBreakers()
+	config := Config{Window: 10 * time.Second, MinRequests: 10, OpenDuration: time.Second, OutlierErrorRate: 0.3, MaxEjectionPercent: 50}
+
+	for i := 0; i < 10; i++ {
+		for _, target := range []string{"b", "c", "d", "a"} {
+			// a fails 40%, others fail 10%
+			request(t, b, target, config, !(i == 0 || (target == "a" && i >= 6)))
+		}
+	}
+	for _, target := range []string{"b", "c", "d"} {
+		if !b.Available("service", target, config) {
+			t.Errorf("expected %s not ejected", target)
+		}
+	}
+	if b.Available("service", "a", config) {
+		t.Fatal("expected outlier ejected")
+	}
+	if status := b.Status("service", config); status[0].Reason != "outlier" {
+		t.Errorf("unexpected status %+v", status[0])
+	}
+
+	// Max ejection percent keeps at least half of targets in rotation
+	for i := 0; i < 30; i++ {
+		for _, target := range []string{"b", "c"} {
+			if done, err := b.Allow("service", target, config); err == nil {
+				done(false)
+			}
+		}
+	}
+	ejected := 0
+	for _, target := range []string{"b", "c", "d"} {
+		if !b.Available("service", target, config) {
+			ejected++
+		}
+	}
+	if ejected != 1 {
+		t.Errorf("expected only 1 more target ejected, got %d", ejected)
+	}
+}
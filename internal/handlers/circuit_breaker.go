@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/handlers/breaker"
+	"apron.network/gateway/internal/models"
+)
+
+// toBreakerConfig converts circuit breaker declared in service to config used by breaker.Breakers, with defaults of unset fields.
+// Trip conditions set to models.CircuitBreakerDisabled are converted to 0, which disables them in breaker.Config.
+func toBreakerConfig(b *models.CircuitBreaker) breaker.Config {
+	orDefault := func(value, defaultValue int64) int64 {
+		switch value {
+		case 0:
+			return defaultValue
+		case models.CircuitBreakerDisabled:
+			return 0
+		}
+		return value
+	}
+	return breaker.Config{
+		ConsecutiveFailures: int(orDefault(int64(b.ConsecutiveFailures), 5)),
+		ErrorRate:           float64(orDefault(int64(b.ErrorRatePercent), 50)) / 100,
+		Window:              time.Duration(orDefault(b.WindowMs, 10000)) * time.Millisecond,
+		MinRequests:         int(orDefault(int64(b.MinRequests), 20)),
+		OpenDuration:        time.Duration(orDefault(b.OpenMs, 10000)) * time.Millisecond,
+		MaxOpenDuration:     time.Duration(orDefault(b.MaxOpenMs, 300000)) * time.Millisecond,
+		HalfOpenRequests:    int(orDefault(int64(b.HalfOpenRequests), 1)),
+		OutlierErrorRate:    float64(b.OutlierErrorRatePercent) / 100,
+		MaxEjectionPercent:  int(orDefault(int64(b.MaxEjectionPercent), 50)),
+	}
+}
+
+// logCircuitStateChange writes state change of circuit to gateway log
+func (h *ProxyHandler) logCircuitStateChange(change breaker.StateChange) {
+	h.Logger.Log(fmt.Sprintf("%s|circuit %s|service: %s, target: %s, from: %s, reason: %s\n",
+		time.Now().UTC().Format("2006-01-02 15:04:05"),
+		change.To,
+		change.Key,
+		change.Target,
+		change.From,
+		change.Reason,
+	))
+}
+
+// serviceCircuitsHandler responds circuit state and counters of upstream targets of the service
+func (h *ManagerHandler) serviceCircuitsHandler(ctx *fasthttp.RequestCtx) {
+	serviceId := ctx.UserValue("service_name").(string)
+	if h.Breakers == nil {
+		internal.WriteErrorResponse(ctx, internal.NotFoundError("circuit breaker is not enabled"))
+		return
+	}
+
+	binaryService, err := h.storageManager.GetRecord(internal.ServiceBucketName, serviceId)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	service := models.ApronService{}
+	if err := proto.Unmarshal([]byte(binaryService), &service); err != nil {
+		internal.WriteErrorResponse(ctx, internal.InternalError(err))
+		return
+	}
+	if service.CircuitBreaker == nil {
+		internal.WriteErrorResponse(ctx, internal.NotFoundError("no circuit breaker declared for service %s", serviceId))
+		return
+	}
+
+	respBody, err := json.Marshal(h.Breakers.Status(serviceId, toBreakerConfig(service.CircuitBreaker)))
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	ctx.SetContentType("application/json")
+	ctx.Write(respBody)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/handlers/breaker"
+	"apron.network/gateway/internal/models"
+)
+
+func TestProxyHandlerCircuitBreaker(t *testing.T) {
+	storageManager := models.NewMemoryStorageManager()
+	alive := startEchoUpstream(t)
+	dead := closedAddr(t)
+
+	circuitBreaker := &models.CircuitBreaker{ConsecutiveFailures: 2, OpenMs: 60000}
+	for _, service := range []*models.ApronService{
+		{Id: "dead_service", Schema: "http", BaseUrl: dead, CircuitBreaker: circuitBreaker},
+		{Id: "mixed_service", Schema: "http", Upstreams: []*models.UpstreamTarget{{BaseUrl: alive}, {BaseUrl: dead}}, CircuitBreaker: circuitBreaker},
+	} {
+		if err := validateServiceSettings(service); err != nil {
+			t.Fatal(err)
+		}
+		binaryService, _ := proto.Marshal(service)
+		storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+		binaryKey, _ := proto.Marshal(&models.ApronApiKey{Key: "key_0", ServiceId: service.Id})
+		storageManager.SaveBinaryKeyData(internal.ServiceApiKeyStorageBucketName(service.Id), models.HashApiKey("key_0"), binaryKey)
+	}
+	proxy := newTestProxyHandler(t, storageManager)
+	manager := newTestManagerHandler(storageManager, proxy)
+
+	for i := 0; i < 2; i++ {
+		if ctx := serveProxy(proxy, "dead_service", "key_0", "/anything"); ctx.Response.StatusCode() != fasthttp.StatusBadGateway {
+			t.Fatalf("expected upstream failure before circuit open, got %d", ctx.Response.StatusCode())
+		}
+	}
+	ctx := serveProxy(proxy, "dead_service", "key_0", "/anything")
+	if ctx.Response.StatusCode() != fasthttp.StatusServiceUnavailable || !strings.Contains(string(ctx.Response.Body()), internal.ErrCodeCircuitOpen) {
+		t.Fatalf("expected circuit_open error, got %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	// Requests are balanced to the other target after circuit of dead target opened
+	failures := 0
+	for i := 0; i < 8; i++ {
+		if serveProxy(proxy, "mixed_service", "key_0", "/anything").Response.StatusCode() != fasthttp.StatusOK {
+			failures++
+		}
+	}
+	if failures != 2 {
+		t.Errorf("expected 2 failures before circuit open, got %d", failures)
+	}
+
+	ctx = serveAdmin(manager, "GET", "/service/mixed_service/circuits", "")
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("expected circuit status, got %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	statuses := []breaker.Status{}
+	if err := json.Unmarshal(ctx.Response.Body(), &statuses); err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if (status.State == "open") != (status.Target == dead) {
+			t.Errorf("unexpected status %+v", status)
+		}
+	}
+
+	if err := models.ValidateCircuitBreaker(&models.CircuitBreaker{ErrorRatePercent: 120}); err == nil {
+		t.Error("expected invalid error rate rejected")
+	}
+}
+
+func TestToBreakerConfigDisabledTrips(t *testing.T) {
+	config := toBreakerConfig(&models.CircuitBreaker{})
+	if config.ConsecutiveFailures != 5 || config.ErrorRate != 0.5 {
+		t.Errorf("expected default trip conditions, got %d and %v", config.ConsecutiveFailures, config.ErrorRate)
+	}
+
+	disabled := &models.CircuitBreaker{
+		ConsecutiveFailures: models.CircuitBreakerDisabled,
+		ErrorRatePercent:    models.CircuitBreakerDisabled,
+	}
+	if err := models.ValidateCircuitBreaker(disabled); err != nil {
+		t.Fatal(err)
+	}
+	config = toBreakerConfig(disabled)
+	if config.ConsecutiveFailures != 0 || config.ErrorRate != 0 {
+		t.Errorf("expected disabled trip conditions, got %d and %v", config.ConsecutiveFailures, config.ErrorRate)
+	}
+
+	for _, invalid := range []*models.CircuitBreaker{{ConsecutiveFailures: -2}, {ErrorRatePercent: -2}, {ErrorRatePercent: 101}} {
+		if models.ValidateCircuitBreaker(invalid) == nil {
+			t.Errorf("expected %v to be rejected", invalid)
+		}
+	}
+}
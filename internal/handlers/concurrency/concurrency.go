@@ -0,0 +1,129 @@
+package concurrency
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrLimitExceeded is returned if max in flight reached and the wait queue is full
+	ErrLimitExceeded = errors.New("concurrency: max in flight requests reached")
+	// ErrQueueTimeout is returned if no slot released before queue timeout
+	ErrQueueTimeout = errors.New("concurrency: timeout while waiting in queue")
+)
+
+// Policy limits in flight requests of a key
+type Policy struct {
+	MaxInFlight  int           // Max requests processed at the same time
+	MaxQueue     int           // Max requests waiting for a slot, requests are rejected immediately if 0
+	QueueTimeout time.Duration // Max duration a request waits in queue
+}
+
+// Limiter limits in flight requests with a semaphore for each key.
+// The semaphore is removed after all slots released, so only active keys are kept in memory.
+type Limiter struct {
+	lock       sync.Mutex
+	semaphores map[string]*semaphore
+}
+
+type semaphore struct {
+	inFlight int
+	waiters  []chan struct{}
+}
+
+func New() *Limiter {
+	return &Limiter{semaphores: make(map[string]*semaphore)}
+}
+
+/*
+Acquire takes a slot of key, and the returned release func should be called after the request finished:
+
+	release, err := limiter.Acquire("service-1", Policy{MaxInFlight: 10, MaxQueue: 100, QueueTimeout: time.Second})
+	if err != nil {
+	    return err
+	}
+	defer release()
+*/
+func (l *Limiter) Acquire(key string, policy Policy) (func(), error) {
+	l.lock.Lock()
+	s, ok := l.semaphores[key]
+	if !ok {
+		s = &semaphore{}
+		l.semaphores[key] = s
+	}
+
+	if s.inFlight < policy.MaxInFlight {
+		s.inFlight++
+		l.lock.Unlock()
+		return l.releaseFunc(key, s), nil
+	}
+	if len(s.waiters) >= policy.MaxQueue {
+		l.lock.Unlock()
+		return nil, ErrLimitExceeded
+	}
+
+	ch := make(chan struct{})
+	s.waiters = append(s.waiters, ch)
+	l.lock.Unlock()
+
+	timer := time.NewTimer(policy.QueueTimeout)
+	defer timer.Stop()
+	select {
+	case <-ch:
+		return l.releaseFunc(key, s), nil
+	case <-timer.C:
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for idx, waiter := range s.waiters {
+		if waiter == ch {
+			s.waiters = append(s.waiters[:idx], s.waiters[idx+1:]...)
+			return nil, ErrQueueTimeout
+		}
+	}
+	// The slot is handed over while timeout, so it is owned by this request
+	return l.releaseFunc(key, s), nil
+}
+
+// InFlight returns count of requests holding slot of key
+func (l *Limiter) InFlight(key string) int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if s, ok := l.semaphores[key]; ok {
+		return s.inFlight
+	}
+	return 0
+}
+
+// Waiting returns count of requests waiting in queue of key
+func (l *Limiter) Waiting(key string) int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if s, ok := l.semaphores[key]; ok {
+		return len(s.waiters)
+	}
+	return 0
+}
+
+// releaseFunc returns func releasing the slot, which hands over the slot to the first waiter if any
+func (l *Limiter) releaseFunc(key string, s *semaphore) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.lock.Lock()
+			defer l.lock.Unlock()
+
+			if len(s.waiters) > 0 {
+				close(s.waiters[0])
+				s.waiters = s.waiters[1:]
+				return
+			}
+			s.inFlight--
+			if s.inFlight <= 0 {
+				delete(l.semaphores, key)
+			}
+		})
+	}
+}
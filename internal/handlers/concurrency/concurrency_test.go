@@ -0,0 +1,77 @@
+package concurrency
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterAcquire(t *testing.T) {
+	l := New()
+	policy := Policy{MaxInFlight: 2}
+
+	release1, err := l.Acquire("key", policy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	release2, err := l.Acquire("key", policy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := l.Acquire("key", policy); err != ErrLimitExceeded {
+		t.Errorf("expected ErrLimitExceeded, got %v", err)
+	}
+	// Other keys are not affected
+	if _, err := l.Acquire("other", policy); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Release twice takes effect only once
+	release1()
+	release1()
+	if inFlight := l.InFlight("key"); inFlight != 1 {
+		t.Errorf("expected 1 in flight, got %d", inFlight)
+	}
+	release2()
+	if inFlight := l.InFlight("key"); inFlight != 0 {
+		t.Errorf("expected 0 in flight, got %d", inFlight)
+	}
+}
+
+func TestLimiterQueue(t *testing.T) {
+	l := New()
+	policy := Policy{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: time.Second}
+
+	release, err := l.Acquire("key", policy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	acquired := make(chan error)
+	go func() {
+		_, err := l.Acquire("key", policy)
+		acquired <- err
+	}()
+
+	// Wait until the request is queued, then the queue is full
+	for start := time.Now(); l.Waiting("key") == 0; time.Sleep(time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatal("request not queued")
+		}
+	}
+	if _, err := l.Acquire("key", policy); err != ErrLimitExceeded {
+		t.Errorf("expected ErrLimitExceeded, got %v", err)
+	}
+
+	// Slot is handed over to queued request
+	release()
+	if err := <-acquired; err != nil {
+		t.Errorf("expected queued request acquired slot, got %v", err)
+	}
+	if inFlight := l.InFlight("key"); inFlight != 1 {
+		t.Errorf("expected 1 in flight, got %d", inFlight)
+	}
+
+	if _, err := l.Acquire("key", Policy{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: 10 * time.Millisecond}); err != ErrQueueTimeout {
+		t.Errorf("expected ErrQueueTimeout, got %v", err)
+	}
+}
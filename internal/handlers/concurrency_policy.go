@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"time"
+
+	"apron.network/gateway/internal/handlers/concurrency"
+	"apron.network/gateway/internal/models"
+)
+
+// toConcurrencyPolicy converts policy declared in service or key to policy used by concurrency.Limiter
+func toConcurrencyPolicy(p *models.ConcurrencyPolicy) concurrency.Policy {
+	return concurrency.Policy{
+		MaxInFlight:  int(p.MaxInFlight),
+		MaxQueue:     int(p.MaxQueue),
+		QueueTimeout: time.Duration(p.QueueTimeoutMs) * time.Millisecond,
+	}
+}
@@ -0,0 +1,204 @@
+package health
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Config decides how often targets are probed, and when health of a target changes
+type Config struct {
+	Interval           time.Duration
+	Timeout            time.Duration // Passed to probe, which should return before timeout
+	HealthyThreshold   int           // Consecutive successes to mark unhealthy target healthy
+	UnhealthyThreshold int           // Consecutive failures to mark healthy target unhealthy
+}
+
+// Probe checks a single target, nil error means the target is alive
+type Probe func(target string, timeout time.Duration) error
+
+// Status is the health state of a target
+type Status struct {
+	Target               string    `json:"target"`
+	Healthy              bool      `json:"healthy"`
+	ConsecutiveSuccesses int       `json:"consecutive_successes"`
+	ConsecutiveFailures  int       `json:"consecutive_failures"`
+	LastCheck            time.Time `json:"last_check"`
+	LastError            string    `json:"last_error,omitempty"`
+}
+
+// Checker probes targets of keys, such as services, in background and keeps health state of each target.
+// Targets are healthy before enough failures observed, so requests are not rejected right after watched.
+type Checker struct {
+	lock    sync.Mutex
+	watches map[string]*watch
+}
+
+type watch struct {
+	config  Config
+	probe   Probe
+	targets map[string]*Status
+	stop    chan struct{}
+}
+
+func New() *Checker {
+	return &Checker{watches: make(map[string]*watch)}
+}
+
+/*
+Watch starts probing targets of key every interval, calling Watch again for the same key updates
+config, probe and targets, while state of targets still watched is kept:
+
+	checker.Watch("service-1", health.Config{Interval: 10 * time.Second, Timeout: 2 * time.Second,
+	    HealthyThreshold: 2, UnhealthyThreshold: 3}, []string{"10.0.0.1:8080", "10.0.0.2:8080"}, probe)
+*/
+func (c *Checker) Watch(key string, config Config, targets []string, probe Probe) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	w, ok := c.watches[key]
+	if !ok {
+		w = &watch{targets: make(map[string]*Status), stop: make(chan struct{})}
+		c.watches[key] = w
+		defer func() { go c.run(key, w) }()
+	}
+	w.config = config
+	w.probe = probe
+
+	watched := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		watched[target] = true
+		if _, ok := w.targets[target]; !ok {
+			w.targets[target] = &Status{Target: target, Healthy: true}
+		}
+	}
+	for target := range w.targets {
+		if !watched[target] {
+			delete(w.targets, target)
+		}
+	}
+}
+
+// Unwatch stops probing targets of key, and targets of key are treated as healthy after that
+func (c *Checker) Unwatch(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if w, ok := c.watches[key]; ok {
+		close(w.stop)
+		delete(c.watches, key)
+	}
+}
+
+// Keys returns keys being watched
+func (c *Checker) Keys() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	keys := make([]string, 0, len(c.watches))
+	for key := range c.watches {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// IsHealthy returns health of target of key, targets not watched are healthy
+func (c *Checker) IsHealthy(key, target string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if w, ok := c.watches[key]; ok {
+		if status, ok := w.targets[target]; ok {
+			return status.Healthy
+		}
+	}
+	return true
+}
+
+// Status returns state of targets of key sorted by target, false is returned if key not watched
+func (c *Checker) Status(key string) ([]Status, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	w, ok := c.watches[key]
+	if !ok {
+		return nil, false
+	}
+	rslt := make([]Status, 0, len(w.targets))
+	for _, status := range w.targets {
+		rslt = append(rslt, *status)
+	}
+	sort.Slice(rslt, func(i, j int) bool { return rslt[i].Target < rslt[j].Target })
+	return rslt, true
+}
+
+func (c *Checker) run(key string, w *watch) {
+	for {
+		c.checkTargets(key, w)
+
+		c.lock.Lock()
+		interval := w.config.Interval
+		c.lock.Unlock()
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-w.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// checkTargets probes all targets of key concurrently, and applies results after all probes returned
+func (c *Checker) checkTargets(key string, w *watch) {
+	c.lock.Lock()
+	config, probe := w.config, w.probe
+	targets := make([]string, 0, len(w.targets))
+	for target := range w.targets {
+		targets = append(targets, target)
+	}
+	c.lock.Unlock()
+
+	errs := make([]error, len(targets))
+	wg := sync.WaitGroup{}
+	for idx, target := range targets {
+		wg.Add(1)
+		go func(idx int, target string) {
+			defer wg.Done()
+			errs[idx] = probe(target, config.Timeout)
+		}(idx, target)
+	}
+	wg.Wait()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	select {
+	case <-w.stop:
+		return
+	default:
+	}
+	now := time.Now()
+	for idx, target := range targets {
+		status, ok := w.targets[target]
+		if !ok {
+			// Target removed while probing
+			continue
+		}
+		status.LastCheck = now
+		if err := errs[idx]; err != nil {
+			status.LastError = err.Error()
+			status.ConsecutiveSuccesses = 0
+			status.ConsecutiveFailures++
+			if status.Healthy && status.ConsecutiveFailures >= config.UnhealthyThreshold {
+				status.Healthy = false
+				log.Printf("Upstream target %s of %s is unhealthy: %v", target, key, err)
+			}
+		} else {
+			status.LastError = ""
+			status.ConsecutiveFailures = 0
+			status.ConsecutiveSuccesses++
+			if !status.Healthy && status.ConsecutiveSuccesses >= config.HealthyThreshold {
+				status.Healthy = true
+				log.Printf("Upstream target %s of %s is healthy", target, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package health
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTargets is a probe whose result of each target can be switched by test
+type fakeTargets struct {
+	lock sync.Mutex
+	down map[string]bool
+}
+
+func (f *fakeTargets) set(target string, down bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.down[target] = down
+}
+
+func (f *fakeTargets) probe(target string, timeout time.Duration) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.down[target] {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func waitFor(t *testing.T, cond func() bool, msg string) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestTargetHealthFollowsThresholds(t *testing.T) {
+	f := &fakeTargets{down: map[string]bool{"b": true}}
+	c := New()
+	defer c.Unwatch("service")
+
+	config := Config{Interval: 10 * time.Millisecond, Timeout: time.Millisecond, HealthyThreshold: 2, UnhealthyThreshold: 3}
+	c.Watch("service", config, []string{"a", "b"}, f.probe)
+
+	// Targets start healthy
+	if !c.IsHealthy("service", "b") {
+		t.Fatal("expected target healthy before checked")
+	}
+	waitFor(t, func() bool { return !c.IsHealthy("service", "b") }, "expected failing target marked unhealthy")
+	if !c.IsHealthy("service", "a") {
+		t.Error("expected alive target kept healthy")
+	}
+
+	statuses, ok := c.Status("service")
+	if !ok || len(statuses) != 2 || statuses[1].Target != "b" || statuses[1].ConsecutiveFailures < 3 || statuses[1].LastError == "" {
+		t.Errorf("unexpected statuses: %+v", statuses)
+	}
+
+	f.set("b", false)
+	waitFor(t, func() bool { return c.IsHealthy("service", "b") }, "expected recovered target marked healthy")
+}
+
+func TestWatchUpdatesTargets(t *testing.T) {
+	f := &fakeTargets{down: map[string]bool{"a": true}}
+	c := New()
+	config := Config{Interval: 10 * time.Millisecond, HealthyThreshold: 1, UnhealthyThreshold: 1}
+	c.Watch("service", config, []string{"a"}, f.probe)
+	waitFor(t, func() bool { return !c.IsHealthy("service", "a") }, "expected failing target marked unhealthy")
+
+	// State of kept target is not reset, and new target starts healthy
+	c.Watch("service", config, []string{"a", "c"}, f.probe)
+	if c.IsHealthy("service", "a") || !c.IsHealthy("service", "c") {
+		t.Error("expected state of kept target unchanged and new target healthy")
+	}
+
+	c.Unwatch("service")
+	if !c.IsHealthy("service", "a") {
+		t.Error("expected targets not watched treated as healthy")
+	}
+	if _, ok := c.Status("service"); ok {
+		t.Error("expected no status after unwatched")
+	}
+}
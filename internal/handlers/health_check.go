@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/golang/protobuf/proto"
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/handlers/health"
+	"apron.network/gateway/internal/models"
+)
+
+// healthCheckConfig converts health check declared in service to config used by health.Checker
+func healthCheckConfig(check *models.HealthCheck) health.Config {
+	healthy, unhealthy := models.HealthCheckThresholds(check)
+	return health.Config{
+		Interval:           models.HealthCheckInterval(check),
+		Timeout:            models.HealthCheckTimeout(check),
+		HealthyThreshold:   healthy,
+		UnhealthyThreshold: unhealthy,
+	}
+}
+
+// newHealthProbe builds probe of health check declared in service, targets are base urls of upstream targets
+func newHealthProbe(service *models.ApronService) health.Probe {
+	check := service.HealthCheck
+	isWebsocket := service.Schema == "ws" || service.Schema == "wss"
+
+	// Http and jsonrpc checks of websocket services are sent to the same host with http
+	httpSchema := service.Schema
+	switch service.Schema {
+	case "ws":
+		httpSchema = "http"
+	case "wss":
+		httpSchema = "https"
+	}
+
+	switch models.HealthCheckType(check) {
+	case models.HealthCheckTypeWebsocket:
+		return func(target string, timeout time.Duration) error {
+			conn, err := dialHealthCheckWebsocket(fmt.Sprintf("%s://%s%s", service.Schema, target, check.Path), timeout)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		}
+	case models.HealthCheckTypeJsonRpc:
+		reqBody, _ := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      1,
+			"method":  models.HealthCheckJsonRpcMethod(check),
+			"params":  []interface{}{},
+		})
+		if isWebsocket {
+			return func(target string, timeout time.Duration) error {
+				return probeJsonRpcWebsocket(fmt.Sprintf("%s://%s%s", service.Schema, target, check.Path), reqBody, timeout)
+			}
+		}
+		return func(target string, timeout time.Duration) error {
+			return probeJsonRpcHttp(fmt.Sprintf("%s://%s%s", httpSchema, target, check.Path), reqBody, timeout)
+		}
+	}
+
+	expectedStatus := models.HealthCheckExpectedStatus(check)
+	return func(target string, timeout time.Duration) error {
+		req := fasthttp.AcquireRequest()
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseRequest(req)
+		defer fasthttp.ReleaseResponse(resp)
+
+		req.SetRequestURI(fmt.Sprintf("%s://%s%s", httpSchema, target, check.Path))
+		req.Header.SetMethod(fasthttp.MethodGet)
+		if err := fasthttp.DoTimeout(req, resp, timeout); err != nil {
+			return err
+		}
+		if resp.StatusCode() != expectedStatus {
+			return fmt.Errorf("unexpected status %d, expected %d", resp.StatusCode(), expectedStatus)
+		}
+		return nil
+	}
+}
+
+func dialHealthCheckWebsocket(urlStr string, timeout time.Duration) (*websocket.Conn, error) {
+	dialer := websocket.Dialer{HandshakeTimeout: timeout}
+	conn, _, err := dialer.Dial(urlStr, nil)
+	return conn, err
+}
+
+func probeJsonRpcHttp(urlStr string, reqBody []byte, timeout time.Duration) error {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(urlStr)
+	req.Header.SetMethod(fasthttp.MethodPost)
+	req.Header.SetContentType("application/json")
+	req.SetBody(reqBody)
+	if err := fasthttp.DoTimeout(req, resp, timeout); err != nil {
+		return err
+	}
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode())
+	}
+	return checkJsonRpcResponse(resp.Body())
+}
+
+func probeJsonRpcWebsocket(urlStr string, reqBody []byte, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	conn, err := dialHealthCheckWebsocket(urlStr, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetWriteDeadline(deadline)
+	if err := conn.WriteMessage(websocket.TextMessage, reqBody); err != nil {
+		return err
+	}
+	conn.SetReadDeadline(deadline)
+	_, msg, err := conn.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return checkJsonRpcResponse(msg)
+}
+
+// checkJsonRpcResponse accepts response with result and without error
+func checkJsonRpcResponse(body []byte) error {
+	resp := struct {
+		Result json.RawMessage `json:"result"`
+		Error  json.RawMessage `json:"error"`
+	}{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("invalid jsonrpc response: %v", err)
+	}
+	if len(resp.Error) > 0 && !bytes.Equal(resp.Error, []byte("null")) {
+		return fmt.Errorf("jsonrpc error: %s", resp.Error)
+	}
+	if len(resp.Result) == 0 {
+		return fmt.Errorf("jsonrpc response without result")
+	}
+	return nil
+}
+
+// StartHealthChecks loads services every interval, and keeps targets of services with health check watched by HealthChecker
+func (h *ProxyHandler) StartHealthChecks(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for {
+			if err := h.SyncHealthChecks(); err != nil {
+				log.Printf("Failed to sync health checks: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// SyncHealthChecks watches targets of services declaring health check, and stops watching removed services
+func (h *ProxyHandler) SyncHealthChecks() error {
+	checked := make(map[string]bool)
+	cursor := 0
+	for {
+		rcds, nextCursor, _, err := h.StorageManager.FetchRecords(internal.ServiceBucketName, cursor, "", 100)
+		if err != nil {
+			return err
+		}
+		for _, content := range rcds {
+			service := &models.ApronService{}
+			if err := proto.Unmarshal([]byte(content), service); err != nil {
+				return internal.InternalError(err)
+			}
+			if service.HealthCheck == nil {
+				continue
+			}
+			upstreams := models.UpstreamTargets(service)
+			targets := make([]string, len(upstreams))
+			for idx, upstream := range upstreams {
+				targets[idx] = upstream.BaseUrl
+			}
+			h.HealthChecker.Watch(service.Id, healthCheckConfig(service.HealthCheck), targets, newHealthProbe(service))
+			checked[service.Id] = true
+		}
+		if nextCursor == 0 {
+			break
+		}
+		cursor = int(nextCursor)
+	}
+
+	for _, serviceId := range h.HealthChecker.Keys() {
+		if !checked[serviceId] {
+			h.HealthChecker.Unwatch(serviceId)
+		}
+	}
+	return nil
+}
+
+// serviceHealthHandler responds health state of upstream targets of the service
+func (h *ManagerHandler) serviceHealthHandler(ctx *fasthttp.RequestCtx) {
+	serviceId := ctx.UserValue("service_name").(string)
+	if h.HealthChecker == nil {
+		internal.WriteErrorResponse(ctx, internal.NotFoundError("health check is not enabled"))
+		return
+	}
+	statuses, ok := h.HealthChecker.Status(serviceId)
+	if !ok {
+		internal.WriteErrorResponse(ctx, internal.NotFoundError("no health check declared for service %s", serviceId))
+		return
+	}
+
+	respBody, err := json.Marshal(statuses)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	ctx.SetContentType("application/json")
+	ctx.Write(respBody)
+}
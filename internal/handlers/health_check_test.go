@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/handlers/health"
+	"apron.network/gateway/internal/models"
+)
+
+// closedAddr returns address of a listener already closed, so connecting to it is refused
+func closedAddr(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.Close()
+	return ln.Addr().String()
+}
+
+func TestProxyHandlerHealthChecks(t *testing.T) {
+	storageManager := models.NewMemoryStorageManager()
+	alive := startEchoUpstream(t)
+	dead := closedAddr(t)
+
+	service := &models.ApronService{
+		Id:        "checked_service",
+		Schema:    "http",
+		Upstreams: []*models.UpstreamTarget{{BaseUrl: alive}, {BaseUrl: dead}},
+		HealthCheck: &models.HealthCheck{
+			Path:               "/health",
+			IntervalMs:         20,
+			TimeoutMs:          20,
+			HealthyThreshold:   1,
+			UnhealthyThreshold: 1,
+		},
+	}
+	if err := validateServiceSettings(service); err != nil {
+		t.Fatal(err)
+	}
+	binaryService, _ := proto.Marshal(service)
+	storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+	binaryKey, _ := proto.Marshal(&models.ApronApiKey{Key: "key_0", ServiceId: service.Id})
+	storageManager.SaveBinaryKeyData(internal.ServiceApiKeyStorageBucketName(service.Id), models.HashApiKey("key_0"), binaryKey)
+
+	proxy := newTestProxyHandler(t, storageManager)
+	manager := newTestManagerHandler(storageManager, proxy)
+	if err := proxy.SyncHealthChecks(); err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.HealthChecker.Unwatch(service.Id)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for proxy.HealthChecker.IsHealthy(service.Id, dead) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected dead target marked unhealthy")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Unhealthy target is removed from rotation
+	for i := 0; i < 4; i++ {
+		ctx := serveProxy(proxy, service.Id, "key_0", "/anything")
+		if ctx.Response.StatusCode() != fasthttp.StatusOK {
+			t.Fatalf("expected requests forwarded to healthy target, got %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+	}
+
+	ctx := serveAdmin(manager, "GET", "/service/checked_service/health", "")
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("expected health status, got %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	statuses := []health.Status{}
+	if err := json.Unmarshal(ctx.Response.Body(), &statuses); err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if status.Healthy != (status.Target == alive) {
+			t.Errorf("unexpected status %+v", status)
+		}
+	}
+	if ctx := serveAdmin(manager, "GET", "/service/unknown_service/health", ""); ctx.Response.StatusCode() != fasthttp.StatusNotFound {
+		t.Errorf("expected 404 for service without health check, got %d", ctx.Response.StatusCode())
+	}
+
+	// No target left after the only target turned unhealthy
+	service.Upstreams = service.Upstreams[1:]
+	binaryService, _ = proto.Marshal(service)
+	storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+	proxy.RecordCache.Invalidate(models.InvalidationEvent{Table: internal.ServiceBucketName, Key: service.Id})
+	if err := proxy.SyncHealthChecks(); err != nil {
+		t.Fatal(err)
+	}
+	ctx = serveProxy(proxy, service.Id, "key_0", "/anything")
+	if ctx.Response.StatusCode() != fasthttp.StatusServiceUnavailable || !strings.Contains(string(ctx.Response.Body()), internal.ErrCodeUpstreamUnavailable) {
+		t.Errorf("expected upstream_unavailable error, got %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+}
+
+func TestHealthCheckSettings(t *testing.T) {
+	if err := checkJsonRpcResponse([]byte(`{"jsonrpc":"2.0","id":1,"result":{"peers":3}}`)); err != nil {
+		t.Errorf("expected jsonrpc result accepted, got %v", err)
+	}
+	if err := checkJsonRpcResponse([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32601}}`)); err == nil {
+		t.Error("expected jsonrpc error rejected")
+	}
+
+	for _, service := range []*models.ApronService{
+		{Schema: "http", HealthCheck: &models.HealthCheck{Type: "tcp"}},
+		{Schema: "http", HealthCheck: &models.HealthCheck{Type: models.HealthCheckTypeWebsocket}},
+		{Schema: "http", HealthCheck: &models.HealthCheck{IntervalMs: 1000, TimeoutMs: 2000}},
+		{Schema: "http", HealthCheck: &models.HealthCheck{ExpectedStatus: 1000}},
+	} {
+		if err := models.ValidateHealthCheck(service); err == nil {
+			t.Errorf("expected health check %+v rejected", service.HealthCheck)
+		}
+	}
+	if err := models.ValidateHealthCheck(&models.ApronService{Schema: "wss", HealthCheck: &models.HealthCheck{Type: models.HealthCheckTypeJsonRpc}}); err != nil {
+		t.Errorf("expected jsonrpc health check accepted, got %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidToken is returned if the token is malformed or the signature is invalid
+	ErrInvalidToken = errors.New("jwt: invalid token")
+	// ErrTokenExpired is returned if exp of token passed
+	ErrTokenExpired = errors.New("jwt: token is expired")
+)
+
+// Supported signing algorithms
+const (
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Claims are decoded payload of token
+type Claims map[string]interface{}
+
+// Validation declares the expected claims of token
+type Validation struct {
+	Issuer    string        // Expected iss, not checked if empty
+	Audiences []string      // Token is accepted if aud contains any of audiences, not checked if empty
+	Leeway    time.Duration // Allowed clock skew while checking exp and nbf
+	Now       time.Time
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify checks signature of token with keys in set, and returns claims if valid.
+// The key is selected by kid of token, or all keys are tried if no kid in token.
+func (ks *KeySet) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: token should have 3 parts", ErrInvalidToken)
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid header encoding", ErrInvalidToken)
+	}
+	h := header{}
+	if err := json.Unmarshal(headerBytes, &h); err != nil {
+		return nil, fmt.Errorf("%w: invalid header", ErrInvalidToken)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid signature encoding", ErrInvalidToken)
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range ks.Keys {
+		if (h.Kid != "" && key.Kid != h.Kid) || (key.Alg != "" && key.Alg != h.Alg) {
+			continue
+		}
+		if verifySignature(h.Alg, key.PublicKey, signed, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("%w: signature verification failed", ErrInvalidToken)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid payload encoding", ErrInvalidToken)
+	}
+	claims := Claims{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("%w: invalid payload", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+// verifySignature checks signature with the key, and false is returned if alg mismatches with key type
+func verifySignature(alg string, publicKey crypto.PublicKey, signed, signature []byte) bool {
+	switch alg {
+	case AlgRS256:
+		key, ok := publicKey.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		digest := sha256.Sum256(signed)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	case AlgES256:
+		key, ok := publicKey.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return false
+		}
+		// JWS signature of ECDSA is r and s concatenated
+		digest := sha256.Sum256(signed)
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(key, digest[:], r, s)
+	case AlgEdDSA:
+		key, ok := publicKey.(ed25519.PublicKey)
+		if !ok {
+			return false
+		}
+		return ed25519.Verify(key, signed, signature)
+	}
+	return false
+}
+
+// Validate checks exp, nbf, iss and aud of claims, and tokens without exp are never accepted
+func (c Claims) Validate(v Validation) error {
+	now := v.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	exp, ok := c.numericDate("exp")
+	if !ok {
+		return fmt.Errorf("%w: exp is missing", ErrInvalidToken)
+	}
+	if !now.Before(exp.Add(v.Leeway)) {
+		return fmt.Errorf("%w at %s", ErrTokenExpired, exp.UTC().Format(time.RFC3339))
+	}
+	if nbf, ok := c.numericDate("nbf"); ok && now.Add(v.Leeway).Before(nbf) {
+		return fmt.Errorf("%w: token is not valid before %s", ErrInvalidToken, nbf.UTC().Format(time.RFC3339))
+	}
+	if v.Issuer != "" {
+		if iss, _ := c.String("iss"); iss != v.Issuer {
+			return fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, iss)
+		}
+	}
+	if len(v.Audiences) > 0 && !c.hasAudience(v.Audiences) {
+		return fmt.Errorf("%w: audience is not accepted", ErrInvalidToken)
+	}
+	return nil
+}
+
+// String returns string value of claim, numbers are formatted as string
+func (c Claims) String(name string) (string, bool) {
+	switch v := c[name].(type) {
+	case string:
+		return v, v != ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+func (c Claims) numericDate(name string) (time.Time, bool) {
+	v, ok := c[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(v), 0), true
+}
+
+// hasAudience checks aud claim, which can be a string or an array of strings
+func (c Claims) hasAudience(audiences []string) bool {
+	tokenAudiences := []string{}
+	switch aud := c["aud"].(type) {
+	case string:
+		tokenAudiences = append(tokenAudiences, aud)
+	case []interface{}:
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				tokenAudiences = append(tokenAudiences, s)
+			}
+		}
+	}
+
+	for _, expected := range audiences {
+		for _, a := range tokenAudiences {
+			if a == expected {
+				return true
+			}
+		}
+	}
+	return false
+}
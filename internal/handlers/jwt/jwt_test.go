@@ -0,0 +1,128 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// sign creates token signed with private key
+func sign(t *testing.T, alg, kid string, privateKey crypto.Signer, claims Claims) string {
+	headerBytes, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64(headerBytes) + "." + b64(payload)
+
+	var signature []byte
+	var err error
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, key, digest[:])
+		signature = make([]byte, 64)
+		rBytes, sBytes := r.Bytes(), s.Bytes()
+		copy(signature[32-len(rBytes):32], rBytes)
+		copy(signature[64-len(sBytes):], sBytes)
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(key, []byte(signed))
+	}
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed + "." + b64(signature)
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPublicKey, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	jwks := fmt.Sprintf(`{"keys": [
+		{"kty": "RSA", "kid": "rsa", "alg": "RS256", "n": %q, "e": %q},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": %q, "y": %q},
+		{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": %q},
+		{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"}
+	]}`,
+		b64(rsaKey.N.Bytes()), b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+		b64(ecKey.X.Bytes()), b64(ecKey.Y.Bytes()),
+		b64(edPublicKey),
+	)
+	keySet, err := ParseKeySet([]byte(jwks))
+	if err != nil || len(keySet.Keys) != 3 {
+		t.Fatalf("failed to parse jwks: %v", err)
+	}
+
+	claims := Claims{"sub": "alice", "iss": "https://issuer", "aud": []string{"apron"}, "exp": time.Now().Add(time.Hour).Unix()}
+	testCases := []struct {
+		desc  string
+		token string
+		valid bool
+	}{
+		{"RS256", sign(t, AlgRS256, "rsa", rsaKey, claims), true},
+		{"ES256", sign(t, AlgES256, "ec", ecKey, claims), true},
+		{"EdDSA", sign(t, AlgEdDSA, "ed", edKey, claims), true},
+		{"EdDSA without kid", sign(t, AlgEdDSA, "", edKey, claims), true},
+		{"mismatched kid", sign(t, AlgES256, "rsa", ecKey, claims), false},
+		{"mismatched alg", sign(t, AlgRS256, "ed", rsaKey, claims), false},
+		{"alg none", b64([]byte(`{"alg":"none"}`)) + "." + b64([]byte(`{"sub":"alice"}`)) + ".", false},
+		{"malformed", "not.a-token", false},
+	}
+	for _, tc := range testCases {
+		verified, err := keySet.Verify(tc.token)
+		if (err == nil) != tc.valid {
+			t.Errorf("%s: expected valid %v, got %v", tc.desc, tc.valid, err)
+		}
+		if err == nil {
+			if sub, _ := verified.String("sub"); sub != "alice" {
+				t.Errorf("%s: unexpected claims %v", tc.desc, verified)
+			}
+		} else if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: expected invalid token error, got %v", tc.desc, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1617235200, 0)
+	v := Validation{Issuer: "https://issuer", Audiences: []string{"apron", "other"}, Leeway: time.Minute, Now: now}
+	exp := float64(now.Unix() + 10)
+
+	testCases := []struct {
+		desc   string
+		claims Claims
+		err    error
+	}{
+		{"valid", Claims{"iss": "https://issuer", "aud": "apron", "exp": float64(now.Unix() + 10)}, nil},
+		{"audience array", Claims{"iss": "https://issuer", "aud": []interface{}{"x", "other"}, "exp": exp}, nil},
+		{"expired in leeway", Claims{"iss": "https://issuer", "aud": "apron", "exp": float64(now.Unix() - 30)}, nil},
+		{"expired", Claims{"iss": "https://issuer", "aud": "apron", "exp": float64(now.Unix() - 120)}, ErrTokenExpired},
+		{"missing exp", Claims{"iss": "https://issuer", "aud": "apron"}, ErrInvalidToken},
+		{"not before", Claims{"iss": "https://issuer", "aud": "apron", "exp": exp, "nbf": float64(now.Unix() + 120)}, ErrInvalidToken},
+		{"wrong issuer", Claims{"iss": "https://evil", "aud": "apron", "exp": exp}, ErrInvalidToken},
+		{"wrong audience", Claims{"iss": "https://issuer", "aud": "evil", "exp": exp}, ErrInvalidToken},
+	}
+	for _, tc := range testCases {
+		err := tc.claims.Validate(v)
+		if (tc.err == nil && err != nil) || (tc.err != nil && !errors.Is(err, tc.err)) {
+			t.Errorf("%s: expected %v, got %v", tc.desc, tc.err, err)
+		}
+	}
+}
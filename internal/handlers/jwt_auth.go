@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/handlers/jwt"
+	"apron.network/gateway/internal/models"
+)
+
+// jwksReloadInterval is the interval of reloading JWKS file, so rotated keys are used without restarting gateway
+const jwksReloadInterval = time.Minute
+
+// jwksCache keeps parsed key sets keyed by service id, and the set is reloaded once JWKS of service changed
+type jwksCache struct {
+	lock  sync.Mutex
+	items map[string]*jwksCacheItem
+}
+
+type jwksCacheItem struct {
+	jwksFile string
+	jwks     string
+	keySet   *jwt.KeySet
+	loadedAt time.Time
+}
+
+func newJwksCache() *jwksCache {
+	return &jwksCache{items: make(map[string]*jwksCacheItem)}
+}
+
+// get returns key set declared in config of service, JWKS file is reloaded after jwksReloadInterval
+func (c *jwksCache) get(serviceId string, config *models.JwtAuthConfig) (*jwt.KeySet, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	item, ok := c.items[serviceId]
+	if ok && item.jwksFile == config.JwksFile && item.jwks == config.Jwks &&
+		(config.JwksFile == "" || time.Since(item.loadedAt) < jwksReloadInterval) {
+		return item.keySet, nil
+	}
+
+	data := []byte(config.Jwks)
+	if config.JwksFile != "" {
+		var err error
+		if data, err = ioutil.ReadFile(config.JwksFile); err != nil {
+			return nil, err
+		}
+	}
+	keySet, err := jwt.ParseKeySet(data)
+	if err != nil {
+		return nil, err
+	}
+	c.items[serviceId] = &jwksCacheItem{jwksFile: config.JwksFile, jwks: config.Jwks, keySet: keySet, loadedAt: time.Now()}
+	return keySet, nil
+}
+
+// validateAuthMode checks auth mode of service, and JWKS of jwt auth should be valid
+func validateAuthMode(service *models.ApronService) error {
+	switch service.AuthMode {
+	case "", models.ServiceAuthModeApiKey:
+		return nil
+	case models.ServiceAuthModeJwt:
+	default:
+		return fmt.Errorf("auth_mode should be api_key or jwt")
+	}
+
+	config := service.JwtAuth
+	if config == nil || (config.JwksFile == "" && config.Jwks == "") {
+		return fmt.Errorf("jwt_auth with jwks_file or jwks is required for jwt auth mode")
+	}
+	if config.LeewaySeconds < models.JwtLeewayDisabled {
+		return fmt.Errorf("leeway_seconds should be -1 to disable, or not negative")
+	}
+	data := []byte(config.Jwks)
+	if config.JwksFile != "" {
+		var err error
+		if data, err = ioutil.ReadFile(config.JwksFile); err != nil {
+			return fmt.Errorf("failed to read jwks_file: %v", err)
+		}
+	}
+	if _, err := jwt.ParseKeySet(data); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkJwksFile allows jwks_file declared by superadmin, or under JwksDir of gateway, since the file is read
+// from gateway host. The unchanged path of an updated service is allowed, so providers can update other fields.
+func (h *ManagerHandler) checkJwksFile(ctx *fasthttp.RequestCtx, service *models.ApronService, previousFile string) error {
+	config := service.JwtAuth
+	if config == nil || config.JwksFile == "" || config.JwksFile == previousFile {
+		return nil
+	}
+	if token := adminToken(ctx); token != nil && token.Role == models.AdminRoleSuperadmin {
+		return nil
+	}
+	if !isPathInDir(h.JwksDir, config.JwksFile) {
+		return internal.ForbiddenError("jwks_file should be under the JWKS directory of gateway")
+	}
+	return nil
+}
+
+// isPathInDir checks whether path is in dir after symlinks resolved, and nothing is in an empty dir
+func isPathInDir(dir, path string) bool {
+	if dir == "" {
+		return false
+	}
+	resolve := func(p string) string {
+		if resolved, err := filepath.EvalSymlinks(p); err == nil {
+			p = resolved
+		}
+		abs, _ := filepath.Abs(p)
+		return abs
+	}
+	rel, err := filepath.Rel(resolve(dir), resolve(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// validateJwtRequest authenticates request with bearer token issued by service provider. The account claim of token
+// is used as account and key id, so rate limits, quotas and usage are counted by account.
+func (h *ProxyHandler) validateJwtRequest(c *ProxyContext) error {
+	header := &c.Ctx.Request.Header
+	auth := string(header.Peek("Authorization"))
+	if len(auth) <= 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return internal.UnauthorizedError("missing bearer token for service %s", c.Service.Id)
+	}
+	token := strings.TrimSpace(auth[7:])
+	// Token is not forwarded to upstream service
+	header.Del("Authorization")
+	delete(c.RequestDetail.Headers, "Authorization")
+
+	config := c.Service.JwtAuth
+	if config == nil {
+		return internal.InternalError(fmt.Errorf("jwt_auth of service %s is missing", c.Service.Id))
+	}
+	keySet, err := h.jwks.get(c.Service.Id, config)
+	if err != nil {
+		return internal.InternalError(fmt.Errorf("failed to load jwks of service %s: %v", c.Service.Id, err))
+	}
+
+	claims, err := keySet.Verify(token)
+	if err == nil {
+		err = claims.Validate(jwt.Validation{
+			Issuer:    config.Issuer,
+			Audiences: config.Audiences,
+			Leeway:    models.JwtLeeway(config),
+		})
+	}
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return internal.KeyExpiredError(err.Error())
+	} else if err != nil {
+		return internal.UnauthorizedError(err.Error())
+	}
+
+	accountClaim := models.JwtAccountClaim(config)
+	accountId, ok := claims.String(accountClaim)
+	if !ok {
+		return internal.UnauthorizedError("claim %s is missing in token", accountClaim)
+	}
+
+	return h.authorizeApiKey(c, &models.ApronApiKey{
+		Id:        accountId,
+		ServiceId: c.Service.Id,
+		AccountId: accountId,
+	})
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/models"
+)
+
+func TestProxyHandlerJwtAuth(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	b64 := base64.RawURLEncoding.EncodeToString
+	signToken := func(claims map[string]interface{}) string {
+		payload, _ := json.Marshal(claims)
+		signed := b64([]byte(`{"alg":"EdDSA","kid":"provider"}`)) + "." + b64(payload)
+		return signed + "." + b64(ed25519.Sign(privateKey, []byte(signed)))
+	}
+
+	storageManager := models.NewMemoryStorageManager()
+	service := &models.ApronService{
+		Id:       "test_service",
+		BaseUrl:  startEchoUpstream(t) + "/",
+		Schema:   "http",
+		AuthMode: models.ServiceAuthModeJwt,
+		JwtAuth: &models.JwtAuthConfig{
+			Jwks:         fmt.Sprintf(`{"keys": [{"kty": "OKP", "crv": "Ed25519", "kid": "provider", "x": %q}]}`, b64(publicKey)),
+			Issuer:       "https://provider",
+			Audiences:    []string{"apron"},
+			AccountClaim: "account",
+		},
+	}
+	if err := validateAuthMode(service); err != nil {
+		t.Fatalf("expected valid jwt auth, got %v", err)
+	}
+	binaryService, _ := proto.Marshal(service)
+	storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+
+	proxy := newTestProxyHandler(t, storageManager)
+
+	exp := time.Now().Add(time.Hour).Unix()
+	testCases := []struct {
+		desc   string
+		token  string
+		status int
+		code   string
+	}{
+		{"valid", signToken(map[string]interface{}{"iss": "https://provider", "aud": "apron", "account": "5GrwvaEF", "exp": exp}), fasthttp.StatusOK, ""},
+		{"expired", signToken(map[string]interface{}{"iss": "https://provider", "aud": "apron", "account": "5GrwvaEF", "exp": 1}), fasthttp.StatusUnauthorized, internal.ErrCodeKeyExpired},
+		{"wrong audience", signToken(map[string]interface{}{"iss": "https://provider", "aud": "other", "account": "5GrwvaEF", "exp": exp}), fasthttp.StatusUnauthorized, internal.ErrCodeUnauthorized},
+		{"missing exp", signToken(map[string]interface{}{"iss": "https://provider", "aud": "apron", "account": "5GrwvaEF"}), fasthttp.StatusUnauthorized, internal.ErrCodeUnauthorized},
+		{"missing account", signToken(map[string]interface{}{"iss": "https://provider", "aud": "apron", "exp": exp}), fasthttp.StatusUnauthorized, internal.ErrCodeUnauthorized},
+		{"missing token", "", fasthttp.StatusUnauthorized, internal.ErrCodeUnauthorized},
+	}
+	for _, tc := range testCases {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.SetRequestURI("/v1/test_service/anything")
+		if tc.token != "" {
+			ctx.Request.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		proxy.InternalHandler(ctx)
+
+		if ctx.Response.StatusCode() != tc.status {
+			t.Errorf("%s: expected status %d, got %d %q", tc.desc, tc.status, ctx.Response.StatusCode(), ctx.Response.Body())
+			continue
+		}
+		if tc.code != "" {
+			gatewayErr := internal.GatewayError{}
+			json.Unmarshal(ctx.Response.Body(), &gatewayErr)
+			if gatewayErr.Code != tc.code {
+				t.Errorf("%s: expected code %s, got %q", tc.desc, tc.code, ctx.Response.Body())
+			}
+		}
+	}
+
+	// Usage is reported with the account claim
+	usages, _ := proxy.AggrAccessRecordManager.ExportAllUsage()
+	if len(usages) != 1 || usages[0].UserKey != "5GrwvaEF" {
+		t.Errorf("expected usage reported with account, got %+v", usages)
+	}
+}
+
+func TestJwksFileRestriction(t *testing.T) {
+	publicKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	jwks := fmt.Sprintf(`{"keys": [{"kty": "OKP", "crv": "Ed25519", "kid": "provider", "x": %q}]}`, base64.RawURLEncoding.EncodeToString(publicKey))
+
+	jwksDir, otherDir := t.TempDir(), t.TempDir()
+	allowedFile := filepath.Join(jwksDir, "provider.jwks")
+	invalidFile := filepath.Join(jwksDir, "invalid.jwks")
+	otherFile := filepath.Join(otherDir, "other.jwks")
+	ioutil.WriteFile(allowedFile, []byte(jwks), 0644)
+	ioutil.WriteFile(invalidFile, []byte("{}"), 0644)
+	ioutil.WriteFile(otherFile, []byte(jwks), 0644)
+
+	storageManager := models.NewMemoryStorageManager()
+	proxy := newTestProxyHandler(t, storageManager)
+	manager := newTestManagerHandler(storageManager, proxy)
+	manager.JwksDir = jwksDir
+
+	ctx := serveAdmin(manager, "POST", "/admin/tokens/", `{"name": "alice", "role": "provider", "account": "alice"}`)
+	provider := &models.ApronAdminToken{}
+	if err := jsonpb.UnmarshalString(string(ctx.Response.Body()), provider); err != nil {
+		t.Fatalf("failed to create provider token %q", ctx.Response.Body())
+	}
+
+	serviceBody := func(id, jwksFile string) string {
+		return fmt.Sprintf(`{"id": %q, "base_url": "httpbin/", "schema": "http", "auth_mode": "jwt", "jwt_auth": {"jwks_file": %q}}`, id, jwksFile)
+	}
+	testCases := []struct {
+		desc   string
+		token  string
+		body   string
+		status int
+	}{
+		{"provider file in jwks dir", provider.Token, serviceBody("allowed_service", allowedFile), fasthttp.StatusCreated},
+		{"provider file outside jwks dir", provider.Token, serviceBody("other_service", otherFile), fasthttp.StatusForbidden},
+		{"provider path escaping jwks dir", provider.Token, serviceBody("escaped_service", filepath.Join(jwksDir, "..", filepath.Base(otherDir), "other.jwks")), fasthttp.StatusForbidden},
+		{"invalid file in jwks dir", provider.Token, serviceBody("invalid_service", invalidFile), fasthttp.StatusBadRequest},
+		{"missing file in jwks dir", provider.Token, serviceBody("missing_service", filepath.Join(jwksDir, "missing.jwks")), fasthttp.StatusBadRequest},
+		{"superadmin file outside jwks dir", testBootstrapToken, serviceBody("admin_service", otherFile), fasthttp.StatusCreated},
+	}
+	for _, tc := range testCases {
+		if ctx := serveAdminAs(manager, tc.token, "POST", "/service/", tc.body); ctx.Response.StatusCode() != tc.status {
+			t.Errorf("%s: expected status %d, got %d %q", tc.desc, tc.status, ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+	}
+
+	// Provider can't move jwks file of its service outside jwks dir
+	if ctx := serveAdminAs(manager, provider.Token, "PUT", "/service/allowed_service", fmt.Sprintf(`{"jwt_auth": {"jwks_file": %q}}`, otherFile)); ctx.Response.StatusCode() != fasthttp.StatusForbidden {
+		t.Errorf("expected jwks file update outside jwks dir rejected, got %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+}
+
+func TestJwksCacheAndLeeway(t *testing.T) {
+	newJwks := func() string {
+		publicKey, _, _ := ed25519.GenerateKey(rand.Reader)
+		return fmt.Sprintf(`{"keys": [{"kty": "OKP", "crv": "Ed25519", "kid": "provider", "x": %q}]}`, base64.RawURLEncoding.EncodeToString(publicKey))
+	}
+
+	// Updated inline JWKS replaces the cached set of the service
+	cache := newJwksCache()
+	first, err := cache.get("test_service", &models.JwtAuthConfig{Jwks: newJwks()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := cache.get("test_service", &models.JwtAuthConfig{Jwks: newJwks()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second || len(cache.items) != 1 {
+		t.Errorf("expected key set of service replaced, got %d cached sets", len(cache.items))
+	}
+	if cached, _ := cache.get("test_service", &models.JwtAuthConfig{Jwks: cache.items["test_service"].jwks}); cached != second {
+		t.Error("expected unchanged key set reused")
+	}
+
+	if leeway := models.JwtLeeway(&models.JwtAuthConfig{}); leeway != models.DefaultJwtLeeway {
+		t.Errorf("expected default leeway, got %v", leeway)
+	}
+	if leeway := models.JwtLeeway(&models.JwtAuthConfig{LeewaySeconds: models.JwtLeewayDisabled}); leeway != 0 {
+		t.Errorf("expected no leeway, got %v", leeway)
+	}
+	for seconds, valid := range map[int64]bool{models.JwtLeewayDisabled: true, 30: true, -2: false} {
+		service := &models.ApronService{AuthMode: models.ServiceAuthModeJwt, JwtAuth: &models.JwtAuthConfig{Jwks: newJwks(), LeewaySeconds: seconds}}
+		if err := validateAuthMode(service); (err == nil) != valid {
+			t.Errorf("leeway_seconds %d: expected valid %v, got %v", seconds, valid, err)
+		}
+	}
+}
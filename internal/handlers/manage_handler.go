@@ -11,58 +11,101 @@ import (
 	"github.com/google/uuid"
 	"github.com/valyala/fasthttp"
 
+	"apron.network/gateway/internal/handlers/breaker"
+	"apron.network/gateway/internal/handlers/health"
 	"apron.network/gateway/internal/models"
 )
 
 // TODO: Add database client to fetch registered service and api keys
 type ManagerHandler struct {
-	AggrAccessRecordManager models.AggregatedAccessRecordManager
-
-	storageManager   *models.StorageManager
+	AggrAccessRecordManager *models.AggregatedAccessRecordManager
+	InvalidationBus         models.InvalidationBus
+	// BootstrapToken is a superadmin token from configuration, which is used to create other admin tokens
+	BootstrapToken string
+	// HealthChecker is shared with proxy handler, so health state of upstream targets can be queried
+	HealthChecker *health.Checker
+	// Breakers is shared with proxy handler, so circuit state of upstream targets can be queried
+	Breakers *breaker.Breakers
+	// JwksDir is the directory JWKS files of jwt auth services can be read from, files in other paths
+	// can only be declared by superadmin. Only superadmin can declare JWKS files if empty.
+	JwksDir string
+
+	storageManager   models.StorageManager
+	quotaManager     *models.QuotaManager
+	auditLog         *models.AuditLog
 	r                *router.Router
 	AccessLogChannel chan string
 	wsConns          map[string]*websocket.Conn
 }
 
-func (h *ManagerHandler) InitStore(storeMgr *models.StorageManager) {
+func (h *ManagerHandler) InitStore(storeMgr models.StorageManager) {
 	h.storageManager = storeMgr
+	h.quotaManager = models.NewQuotaManager(storeMgr)
+	h.auditLog = models.NewAuditLog(storeMgr)
 	h.wsConns = make(map[string]*websocket.Conn)
 }
 
 func (h *ManagerHandler) Handler() func(ctx *fasthttp.RequestCtx) {
-	return h.r.Handler
+	return RecoveryMiddleware(h.r.Handler)
 }
 
+// InitRouters builds admin routes, every route requires admin token with the permission declared here
 func (h *ManagerHandler) InitRouters() {
 	h.r = router.New()
 
-	h.r.GET("/", h.indexHandler)
+	h.r.GET("/", h.authorize(adminPermRead, h.indexHandler))
 
-	h.r.GET("/detailed_logs", h.detailedUserReportHandler)
+	h.r.GET("/detailed_logs", h.authorize(adminPermReadAll, h.detailedUserReportHandler))
 
 	// Service related
 	serviceRouter := h.r.Group("/service")
-	serviceRouter.GET("/", h.listServiceHandler)
-	serviceRouter.GET("/{service_name}/report/{key_id}", h.serviceUsageReportHandler)
-	serviceRouter.GET("/report/", h.allUsageReportHandler)
-	serviceRouter.POST("/", h.newServiceHandler)
-	serviceRouter.POST("/{service_name}", h.serviceDetailHandler)
-	serviceRouter.PUT("/{service_name}", h.updateServiceHandler)
-	serviceRouter.DELETE("/{service_name}", h.deleteServiceHandler)
+	serviceRouter.GET("/", h.authorize(adminPermRead, h.listServiceHandler))
+	serviceRouter.GET("/{service_name}/report/{key_id}", h.authorize(adminPermRead, h.serviceUsageReportHandler))
+	serviceRouter.GET("/report/", h.authorize(adminPermReadAll, h.allUsageReportHandler))
+	serviceRouter.POST("/", h.authorize(adminPermWrite, h.newServiceHandler))
+	serviceRouter.POST("/{service_name}", h.authorize(adminPermRead, h.serviceDetailHandler))
+	serviceRouter.PUT("/{service_name}", h.authorize(adminPermWrite, h.updateServiceHandler))
+	serviceRouter.DELETE("/{service_name}", h.authorize(adminPermWrite, h.deleteServiceHandler))
+	serviceRouter.GET("/{service_name}/health", h.authorize(adminPermRead, h.serviceHealthHandler))
+	serviceRouter.GET("/{service_name}/circuits", h.authorize(adminPermRead, h.serviceCircuitsHandler))
 
 	// API key related
 	apiKeyRouter := serviceRouter.Group("/{service_id}/keys")
-	apiKeyRouter.GET("/", h.listApiKeysHandler)
-	apiKeyRouter.POST("/", h.newApiKeyHandler)
-	apiKeyRouter.GET("/{key_id}", h.apiKeyDetailHandler)
-	apiKeyRouter.PUT("/{key_id}", h.updateApiKeyHandler)
-	apiKeyRouter.DELETE("/{key_id}", h.deleteApiKeyHandler)
+	apiKeyRouter.GET("/", h.authorize(adminPermRead, h.listApiKeysHandler))
+	apiKeyRouter.POST("/", h.authorize(adminPermWrite, h.newApiKeyHandler))
+	apiKeyRouter.GET("/{key_id}", h.authorize(adminPermRead, h.apiKeyDetailHandler))
+	apiKeyRouter.PUT("/{key_id}", h.authorize(adminPermWrite, h.updateApiKeyHandler))
+	apiKeyRouter.DELETE("/{key_id}", h.authorize(adminPermWrite, h.deleteApiKeyHandler))
+	apiKeyRouter.POST("/{key_id}/rotate", h.authorize(adminPermWrite, h.rotateApiKeyHandler))
+	apiKeyRouter.GET("/{key_id}/quota", h.authorize(adminPermRead, h.apiKeyQuotaHandler))
+	apiKeyRouter.DELETE("/{key_id}/quota", h.authorize(adminPermWrite, h.resetApiKeyQuotaHandler))
 
 	// User mgmt related
 	userRouter := h.r.Group("/users")
-	userRouter.GET("/", h.listAllUsersHandler)
-	userRouter.PUT("/", h.updateUserProfileHandler)
-	userRouter.GET("/keys", h.listAllUserKeysHandler)
+	userRouter.GET("/", h.authorize(adminPermReadAll, h.listAllUsersHandler))
+	userRouter.PUT("/", h.authorize(adminPermSuperadmin, h.updateUserProfileHandler))
+	userRouter.GET("/keys", h.authorize(adminPermReadAll, h.listAllUserKeysHandler))
+
+	// Admin token related
+	adminTokenRouter := h.r.Group("/admin/tokens")
+	adminTokenRouter.GET("/", h.authorize(adminPermSuperadmin, h.listAdminTokensHandler))
+	adminTokenRouter.POST("/", h.authorize(adminPermSuperadmin, h.newAdminTokenHandler))
+	adminTokenRouter.DELETE("/{token_id}", h.authorize(adminPermSuperadmin, h.deleteAdminTokenHandler))
+
+	// Audit log related
+	auditRouter := h.r.Group("/audit")
+	auditRouter.GET("/", h.authorize(adminPermReadAll, h.listAuditEntriesHandler))
+	auditRouter.GET("/verify", h.authorize(adminPermReadAll, h.verifyAuditLogHandler))
+}
+
+// notifyRecordChanged publishes invalidation event, so the cached record in all gateway nodes will be refreshed
+func (h *ManagerHandler) notifyRecordChanged(table, key string) {
+	if h.InvalidationBus == nil {
+		return
+	}
+	if err := h.InvalidationBus.Publish(models.InvalidationEvent{Table: table, Key: key}); err != nil {
+		log.Printf("Failed to publish invalidation event for %s in %s: %v\n", key, table, err)
+	}
 }
 
 func (h *ManagerHandler) indexHandler(ctx *fasthttp.RequestCtx) {
@@ -103,21 +146,22 @@ func (h *ManagerHandler) detailedUserReportHandler(ctx *fasthttp.RequestCtx) {
 				}
 			}
 		})
-		internal.CheckError(err)
+		if err != nil {
+			internal.WriteErrorResponse(ctx, internal.BadRequestError("websocket upgrade failed: %v", err))
+		}
 	} else {
-		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		internal.WriteErrorResponse(ctx, internal.NotFoundError("detailed logs only available via websocket"))
 	}
 }
 
 func (h *ManagerHandler) allUsageReportHandler(ctx *fasthttp.RequestCtx) {
 	if rslt, err := h.AggrAccessRecordManager.ExportAllUsage(); err != nil {
-		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
-		ctx.SetBodyString(err.Error())
+		internal.WriteErrorResponse(ctx, err)
 	} else {
 		usageRecordsJsonByte, err := json.Marshal(rslt)
 		if err != nil {
-			ctx.SetStatusCode(fasthttp.StatusInternalServerError)
-			ctx.SetBodyString(err.Error())
+			internal.WriteErrorResponse(ctx, err)
+			return
 		}
 		ctx.SetBody(usageRecordsJsonByte)
 	}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"fmt"
 	"net"
+	"os"
 	"testing"
 
 	"github.com/valyala/fasthttp"
@@ -13,6 +14,7 @@ var h = &ManagerHandler{}
 
 func TestMain(m *testing.M) {
 	h.InitRouters()
+	os.Exit(m.Run())
 }
 
 func serve(handler fasthttp.RequestHandler, req *fasthttp.Request, res *fasthttp.Response) error {
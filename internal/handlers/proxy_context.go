@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"sync"
+
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal/handlers/ratelimiter"
+	"apron.network/gateway/internal/models"
+)
+
+// ProxyContext holds all data related to a single proxied request.
+// A new context is created for every incoming request and passed through the proxy pipeline,
+// so concurrent requests never share parsed request detail or loaded service.
+type ProxyContext struct {
+	Ctx           *fasthttp.RequestCtx
+	RequestDetail *models.RequestDetail
+	Service       *models.ApronService
+	ApiKey        *models.ApronApiKey
+	RateLimit     *ratelimiter.Result
+	Quota         *models.QuotaStatus
+	// Scopes of the key matched by request, which are used to check websocket messages
+	Scopes []*models.ApiKeyScope
+	// Target is base url of the upstream target picked by balancer
+	Target string
+	// upstreamDone reports result of request to target to circuit breaker
+	upstreamDone func(success bool)
+	// releaseTarget releases outstanding request of target, so the target can be released before retried
+	releaseTarget func()
+	// triedTargets are targets failed in previous attempts, which are avoided by retries
+	triedTargets map[string]bool
+
+	// Upgraded is set if the connection is hijacked by websocket session,
+	// which is still alive after the pipeline returned.
+	Upgraded   bool
+	closeLock  sync.Mutex
+	closeHooks []func()
+	closed     bool
+}
+
+// OnSessionClose registers fn called after the request finished, or the websocket session closed if upgraded
+func (c *ProxyContext) OnSessionClose(fn func()) {
+	c.closeLock.Lock()
+	defer c.closeLock.Unlock()
+	if c.closed {
+		fn()
+		return
+	}
+	c.closeHooks = append(c.closeHooks, fn)
+}
+
+// CloseSession calls registered hooks in reverse order, only the first call takes effect
+func (c *ProxyContext) CloseSession() {
+	c.closeLock.Lock()
+	hooks := c.closeHooks
+	c.closeHooks = nil
+	c.closed = true
+	c.closeLock.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
+// reportUpstream reports whether the request to target succeeded, it does nothing if circuit breaker not declared
+func (c *ProxyContext) reportUpstream(success bool) {
+	if c.upstreamDone != nil {
+		c.upstreamDone(success)
+	}
+}
+
+// ProxyRequestHandler processes a proxy request, the pipeline stops if error returned
+type ProxyRequestHandler func(c *ProxyContext) error
+
+// ProxyMiddleware wraps next handler in the proxy pipeline
+type ProxyMiddleware func(next ProxyRequestHandler) ProxyRequestHandler
+
+// chainProxyMiddlewares builds a handler which invokes middlewares in passed in order,
+// and the final handler will be invoked if all middlewares passed.
+func chainProxyMiddlewares(final ProxyRequestHandler, middlewares ...ProxyMiddleware) ProxyRequestHandler {
+	h := final
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
@@ -3,177 +3,244 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"net/http"
+	"net"
 	"net/url"
-	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"apron.network/gateway/internal/handlers/balancer"
+	"apron.network/gateway/internal/handlers/breaker"
+	"apron.network/gateway/internal/handlers/concurrency"
+	"apron.network/gateway/internal/handlers/health"
 	"apron.network/gateway/internal/handlers/ratelimiter"
+	"apron.network/gateway/internal/handlers/retry"
 
 	"github.com/fasthttp/websocket"
-	"github.com/golang/protobuf/proto"
 	"github.com/valyala/fasthttp"
 
 	"apron.network/gateway/internal"
 	"apron.network/gateway/internal/models"
 )
 
+// corsApiKeyUserValue is the user value name of api key restricting allowed origins
+const corsApiKeyUserValue = "apron_cors_api_key"
+
 type ProxyHandler struct {
-	HttpClient              *http.Client
-	StorageManager          *models.StorageManager
+	StorageManager          models.StorageManager
+	RecordCache             *models.RecordCache
 	RateLimiter             *ratelimiter.Limiter
+	QuotaManager            *models.QuotaManager
+	ConcurrencyLimiter      *concurrency.Limiter
+	Balancer                *balancer.Balancer
+	HealthChecker           *health.Checker
+	Breakers                *breaker.Breakers
+	RetryBudget             *retry.Budget
 	Logger                  *internal.GatewayLogger
-	AggrAccessRecordManager models.AggregatedAccessRecordManager
+	AggrAccessRecordManager *models.AggregatedAccessRecordManager
 	AccessLogChannel        chan string
-
-	upgrader         *websocket.FastHTTPUpgrader
-	requestDetail    *models.RequestDetail
-	serviceAggrCount map[string]uint32 // Simple aggr count for detail logs
+	// SignatureMaxSkew is the max difference between timestamp of signed request and gateway clock
+	SignatureMaxSkew time.Duration
+	// AccessTokenTTL is the lifetime of access tokens issued by wallet login
+	AccessTokenTTL time.Duration
+
+	jwks        *jwksCache
+	upgrader    *websocket.FastHTTPUpgrader
+	clients     upstreamClients
+	middlewares []ProxyMiddleware
+	pipeline    ProxyRequestHandler
 }
 
-// InternalHandler ...
-func (h *ProxyHandler) InternalHandler(ctx *fasthttp.RequestCtx) {
-	requestDetail, err := models.ExtractCtxRequestDetail(ctx)
-	internal.CheckError(err)
-	h.requestDetail = requestDetail
-	h.validateRequest(ctx)
-
-	key := string(ctx.Path()) // TODO: need process path before handle rate limit
-	res, err := h.RateLimiter.Get(key)
-	if err != nil {
-		return
+// Init builds the proxy pipeline, the middlewares will be invoked in order before the request forwarded
+func (h *ProxyHandler) Init() {
+	if h.RecordCache == nil {
+		h.RecordCache = models.NewRecordCache(h.StorageManager)
+	}
+	if h.QuotaManager == nil {
+		h.QuotaManager = models.NewQuotaManager(h.StorageManager)
+	}
+	if h.ConcurrencyLimiter == nil {
+		h.ConcurrencyLimiter = concurrency.New()
+	}
+	if h.Balancer == nil {
+		h.Balancer = balancer.New()
+	}
+	if h.HealthChecker == nil {
+		h.HealthChecker = health.New()
+	}
+	if h.Breakers == nil {
+		h.Breakers = breaker.New()
+	}
+	if h.RetryBudget == nil {
+		h.RetryBudget = retry.NewBudget()
+	}
+	if h.Breakers.OnStateChange == nil {
+		h.Breakers.OnStateChange = h.logCircuitStateChange
+	}
+	h.jwks = newJwksCache()
+	if h.SignatureMaxSkew == 0 {
+		h.SignatureMaxSkew = defaultSignatureMaxSkew
+	}
+	if h.AccessTokenTTL == 0 {
+		h.AccessTokenTTL = defaultAccessTokenTTL
 	}
 
-	fmt.Printf("X-Ratelimit-Limit: %s\n", strconv.FormatInt(int64(res.Total), 10))
-	fmt.Printf("X-Ratelimit-Remaining: %s\n", strconv.FormatInt(int64(res.Remaining), 10))
-	fmt.Printf("X-Ratelimit-Reset: %s\n", strconv.FormatInt(res.Reset.Unix(), 10))
+	h.upgrader = &websocket.FastHTTPUpgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin: func(ctx *fasthttp.RequestCtx) bool {
+			return true
+		},
+	}
 
-	// TODO: handle the case of no remain resource left, the key point is how to notify clients in response
-	if res.Remaining < 0 {
-		after := int64(res.Reset.Sub(time.Now())) / 1e9
-		fmt.Printf("Retry-After: %s\n", strconv.FormatInt(after, 10))
+	h.middlewares = []ProxyMiddleware{
+		h.parseRequestMiddleware,
+		h.authenticateMiddleware,
+		h.concurrencyMiddleware,
+		h.rateLimitMiddleware,
+		h.quotaMiddleware,
+		h.meterMiddleware,
+	}
+	h.pipeline = chainProxyMiddlewares(h.ForwardHandler, h.middlewares...)
+}
 
-		h.Logger.Log(fmt.Sprintf("%s|429 error|%s: from %s, service: %s, api_key: %s\n",
-			time.Now().UTC().Format("2006-01-02 15:04:05"),
-			requestDetail.URI.String(),
-			ctx.RemoteIP().String(),
-			requestDetail.ServiceNameStr,
-			requestDetail.ApiKeyStr,
-		))
+// InternalHandler creates context for the request and passes it through proxy pipeline,
+// errors returned from pipeline will be responded as JSON.
+func (h *ProxyHandler) InternalHandler(ctx *fasthttp.RequestCtx) {
+	defer recoverPanic(ctx)
 
+	// Browsers never send keys in headers with preflight, so it is answered without authentication,
+	// and CORS headers are added by the caller
+	if isCorsPreflight(ctx) {
+		ctx.SetStatusCode(fasthttp.StatusNoContent)
 		return
 	}
-
-	h.Logger.Log(fmt.Sprintf("%s|%s: from %s, service: %s, api_key: %s\n",
-		time.Now().UTC().Format("2006-01-02 15:04:05"),
-		requestDetail.URI.String(),
-		ctx.RemoteIP().String(),
-		requestDetail.ServiceNameStr,
-		requestDetail.ApiKeyStr,
-	))
-	h.AggrAccessRecordManager.IncUsage(requestDetail.ServiceNameStr, requestDetail.ApiKeyStr)
-	access_log := models.AccessLog{
-		Ts:          int64(int(time.Now().UnixNano() / 1e6)),
-		ServiceName: requestDetail.ServiceNameStr,
-		UserKey:     requestDetail.ApiKeyStr,
-		RequestIp:   string(ctx.RemoteIP().String()),
-		RequestPath: string(requestDetail.ProxyRequestPath),
-	}
-	access_log_bytes, err := json.Marshal(&access_log)
-	internal.CheckError(err)
-	h.AccessLogChannel <- string(access_log_bytes)
-
-	h.ForwardHandler(ctx)
-}
-
-// ForwardHandler receives request and forward to configured services, which contains those actions
-// - Identify request user
-// - Authenticate user
-// - Find request related service (based on passed in user credentials)
-// - Transparent proxy
-func (h *ProxyHandler) ForwardHandler(ctx *fasthttp.RequestCtx) {
-	if err := h.validateRequest(ctx); err != nil {
-		ctx.SetStatusCode(fasthttp.StatusForbidden)
-		ctx.SetBodyString(err.Error())
+	if h.walletLoginHandler(ctx) {
 		return
 	}
 
-	service := h.loadService(h.requestDetail.ServiceNameStr)
+	c := &ProxyContext{Ctx: ctx}
+	defer func() {
+		if !c.Upgraded {
+			c.CloseSession()
+		}
+	}()
 
-	if websocket.FastHTTPIsWebSocketUpgrade(ctx) && (service.Schema == "ws" || service.Schema == "wss") {
-		h.forwardWebsocketRequest(ctx, service)
-	} else if service.Schema == "http" || service.Schema == "https" {
-		h.forwardHttpRequest(ctx, service)
-	} else {
-		ctx.SetStatusCode(fasthttp.StatusBadRequest)
-		ctx.SetBodyString("regisited service has different schema with request")
+	if err := h.pipeline(c); err != nil {
+		internal.WriteErrorResponse(ctx, err)
 	}
 }
-func (h *ProxyHandler) forwardWebsocketRequest(ctx *fasthttp.RequestCtx, service models.ApronService) {
-	h.upgrader = &websocket.FastHTTPUpgrader{
-		ReadBufferSize:  1024,
-		WriteBufferSize: 1024,
-		CheckOrigin: func(ctx *fasthttp.RequestCtx) bool {
-			return true
-		},
+
+// ForwardHandler forwards request to an upstream target of the service loaded in proxy context.
+// The request is transparent proxied with websocket or http based on service schema.
+func (h *ProxyHandler) ForwardHandler(c *ProxyContext) error {
+	service := c.Service
+
+	isWebsocket := websocket.FastHTTPIsWebSocketUpgrade(c.Ctx) && (service.Schema == "ws" || service.Schema == "wss")
+	if !isWebsocket && service.Schema != "http" && service.Schema != "https" {
+		return internal.BadRequestError("regisited service has different schema with request")
+	}
+
+	if isWebsocket {
+		if err := h.pickUpstream(c); err != nil {
+			return err
+		}
+		return h.forwardWebsocketRequest(c)
 	}
+	return h.forwardHttpRequest(c)
+}
 
-	var err error
+func (h *ProxyHandler) forwardWebsocketRequest(c *ProxyContext) error {
+	service := c.Service
 
-	serviceUrlStr := fmt.Sprintf("%s://%s", service.Schema, service.BaseUrl)
+	serviceUrlStr := fmt.Sprintf("%s://%s", service.Schema, c.Target)
 	serviceUrl, _ := url.Parse(serviceUrlStr)
-	fmt.Printf("Service url: %+v\n", serviceUrl)
 
+	// Handshake is limited by total timeout if declared, or the read timeout
+	timeouts := toUpstreamTimeouts(service.Timeouts)
+	handshakeTimeout := timeouts.Read
+	if timeouts.Total > 0 {
+		handshakeTimeout = timeouts.Total
+	}
 	dialer := websocket.Dialer{
-		HandshakeTimeout: 15 * time.Second,
+		NetDial: func(network, addr string) (net.Conn, error) {
+			return net.DialTimeout(network, addr, timeouts.Connect)
+		},
+		HandshakeTimeout: handshakeTimeout,
 	}
 
 	// TODO: Check whether header information are required for service ws
 	proxyServerWsConn, _, err := dialer.Dial(serviceUrl.String(), nil)
-	internal.CheckError(err)
+	c.reportUpstream(err == nil)
+	if err != nil {
+		return h.upstreamError(c, err, handshakeTimeoutCause(err, timeouts))
+	}
 
-	err = h.upgrader.Upgrade(ctx, func(clientWsConn *websocket.Conn) {
+	// The session outlives the pipeline, so hooks registered in context are called after session closed
+	c.Upgraded = true
+	err = h.upgrader.Upgrade(c.Ctx, func(clientWsConn *websocket.Conn) {
+		defer c.CloseSession()
 		defer clientWsConn.Close()
+		defer proxyServerWsConn.Close()
 
 		var (
 			errClient      = make(chan error, 1)
 			errProxyServer = make(chan error, 1)
+			client         = &lockedWsConn{Conn: clientWsConn}
+			proxyServer    = &lockedWsConn{Conn: proxyServerWsConn, writeTimeout: timeouts.Write}
 		)
 
-		go forwardWsMessage(clientWsConn, proxyServerWsConn, errClient)
-		go forwardWsMessage(proxyServerWsConn, clientWsConn, errProxyServer)
-
-		for {
-			select {
-			case err := <-errClient:
-				fmt.Sprintf("Error while forwarding response: %+v\n", err.Error())
-			case err := <-errProxyServer:
-				fmt.Sprintf("Error while forwarding request: %+v\n", err.Error())
-			}
+		// Messages from client are checked with key scopes, and rejected messages are responded to client
+		checkClientMessage := func(msg []byte) error {
+			return models.CheckWsMessageScopes(c.Scopes, msg)
+		}
+		go forwardWsMessage(client, proxyServer, checkClientMessage, errClient)
+		go forwardWsMessage(proxyServer, client, nil, errProxyServer)
+
+		// Session ends once either side closed, and the other side will be closed by deferred calls
+		var sessionErr error
+		side := "client"
+		select {
+		case sessionErr = <-errClient:
+		case sessionErr = <-errProxyServer:
+			side = "upstream"
 		}
+		h.logWsSessionError(c, side, sessionErr)
 	})
-	internal.CheckError(err)
+	if err != nil {
+		c.Upgraded = false
+		proxyServerWsConn.Close()
+		return internal.BadRequestError("websocket upgrade failed: %v", err)
+	}
+	return nil
 }
 
-func (h *ProxyHandler) forwardHttpRequest(ctx *fasthttp.RequestCtx, service models.ApronService) {
-	// Build URI, the forward URL is local httpbin URL
-	serviceUrlStr := fmt.Sprintf("%s://%s", service.Schema, service.BaseUrl)
+// upstreamRequestUrl builds url of the request with base url of picked target
+func upstreamRequestUrl(c *ProxyContext) string {
+	requestDetail := c.RequestDetail
+	serviceUrlStr := fmt.Sprintf("%s://%s", c.Service.Schema, c.Target)
 	serviceUrl, _ := url.Parse(serviceUrlStr)
-	if bytes.Compare(h.requestDetail.Path, []byte("/")) != 0 {
-		serviceUrl.Path += string(h.requestDetail.ProxyRequestPath)
+	if bytes.Compare(requestDetail.Path, []byte("/")) != 0 {
+		serviceUrl.Path += string(requestDetail.ProxyRequestPath)
 	}
 
 	query := serviceUrl.Query()
-	for k, values := range h.requestDetail.QueryParams {
+	for k, values := range requestDetail.QueryParams {
 		for _, v := range values {
 			query.Add(k, v)
 		}
 	}
 	serviceUrl.RawQuery = query.Encode()
+	return serviceUrl.String()
+}
 
-	fmt.Printf("host: %+v, path: %+v, queries: %+v\n", serviceUrl.Host, serviceUrl.Path, serviceUrl.RawQuery)
+// forwardHttpRequest forwards request to picked target, and failed attempts are retried with retry policy of service.
+// Retries happen after the request metered, so they are not counted in usage.
+func (h *ProxyHandler) forwardHttpRequest(c *ProxyContext) error {
+	ctx := c.Ctx
+	service := c.Service
+	requestDetail := c.RequestDetail
 
 	// Build request, query params are included in URI
 	proxyReq := fasthttp.AcquireRequest()
@@ -181,16 +248,88 @@ func (h *ProxyHandler) forwardHttpRequest(ctx *fasthttp.RequestCtx, service mode
 	defer fasthttp.ReleaseRequest(proxyReq)
 	defer fasthttp.ReleaseResponse(proxyResp)
 
-	proxyReq.SetRequestURI(serviceUrl.String())
 	ctx.Request.Header.VisitAll(func(k, v []byte) {
 		proxyReq.Header.SetCanonical(k, v)
 	})
-	proxyReq.Header.SetMethod(h.requestDetail.Method)
-	proxyReq.SetBody(h.requestDetail.RequestBody)
-	if err := fasthttp.Do(proxyReq, proxyResp); err != nil {
-		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
-		ctx.WriteString(err.Error())
-		return
+	proxyReq.Header.SetMethod(requestDetail.Method)
+	proxyReq.SetBody(requestDetail.RequestBody)
+
+	timeouts := toUpstreamTimeouts(service.Timeouts)
+	client := h.clients.get(timeouts)
+	var deadline time.Time
+	if timeouts.Total > 0 {
+		deadline = time.Now().Add(timeouts.Total)
+	}
+
+	policy := service.RetryPolicy
+	maxAttempts := 1
+	if policy != nil {
+		h.RetryBudget.RecordRequest(service.Id, toBudgetPolicy(policy))
+		if policy.RetryNonIdempotent || models.IsIdempotentMethod(requestDetail.Method) {
+			maxAttempts = models.RetryMaxAttempts(policy)
+		}
+	}
+
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		if err := h.pickUpstream(c); err != nil {
+			if attempt == 1 {
+				return err
+			}
+			// No target left for retry, result of the last attempt is responded
+			if lastErr != nil {
+				return h.upstreamRequestError(c, lastErr, deadline)
+			}
+			break
+		}
+		proxyReq.SetRequestURI(upstreamRequestUrl(c))
+		proxyResp.Reset()
+
+		var err error
+		if deadline.IsZero() {
+			err = client.Do(proxyReq, proxyResp)
+		} else {
+			err = client.DoDeadline(proxyReq, proxyResp, deadline)
+		}
+		lastErr = err
+		status := 0
+		if err == nil {
+			status = proxyResp.StatusCode()
+		}
+		c.reportUpstream(err == nil && status < fasthttp.StatusInternalServerError)
+
+		// Retry is skipped if no time is left for another attempt after backoff
+		var backoff time.Duration
+		if policy != nil {
+			backoff = retryBackoff(policy, attempt)
+		}
+		deadlineExceeded := !deadline.IsZero() && time.Until(deadline) <= backoff
+		if attempt >= maxAttempts || deadlineExceeded || !isRetryable(policy, err, status) || !h.RetryBudget.AllowRetry(service.Id, toBudgetPolicy(policy)) {
+			if err != nil {
+				return h.upstreamRequestError(c, err, deadline)
+			}
+			break
+		}
+
+		cause := fmt.Sprintf("status %d", status)
+		if err != nil {
+			cause = err.Error()
+		}
+		h.Logger.Log(fmt.Sprintf("%s|retry|service: %s, target: %s, attempt: %d, cause: %s\n",
+			time.Now().UTC().Format("2006-01-02 15:04:05"),
+			service.Id,
+			c.Target,
+			attempt,
+			cause,
+		))
+
+		// Target of failed attempt is released, and avoided by the retry
+		c.releaseTarget()
+		if c.triedTargets == nil {
+			c.triedTargets = make(map[string]bool)
+		}
+		c.triedTargets[c.Target] = true
+		time.Sleep(backoff)
 	}
 
 	respBody := proxyResp.Body()
@@ -203,40 +342,176 @@ func (h *ProxyHandler) forwardHttpRequest(ctx *fasthttp.RequestCtx, service mode
 	})
 
 	ctx.SetBody(respBody)
+	return nil
 }
 
-// TODO: Validator related, perhaps can move to a new middleware
-
 // validateRequest checks whether the request can be forwarded to backend services.
-// It will check whether the key is existing in ApronApiKey:<service_name> bucket/table
-func (h *ProxyHandler) validateRequest(ctx *fasthttp.RequestCtx) error {
-	// Check whether API key and service has related record
-	serviceBucketName := internal.ServiceApiKeyStorageBucketName(h.requestDetail.ServiceNameStr)
-	if h.StorageManager.IsKeyExisting(serviceBucketName) && h.StorageManager.IsKeyExistingInBucket(serviceBucketName, h.requestDetail.ApiKeyStr) {
-		return nil
-	}
-	// Key not found in service bucket, return forbidden
-	ctx.SetStatusCode(fasthttp.StatusForbidden)
-	return errors.New("unauthorized")
+// It will check whether the hash of key is existing in ApronApiKey:<service_name> bucket/table,
+// and the key record will be saved in proxy context if found.
+func (h *ProxyHandler) validateRequest(c *ProxyContext) error {
+	invalidKeyErr := internal.UnauthorizedError("invalid api key for service %s", c.RequestDetail.ServiceNameStr)
+
+	apiKeyStr := c.RequestDetail.ApiKeyStr
+	if apiKeyStr == "" {
+		return internal.UnauthorizedError("missing api key for service %s", c.RequestDetail.ServiceNameStr)
+	}
+	if models.IsWellFormedAccessToken(apiKeyStr) {
+		return h.validateAccessToken(c)
+	}
+	// Prefixed keys failing checksum are rejected without lookup, while legacy keys migrated from plaintext have no prefix
+	if strings.HasPrefix(apiKeyStr, models.ApiKeyPrefix) && !models.IsWellFormedApiKey(apiKeyStr) {
+		return invalidKeyErr
+	}
+	apiKey, err := h.RecordCache.GetApiKey(c.RequestDetail.ServiceNameStr, models.HashApiKey(apiKeyStr))
+	if err != nil {
+		if internal.ToGatewayError(err).Code == internal.ErrCodeNotFound {
+			// Key not found in service bucket
+			return invalidKeyErr
+		}
+		return err
+	}
+	if apiKey.AuthMode == models.ApiKeyAuthModeHmac {
+		// Secret of hmac key should never be sent in request
+		return internal.UnauthorizedError("key %s only accepts signed request", models.ApiKeyId(apiKey))
+	}
+
+	return h.authorizeApiKey(c, apiKey)
 }
 
-func (h *ProxyHandler) loadService(serviceName string) models.ApronService {
-	r, err := h.StorageManager.GetRecord(internal.ServiceBucketName, serviceName)
-	internal.CheckError(err)
+// authorizeApiKey checks expiry, client restrictions and scopes of the authenticated key,
+// and the key is saved in proxy context if the request is allowed.
+func (h *ProxyHandler) authorizeApiKey(c *ProxyContext, apiKey *models.ApronApiKey) error {
+	if apiKey.ExpiredAt != 0 && time.Now().Unix() >= apiKey.ExpiredAt {
+		return internal.KeyExpiredError("api key expired at %s", time.Unix(apiKey.ExpiredAt, 0).UTC().Format(time.RFC3339))
+	}
+	if len(apiKey.AllowedOrigins) > 0 {
+		// CORS wrapper echoes origin only if allowed by the key
+		c.Ctx.SetUserValue(corsApiKeyUserValue, apiKey)
+	}
+	header := &c.Ctx.Request.Header
+	if err := models.CheckClientRestrictions(apiKey, c.Ctx.RemoteIP(), string(header.Peek("Origin")), string(header.Referer())); err != nil {
+		return internal.ForbiddenError(err.Error())
+	}
+	scopes, err := models.MatchApiKeyScopes(apiKey.Scopes, c.RequestDetail.Method, string(c.RequestDetail.ProxyRequestPath))
+	if err != nil {
+		return internal.ForbiddenError(err.Error())
+	}
 
-	service := models.ApronService{}
-	err = proto.Unmarshal([]byte(r), &service)
-	internal.CheckError(err)
+	c.ApiKey = apiKey
+	c.Scopes = scopes
+	return nil
+}
 
-	return service
+// extractApiKey finds api key from locations accepted by the service in order, and credentials in
+// all accepted locations are removed from the request so they won't be forwarded to upstream service.
+func extractApiKey(c *ProxyContext) {
+	detail := c.RequestDetail
+	header := &c.Ctx.Request.Header
+
+	for _, location := range models.ApiKeyLocations(c.Service) {
+		key := ""
+		switch location {
+		case models.ApiKeyLocationBearer:
+			auth := string(header.Peek("Authorization"))
+			if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+				key = strings.TrimSpace(auth[7:])
+				header.Del("Authorization")
+				delete(detail.Headers, "Authorization")
+			}
+		case models.ApiKeyLocationHeader:
+			key = string(header.Peek(models.ApiKeyHeaderName))
+			header.Del(models.ApiKeyHeaderName)
+			delete(detail.Headers, models.ApiKeyHeaderName)
+		case models.ApiKeyLocationQuery:
+			param := models.ApiKeyQueryParam(c.Service)
+			if values := detail.QueryParams[param]; len(values) > 0 {
+				key = values[0]
+			}
+			delete(detail.QueryParams, param)
+		case models.ApiKeyLocationPath:
+			if detail.ApiKeyStr != "" {
+				// Service path is not touched if key already found in other locations
+				continue
+			}
+			// Key is the first segment of service path, such as /v1/<service>/<key>/anything
+			segments := bytes.SplitN(bytes.TrimPrefix(detail.ServicePath, []byte("/")), []byte("/"), 2)
+			if len(segments) == 2 && len(segments[0]) > 0 {
+				key = string(segments[0])
+				detail.ProxyRequestPath = segments[1]
+			}
+		}
+
+		if key != "" && detail.ApiKeyStr == "" {
+			detail.ApiKeyStr = key
+			detail.ApiKey = []byte(key)
+			detail.ApiKeyLocation = location
+		}
+	}
+}
+
+// isCorsPreflight checks whether the request is a CORS preflight sent by browser before the actual request
+func isCorsPreflight(ctx *fasthttp.RequestCtx) bool {
+	return ctx.IsOptions() && len(ctx.Request.Header.Peek("Access-Control-Request-Method")) > 0
+}
+
+// CorsAllowOrigin returns value of Access-Control-Allow-Origin if allowed origins restricted by the api key of request,
+// the origin is echoed if allowed, otherwise empty string is returned.
+func CorsAllowOrigin(ctx *fasthttp.RequestCtx) (allowOrigin string, restricted bool) {
+	apiKey, ok := ctx.UserValue(corsApiKeyUserValue).(*models.ApronApiKey)
+	if !ok {
+		return "", false
+	}
+	origin := string(ctx.Request.Header.Peek("Origin"))
+	if models.MatchAllowedOrigin(apiKey, origin) {
+		return origin, true
+	}
+	return "", true
+}
+
+func (h *ProxyHandler) loadService(serviceName string) (*models.ApronService, error) {
+	return h.RecordCache.GetService(serviceName)
 }
 
-func forwardWsMessage(src, dest *websocket.Conn, errCh chan error) {
+// lockedWsConn serializes writes to websocket connection, since client connection is written by both forwarders
+type lockedWsConn struct {
+	*websocket.Conn
+	writeLock sync.Mutex
+	// writeTimeout limits writing each message if positive
+	writeTimeout time.Duration
+}
+
+func (c *lockedWsConn) WriteMessage(messageType int, data []byte) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	if c.writeTimeout > 0 {
+		c.Conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	}
+	return c.Conn.WriteMessage(messageType, data)
+}
+
+// logWsSessionError writes error ended websocket session to gateway log, normal closures are not logged.
+// Message payload is never logged since it may carry user data.
+func (h *ProxyHandler) logWsSessionError(c *ProxyContext, side string, err error) {
+	if err == nil || websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		return
+	}
+	h.Logger.Log(fmt.Sprintf("%s|ws session error|service: %s, api_key: %s, target: %s, side: %s, error: %v\n",
+		time.Now().UTC().Format("2006-01-02 15:04:05"),
+		c.RequestDetail.ServiceNameStr,
+		models.ApiKeyId(c.ApiKey),
+		c.Target,
+		side,
+		err,
+	))
+}
+
+// forwardWsMessage forwards messages from src to dest until error occurred,
+// and the message is dropped with error written back to src if check failed.
+func forwardWsMessage(src, dest *lockedWsConn, check func(msg []byte) error, errCh chan error) {
 	for {
 		msgType, msgBytes, err := src.ReadMessage()
 
 		if err != nil {
-			fmt.Printf("src.ReadMessage failed, msgType=%d, msg=%s, err=%v\n", msgType, msgBytes, err)
 			if ce, ok := err.(*websocket.CloseError); ok {
 				msgBytes = websocket.FormatCloseMessage(ce.Code, ce.Text)
 			} else {
@@ -245,16 +520,24 @@ func forwardWsMessage(src, dest *websocket.Conn, errCh chan error) {
 
 			errCh <- err
 
-			if err = dest.WriteMessage(websocket.CloseMessage, msgBytes); err != nil {
-				fmt.Printf("write close message failed, err=%v", err)
-			}
+			// Close message can't be written if dest is already closed, which is expected when session ends
+			_ = dest.WriteMessage(websocket.CloseMessage, msgBytes)
 
 			break
 		}
 
-		dest.WriteMessage(msgType, msgBytes)
-		if err != nil {
-			fmt.Printf("dest.WriteMessage error: %v\n", err)
+		if check != nil {
+			if checkErr := check(msgBytes); checkErr != nil {
+				errBody, _ := json.Marshal(internal.ForbiddenError(checkErr.Error()))
+				if err = src.WriteMessage(websocket.TextMessage, errBody); err != nil {
+					errCh <- err
+					break
+				}
+				continue
+			}
+		}
+
+		if err = dest.WriteMessage(msgType, msgBytes); err != nil {
 			errCh <- err
 			break
 		}
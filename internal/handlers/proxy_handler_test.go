@@ -0,0 +1,578 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/protobuf/proto"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/handlers/ratelimiter"
+	"apron.network/gateway/internal/models"
+)
+
+// startEchoUpstream starts a http server which responds request path and body
+func startEchoUpstream(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen upstream: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go fasthttp.Serve(ln, func(ctx *fasthttp.RequestCtx) {
+		fmt.Fprintf(ctx, "%s|%s", ctx.Path(), ctx.PostBody())
+	})
+
+	return ln.Addr().String()
+}
+
+func newTestProxyHandler(t *testing.T, storageManager models.StorageManager) *ProxyHandler {
+	logDir, err := ioutil.TempDir("", "proxy_test")
+	if err != nil {
+		t.Fatalf("failed to create log dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(logDir) })
+
+	logger := &internal.GatewayLogger{LogFile: filepath.Join(logDir, "proxy_log.txt")}
+	logger.Init()
+
+	accessLogChannel := make(chan string, 1024)
+	go func() {
+		for range accessLogChannel {
+		}
+	}()
+
+	manager := &models.AggregatedAccessRecordManager{}
+	manager.Init()
+
+	proxy := &ProxyHandler{
+		StorageManager:          storageManager,
+		RateLimiter:             ratelimiter.New(ratelimiter.Options{Max: 1000000, Duration: time.Minute}),
+		Logger:                  logger,
+		AggrAccessRecordManager: manager,
+		AccessLogChannel:        accessLogChannel,
+	}
+	proxy.Init()
+	return proxy
+}
+
+func TestProxyHandlerConcurrentRequests(t *testing.T) {
+	const (
+		serviceCount      = 8
+		keyCount          = 8
+		requestCountByKey = 20
+	)
+
+	redisServer, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start redis: %v", err)
+	}
+	defer redisServer.Close()
+
+	storageManager := models.NewRedisStorageManager(redis.NewClient(&redis.Options{Addr: redisServer.Addr()}))
+
+	// Each service forwards to a different base path of echo upstream
+	upstreamAddr := startEchoUpstream(t)
+	for i := 0; i < serviceCount; i++ {
+		serviceId := fmt.Sprintf("service-%d", i)
+		service := &models.ApronService{
+			Id:      serviceId,
+			Name:    serviceId,
+			BaseUrl: fmt.Sprintf("%s/%s/", upstreamAddr, serviceId),
+			Schema:  "http",
+		}
+		binaryService, _ := proto.Marshal(service)
+		if err := storageManager.SaveBinaryKeyData(internal.ServiceBucketName, serviceId, binaryService); err != nil {
+			t.Fatalf("failed to save service: %v", err)
+		}
+
+		for j := 0; j < keyCount; j++ {
+			apiKey := &models.ApronApiKey{Key: fmt.Sprintf("key-%d-%d", i, j), ServiceId: serviceId}
+			binaryKey, _ := proto.Marshal(apiKey)
+			if err := storageManager.SaveBinaryKeyData(internal.ServiceApiKeyStorageBucketName(serviceId), models.HashApiKey(apiKey.Key), binaryKey); err != nil {
+				t.Fatalf("failed to save key: %v", err)
+			}
+		}
+	}
+
+	proxy := newTestProxyHandler(t, storageManager)
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	go fasthttp.Serve(ln, proxy.InternalHandler)
+
+	client := &fasthttp.Client{
+		Dial: func(addr string) (net.Conn, error) {
+			return ln.Dial()
+		},
+		MaxConnsPerHost: serviceCount * keyCount,
+	}
+
+	wg := sync.WaitGroup{}
+	errCh := make(chan error, serviceCount*keyCount*requestCountByKey)
+	for i := 0; i < serviceCount; i++ {
+		for j := 0; j < keyCount; j++ {
+			wg.Add(1)
+			go func(serviceId, key string) {
+				defer wg.Done()
+				for n := 0; n < requestCountByKey; n++ {
+					req := fasthttp.AcquireRequest()
+					resp := fasthttp.AcquireResponse()
+
+					reqBody := fmt.Sprintf("%s-%d", key, n)
+					req.SetRequestURI(fmt.Sprintf("http://proxy/v1/%s/%s/echo/%s", serviceId, key, key))
+					req.Header.SetMethod("POST")
+					req.SetBodyString(reqBody)
+
+					if err := client.Do(req, resp); err != nil {
+						errCh <- err
+					} else if expected := fmt.Sprintf("/%s/echo/%s|%s", serviceId, key, reqBody); string(resp.Body()) != expected {
+						errCh <- fmt.Errorf("expected response %q, got %q (status %d)", expected, resp.Body(), resp.StatusCode())
+					}
+
+					fasthttp.ReleaseRequest(req)
+					fasthttp.ReleaseResponse(resp)
+				}
+			}(fmt.Sprintf("service-%d", i), fmt.Sprintf("key-%d-%d", i, j))
+		}
+	}
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		t.Error(err)
+	}
+
+	usages, _ := proxy.AggrAccessRecordManager.ExportAllUsage()
+	if len(usages) != serviceCount*keyCount {
+		t.Fatalf("expected %d usage records, got %d", serviceCount*keyCount, len(usages))
+	}
+	for _, usage := range usages {
+		if usage.Usage != requestCountByKey {
+			t.Errorf("expected usage %d for %s/%s, got %d", requestCountByKey, usage.ServiceUuid, usage.UserKey, usage.Usage)
+		}
+	}
+}
+
+func TestProxyHandlerErrorResponses(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start redis: %v", err)
+	}
+	defer redisServer.Close()
+
+	storageManager := models.NewRedisStorageManager(redis.NewClient(&redis.Options{Addr: redisServer.Addr()}))
+	service := &models.ApronService{Id: "test-service", Name: "test-service", BaseUrl: "localhost", Schema: "http"}
+	binaryService, _ := proto.Marshal(service)
+	storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+	apiKey := &models.ApronApiKey{Key: "valid-key", ServiceId: "no-such-service"}
+	binaryKey, _ := proto.Marshal(apiKey)
+	storageManager.SaveBinaryKeyData(internal.ServiceApiKeyStorageBucketName(apiKey.ServiceId), models.HashApiKey(apiKey.Key), binaryKey)
+
+	proxy := newTestProxyHandler(t, storageManager)
+
+	testCases := []struct {
+		uri    string
+		status int
+		code   string
+	}{
+		{"/not/proxy/path", fasthttp.StatusNotFound, internal.ErrCodeNotFound},
+		{"/v1/test-service/invalid-key/anything", fasthttp.StatusUnauthorized, internal.ErrCodeUnauthorized},
+		{"/v1/test-service/", fasthttp.StatusUnauthorized, internal.ErrCodeUnauthorized},
+		{"/v1/no-such-service/valid-key/anything", fasthttp.StatusNotFound, internal.ErrCodeNotFound},
+	}
+
+	for _, tc := range testCases {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.SetRequestURI(tc.uri)
+		proxy.InternalHandler(ctx)
+
+		gatewayErr := internal.GatewayError{}
+		if err := json.Unmarshal(ctx.Response.Body(), &gatewayErr); err != nil {
+			t.Errorf("%s: invalid error body %q: %v", tc.uri, ctx.Response.Body(), err)
+		}
+		if ctx.Response.StatusCode() != tc.status || gatewayErr.Code != tc.code {
+			t.Errorf("%s: expected %d/%s, got %d/%s", tc.uri, tc.status, tc.code, ctx.Response.StatusCode(), gatewayErr.Code)
+		}
+	}
+
+	// Storage down should not panic and is reported as storage unavailable, uncached service is used to hit storage
+	redisServer.Close()
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI("/v1/uncached-service/some-key/anything")
+	proxy.InternalHandler(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusServiceUnavailable {
+		t.Errorf("expected status %d while storage down, got %d", fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode())
+	}
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	handler := RecoveryMiddleware(func(ctx *fasthttp.RequestCtx) {
+		panic("unexpected")
+	})
+
+	ctx := &fasthttp.RequestCtx{}
+	handler(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", fasthttp.StatusInternalServerError, ctx.Response.StatusCode())
+	}
+}
+
+func TestProxyHandlerRateLimit(t *testing.T) {
+	storageManager := models.NewMemoryStorageManager()
+	upstreamAddr := startEchoUpstream(t)
+
+	service := &models.ApronService{
+		Id:                "test_service",
+		BaseUrl:           upstreamAddr + "/",
+		Schema:            "http",
+		RateLimitPolicies: []*models.RateLimitPolicy{{Max: 2, DurationMs: 60000}},
+	}
+	binaryService, _ := proto.Marshal(service)
+	storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+
+	// Policy declared in key overrides service policy
+	for _, apiKey := range []*models.ApronApiKey{
+		{Key: "service-policy-key", ServiceId: service.Id},
+		{Key: "key-policy-key", ServiceId: service.Id, RateLimitPolicies: []*models.RateLimitPolicy{{Max: 3, DurationMs: 60000}}},
+	} {
+		binaryKey, _ := proto.Marshal(apiKey)
+		storageManager.SaveBinaryKeyData(internal.ServiceApiKeyStorageBucketName(service.Id), models.HashApiKey(apiKey.Key), binaryKey)
+	}
+
+	proxy := newTestProxyHandler(t, storageManager)
+
+	for key, allowed := range map[string]int{"service-policy-key": 2, "key-policy-key": 3} {
+		for i := 0; i <= allowed; i++ {
+			ctx := &fasthttp.RequestCtx{}
+			ctx.Request.SetRequestURI(fmt.Sprintf("/v1/%s/%s/anything", service.Id, key))
+			proxy.InternalHandler(ctx)
+
+			if limit := string(ctx.Response.Header.Peek("RateLimit-Limit")); limit != strconv.Itoa(allowed) {
+				t.Errorf("%s: expected RateLimit-Limit %d, got %q", key, allowed, limit)
+			}
+			if remaining := string(ctx.Response.Header.Peek("X-RateLimit-Remaining")); i < allowed && remaining != strconv.Itoa(allowed-i-1) {
+				t.Errorf("%s: expected X-RateLimit-Remaining %d, got %q", key, allowed-i-1, remaining)
+			}
+
+			if i < allowed {
+				if ctx.Response.StatusCode() != fasthttp.StatusOK {
+					t.Errorf("%s: request %d expected to be allowed, got status %d", key, i, ctx.Response.StatusCode())
+				}
+				continue
+			}
+
+			gatewayErr := internal.GatewayError{}
+			json.Unmarshal(ctx.Response.Body(), &gatewayErr)
+			if ctx.Response.StatusCode() != fasthttp.StatusTooManyRequests || gatewayErr.Code != internal.ErrCodeRateLimited {
+				t.Errorf("%s: expected 429 rate_limited, got %d %q", key, ctx.Response.StatusCode(), ctx.Response.Body())
+			}
+			if len(ctx.Response.Header.Peek("Retry-After")) == 0 {
+				t.Errorf("%s: missing Retry-After header", key)
+			}
+		}
+	}
+
+	// Blocked requests are not counted in usage
+	usages, _ := proxy.AggrAccessRecordManager.ExportAllUsage()
+	for _, usage := range usages {
+		if expected := map[string]uint64{"service-policy-key": 2, "key-policy-key": 3}[usage.UserKey]; usage.Usage != expected {
+			t.Errorf("expected usage %d for %s, got %d", expected, usage.UserKey, usage.Usage)
+		}
+	}
+}
+
+func TestProxyHandlerQuota(t *testing.T) {
+	storageManager := models.NewMemoryStorageManager()
+	upstreamAddr := startEchoUpstream(t)
+
+	service := &models.ApronService{
+		Id:          "test_service",
+		BaseUrl:     upstreamAddr + "/",
+		Schema:      "http",
+		QuotaPolicy: &models.QuotaPolicy{Period: models.QuotaPeriodMonthly, Limit: 2, WarningThresholds: []int32{50}, ExceededStatus: 402},
+	}
+	binaryService, _ := proto.Marshal(service)
+	storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+	binaryKey, _ := proto.Marshal(&models.ApronApiKey{Key: "test_key", ServiceId: service.Id})
+	storageManager.SaveBinaryKeyData(internal.ServiceApiKeyStorageBucketName(service.Id), models.HashApiKey("test_key"), binaryKey)
+
+	proxy := newTestProxyHandler(t, storageManager)
+
+	for i := 0; i < 3; i++ {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.SetRequestURI(fmt.Sprintf("/v1/%s/test_key/anything", service.Id))
+		proxy.InternalHandler(ctx)
+
+		if limit := string(ctx.Response.Header.Peek("X-Quota-Limit")); limit != "2" {
+			t.Errorf("request %d: expected X-Quota-Limit 2, got %q", i, limit)
+		}
+		if i < 2 {
+			if ctx.Response.StatusCode() != fasthttp.StatusOK {
+				t.Errorf("request %d: expected to be allowed, got status %d", i, ctx.Response.StatusCode())
+			}
+			if remaining := string(ctx.Response.Header.Peek("X-Quota-Remaining")); remaining != strconv.Itoa(1-i) {
+				t.Errorf("request %d: expected X-Quota-Remaining %d, got %q", i, 1-i, remaining)
+			}
+			if len(ctx.Response.Header.Peek("X-Quota-Warning")) == 0 {
+				t.Errorf("request %d: missing X-Quota-Warning header", i)
+			}
+			continue
+		}
+
+		gatewayErr := internal.GatewayError{}
+		json.Unmarshal(ctx.Response.Body(), &gatewayErr)
+		if ctx.Response.StatusCode() != fasthttp.StatusPaymentRequired || gatewayErr.Code != internal.ErrCodeQuotaExceeded {
+			t.Errorf("expected 402 quota_exceeded, got %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+	}
+}
+
+func TestProxyHandlerConcurrencyLimit(t *testing.T) {
+	storageManager := models.NewMemoryStorageManager()
+
+	// Upstream blocks until released, so requests are kept in flight
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen upstream: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	received := make(chan struct{}, 10)
+	unblock := make(chan struct{})
+	go fasthttp.Serve(ln, func(ctx *fasthttp.RequestCtx) {
+		received <- struct{}{}
+		<-unblock
+	})
+
+	service := &models.ApronService{
+		Id:                   "test_service",
+		BaseUrl:              ln.Addr().String() + "/",
+		Schema:               "http",
+		KeyConcurrencyPolicy: &models.ConcurrencyPolicy{MaxInFlight: 1, MaxQueue: 1, QueueTimeoutMs: 5000},
+	}
+	binaryService, _ := proto.Marshal(service)
+	storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+	binaryKey, _ := proto.Marshal(&models.ApronApiKey{Key: "test_key", ServiceId: service.Id})
+	storageManager.SaveBinaryKeyData(internal.ServiceApiKeyStorageBucketName(service.Id), models.HashApiKey("test_key"), binaryKey)
+
+	proxy := newTestProxyHandler(t, storageManager)
+	sendRequest := func() *fasthttp.RequestCtx {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.SetRequestURI(fmt.Sprintf("/v1/%s/test_key/anything", service.Id))
+		proxy.InternalHandler(ctx)
+		return ctx
+	}
+
+	// First request is in flight, and the second one waits in queue
+	results := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() { results <- sendRequest().Response.StatusCode() }()
+	}
+	<-received
+	for start := time.Now(); proxy.ConcurrencyLimiter.Waiting("key:test_service:test_key") == 0; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("request not queued")
+		}
+	}
+
+	ctx := sendRequest()
+	gatewayErr := internal.GatewayError{}
+	json.Unmarshal(ctx.Response.Body(), &gatewayErr)
+	if ctx.Response.StatusCode() != fasthttp.StatusServiceUnavailable || gatewayErr.Code != internal.ErrCodeConcurrencyLimited {
+		t.Errorf("expected 503 concurrency_limited after queue is full, got %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	close(unblock)
+	for i := 0; i < 2; i++ {
+		if status := <-results; status != fasthttp.StatusOK {
+			t.Errorf("expected in flight and queued requests succeeded, got %d", status)
+		}
+	}
+	if inFlight := proxy.ConcurrencyLimiter.InFlight("key:test_service:test_key"); inFlight != 0 {
+		t.Errorf("expected all slots released, got %d in flight", inFlight)
+	}
+}
+
+func TestProxyHandlerApiKeyLocations(t *testing.T) {
+	// Upstream responds credentials it received, which should be stripped by proxy
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen upstream: %v", err)
+	}
+	defer ln.Close()
+	go fasthttp.Serve(ln, func(ctx *fasthttp.RequestCtx) {
+		fmt.Fprintf(ctx, "%s|%s|%s|%s", ctx.Path(), ctx.QueryArgs().String(),
+			ctx.Request.Header.Peek("Authorization"), ctx.Request.Header.Peek(models.ApiKeyHeaderName))
+	})
+
+	storageManager := models.NewMemoryStorageManager()
+	services := []*models.ApronService{
+		{Id: "default_service", BaseUrl: ln.Addr().String() + "/", Schema: "http"},
+		{
+			Id:            "header_service",
+			BaseUrl:       ln.Addr().String() + "/",
+			Schema:        "http",
+			KeyLocations:  []string{models.ApiKeyLocationBearer, models.ApiKeyLocationQuery},
+			KeyQueryParam: "token",
+		},
+	}
+	for _, service := range services {
+		binaryService, _ := proto.Marshal(service)
+		storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+		binaryKey, _ := proto.Marshal(&models.ApronApiKey{Key: "test_key", ServiceId: service.Id})
+		storageManager.SaveBinaryKeyData(internal.ServiceApiKeyStorageBucketName(service.Id), models.HashApiKey("test_key"), binaryKey)
+	}
+
+	proxy := newTestProxyHandler(t, storageManager)
+
+	testCases := []struct {
+		uri     string
+		headers map[string]string
+		status  int
+		body    string
+	}{
+		{"/v1/default_service/test_key/anything", nil, fasthttp.StatusOK, "/anything|||"},
+		{"/v1/default_service/anything", map[string]string{"Authorization": "Bearer test_key"}, fasthttp.StatusOK, "/anything|||"},
+		{"/v1/default_service/anything", map[string]string{models.ApiKeyHeaderName: "test_key"}, fasthttp.StatusOK, "/anything|||"},
+		{"/v1/default_service/anything?api_key=test_key", nil, fasthttp.StatusUnauthorized, ""},
+		{"/v1/header_service/anything?token=test_key&foo=bar", nil, fasthttp.StatusOK, "/anything|foo=bar||"},
+		{"/v1/header_service/anything", map[string]string{"Authorization": "bearer test_key"}, fasthttp.StatusOK, "/anything|||"},
+		{"/v1/header_service/anything", map[string]string{models.ApiKeyHeaderName: "test_key"}, fasthttp.StatusUnauthorized, ""},
+		{"/v1/header_service/test_key/anything", nil, fasthttp.StatusUnauthorized, ""},
+		{"/v1/header_service/anything", map[string]string{"Authorization": "Basic dGVzdA=="}, fasthttp.StatusUnauthorized, ""},
+	}
+
+	for _, tc := range testCases {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.SetRequestURI(tc.uri)
+		for k, v := range tc.headers {
+			ctx.Request.Header.Set(k, v)
+		}
+		proxy.InternalHandler(ctx)
+
+		if ctx.Response.StatusCode() != tc.status {
+			t.Errorf("%s %v: expected status %d, got %d %q", tc.uri, tc.headers, tc.status, ctx.Response.StatusCode(), ctx.Response.Body())
+			continue
+		}
+		if tc.status == fasthttp.StatusOK && string(ctx.Response.Body()) != tc.body {
+			t.Errorf("%s %v: expected upstream received %q, got %q", tc.uri, tc.headers, tc.body, ctx.Response.Body())
+		}
+	}
+
+	// Preflight of requests with header keys carries no key, and is answered without forwarding
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(fasthttp.MethodOptions)
+	ctx.Request.SetRequestURI("/v1/default_service/anything")
+	ctx.Request.Header.Set("Origin", "https://dapp.example.com")
+	ctx.Request.Header.Set("Access-Control-Request-Method", fasthttp.MethodGet)
+	ctx.Request.Header.Set("Access-Control-Request-Headers", models.ApiKeyHeaderName)
+	proxy.InternalHandler(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusNoContent || len(ctx.Response.Body()) != 0 {
+		t.Errorf("expected preflight answered with 204, got %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	// Other OPTIONS requests still require a key
+	ctx = &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(fasthttp.MethodOptions)
+	ctx.Request.SetRequestURI("/v1/default_service/anything")
+	proxy.InternalHandler(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusUnauthorized {
+		t.Errorf("expected OPTIONS without key rejected, got %d", ctx.Response.StatusCode())
+	}
+}
+
+func TestProxyHandlerApiKeyScopes(t *testing.T) {
+	storageManager := models.NewMemoryStorageManager()
+	service := &models.ApronService{Id: "test_service", BaseUrl: startEchoUpstream(t) + "/", Schema: "http"}
+	binaryService, _ := proto.Marshal(service)
+	storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+	binaryKey, _ := proto.Marshal(&models.ApronApiKey{
+		Key:       "test_key",
+		ServiceId: service.Id,
+		Scopes:    []*models.ApiKeyScope{{Methods: []string{"GET"}, Paths: []string{"public/**"}}},
+	})
+	storageManager.SaveBinaryKeyData(internal.ServiceApiKeyStorageBucketName(service.Id), models.HashApiKey("test_key"), binaryKey)
+
+	proxy := newTestProxyHandler(t, storageManager)
+
+	testCases := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{"GET", "/public/docs/1", fasthttp.StatusOK},
+		{"POST", "/public/docs/1", fasthttp.StatusForbidden},
+		{"GET", "/private/docs/1", fasthttp.StatusForbidden},
+	}
+	for _, tc := range testCases {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.Header.SetMethod(tc.method)
+		ctx.Request.SetRequestURI(fmt.Sprintf("/v1/%s/test_key%s", service.Id, tc.path))
+		proxy.InternalHandler(ctx)
+
+		if ctx.Response.StatusCode() != tc.status {
+			t.Errorf("%s %s: expected status %d, got %d %q", tc.method, tc.path, tc.status, ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+		if tc.status == fasthttp.StatusForbidden {
+			gatewayErr := internal.GatewayError{}
+			json.Unmarshal(ctx.Response.Body(), &gatewayErr)
+			if gatewayErr.Code != internal.ErrCodeForbidden {
+				t.Errorf("%s %s: expected forbidden error, got %q", tc.method, tc.path, ctx.Response.Body())
+			}
+		}
+	}
+}
+
+func TestProxyHandlerClientRestrictions(t *testing.T) {
+	storageManager := models.NewMemoryStorageManager()
+	service := &models.ApronService{Id: "test_service", BaseUrl: startEchoUpstream(t) + "/", Schema: "http"}
+	binaryService, _ := proto.Marshal(service)
+	storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+	binaryKey, _ := proto.Marshal(&models.ApronApiKey{
+		Key:            "test_key",
+		ServiceId:      service.Id,
+		AllowedIps:     []string{"10.0.0.0/8"},
+		AllowedOrigins: []string{"https://dapp.io"},
+	})
+	storageManager.SaveBinaryKeyData(internal.ServiceApiKeyStorageBucketName(service.Id), models.HashApiKey("test_key"), binaryKey)
+
+	proxy := newTestProxyHandler(t, storageManager)
+
+	testCases := []struct {
+		ip     string
+		origin string
+		status int
+	}{
+		{"10.0.0.1", "https://dapp.io", fasthttp.StatusOK},
+		{"172.16.0.1", "https://dapp.io", fasthttp.StatusForbidden},
+		{"10.0.0.1", "https://evil.com", fasthttp.StatusForbidden},
+	}
+	for _, tc := range testCases {
+		req := fasthttp.AcquireRequest()
+		req.SetRequestURI(fmt.Sprintf("/v1/%s/test_key/anything", service.Id))
+		req.Header.Set("Origin", tc.origin)
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Init(req, &net.TCPAddr{IP: net.ParseIP(tc.ip), Port: 12345}, nil)
+		proxy.InternalHandler(ctx)
+
+		if ctx.Response.StatusCode() != tc.status {
+			t.Errorf("%s %s: expected status %d, got %d %q", tc.ip, tc.origin, tc.status, ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+		allowOrigin, restricted := CorsAllowOrigin(ctx)
+		if !restricted || (tc.origin == "https://dapp.io") != (allowOrigin == tc.origin) {
+			t.Errorf("%s %s: unexpected allowed origin %q", tc.ip, tc.origin, allowOrigin)
+		}
+		fasthttp.ReleaseRequest(req)
+	}
+}
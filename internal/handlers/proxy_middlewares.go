@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/handlers/ratelimiter"
+	"apron.network/gateway/internal/models"
+)
+
+// parseRequestMiddleware extracts request detail from fasthttp ctx and saves it to proxy context
+func (h *ProxyHandler) parseRequestMiddleware(next ProxyRequestHandler) ProxyRequestHandler {
+	return func(c *ProxyContext) error {
+		requestDetail, err := models.ExtractCtxRequestDetail(c.Ctx)
+		if err != nil {
+			return internal.BadRequestError("invalid request: %v", err)
+		}
+		if requestDetail.ServiceNameStr == "" {
+			return internal.NotFoundError("invalid proxy request path %s", requestDetail.Path)
+		}
+		c.RequestDetail = requestDetail
+		return next(c)
+	}
+}
+
+// authenticateMiddleware loads requested service and checks the api key or token of request,
+// the service is loaded first since auth mode and key locations are declared in service.
+func (h *ProxyHandler) authenticateMiddleware(next ProxyRequestHandler) ProxyRequestHandler {
+	return func(c *ProxyContext) error {
+		service, err := h.loadService(c.RequestDetail.ServiceNameStr)
+		if err != nil {
+			return err
+		}
+		c.Service = service
+
+		if service.AuthMode == models.ServiceAuthModeJwt {
+			err = h.validateJwtRequest(c)
+		} else if isSignedRequest(c) {
+			err = h.validateSignedRequest(c)
+		} else {
+			extractApiKey(c)
+			err = h.validateRequest(c)
+		}
+		if err != nil {
+			return err
+		}
+		return next(c)
+	}
+}
+
+// concurrencyMiddleware limits in flight requests of the key and the service,
+// and the slots are held until the request finished or the websocket session closed.
+func (h *ProxyHandler) concurrencyMiddleware(next ProxyRequestHandler) ProxyRequestHandler {
+	return func(c *ProxyContext) error {
+		// Key slot is acquired first, so requests waiting for key slot do not occupy service slots
+		if policy := models.EffectiveKeyConcurrencyPolicy(c.Service, c.ApiKey); policy != nil {
+			key := fmt.Sprintf("key:%s:%s", c.Service.Id, models.ApiKeyId(c.ApiKey))
+			if err := h.acquireConcurrencySlot(c, key, policy); err != nil {
+				return err
+			}
+		}
+		if policy := c.Service.ConcurrencyPolicy; policy != nil {
+			if err := h.acquireConcurrencySlot(c, "service:"+c.Service.Id, policy); err != nil {
+				return err
+			}
+		}
+		return next(c)
+	}
+}
+
+func (h *ProxyHandler) acquireConcurrencySlot(c *ProxyContext, key string, policy *models.ConcurrencyPolicy) error {
+	release, err := h.ConcurrencyLimiter.Acquire(key, toConcurrencyPolicy(policy))
+	if err != nil {
+		c.Ctx.Response.Header.Set("Retry-After", "1")
+		return internal.ConcurrencyLimitedError("too many in flight requests, max %d allowed: %v", policy.MaxInFlight, err)
+	}
+	c.OnSessionClose(release)
+	return nil
+}
+
+// rateLimitMiddleware responds 429 if rate limit reached,
+// and the rate limit status is set to response headers of every request.
+func (h *ProxyHandler) rateLimitMiddleware(next ProxyRequestHandler) ProxyRequestHandler {
+	return func(c *ProxyContext) error {
+		// Requests are limited by service and key, with policies declared in key or service
+		key := fmt.Sprintf("%s:%s", c.Service.Id, models.ApiKeyId(c.ApiKey))
+		policies := models.EffectiveRateLimitPolicies(c.Service, c.ApiKey)
+		res, err := h.RateLimiter.GetWithPolicies(key, toLimiterPolicies(policies)...)
+		if err != nil {
+			return internal.InternalError(err)
+		}
+		c.RateLimit = &res
+		setRateLimitHeaders(c.Ctx, res)
+
+		if res.Remaining < 0 {
+			c.Ctx.Response.Header.Set("Retry-After", strconv.FormatInt(secondsUntil(res.Reset), 10))
+
+			h.Logger.Log(fmt.Sprintf("%s|429 error|%s: from %s, service: %s, api_key: %s\n",
+				time.Now().UTC().Format("2006-01-02 15:04:05"),
+				loggableUri(c),
+				c.Ctx.RemoteIP().String(),
+				c.RequestDetail.ServiceNameStr,
+				models.ApiKeyId(c.ApiKey),
+			))
+
+			return internal.TooManyRequestsError("rate limit exceeded, %d requests allowed in %s", res.Total, res.Duration)
+		}
+
+		err = next(c)
+
+		// Set again after forwarded since headers may be overwritten by upstream response
+		setRateLimitHeaders(c.Ctx, res)
+		return err
+	}
+}
+
+// setRateLimitHeaders sets both X-RateLimit-* headers and IETF draft RateLimit-* headers
+func setRateLimitHeaders(ctx *fasthttp.RequestCtx, res ratelimiter.Result) {
+	remaining := res.Remaining
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	limitStr := strconv.Itoa(res.Total)
+	remainingStr := strconv.Itoa(remaining)
+	ctx.Response.Header.Set("X-RateLimit-Limit", limitStr)
+	ctx.Response.Header.Set("X-RateLimit-Remaining", remainingStr)
+	ctx.Response.Header.Set("X-RateLimit-Reset", strconv.FormatInt(res.Reset.Unix(), 10))
+
+	ctx.Response.Header.Set("RateLimit-Limit", limitStr)
+	ctx.Response.Header.Set("RateLimit-Remaining", remainingStr)
+	ctx.Response.Header.Set("RateLimit-Reset", strconv.FormatInt(secondsUntil(res.Reset), 10))
+	ctx.Response.Header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", res.Total, int64(math.Ceil(res.Duration.Seconds()))))
+}
+
+// secondsUntil returns seconds from now to t rounded up, and at least 1 second
+func secondsUntil(t time.Time) int64 {
+	seconds := int64(math.Ceil(time.Until(t).Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// quotaMiddleware counts the request in quota of current period, and rejects the request after quota exhausted.
+// Requests are not limited if no quota policy declared in service or key.
+func (h *ProxyHandler) quotaMiddleware(next ProxyRequestHandler) ProxyRequestHandler {
+	return func(c *ProxyContext) error {
+		policy := models.EffectiveQuotaPolicy(c.Service, c.ApiKey)
+		if policy == nil {
+			return next(c)
+		}
+
+		status, err := h.QuotaManager.Consume(c.Service.Id, c.ApiKey, policy)
+		if err != nil {
+			return err
+		}
+		c.Quota = &status
+		setQuotaHeaders(c.Ctx, status)
+
+		if status.Exceeded {
+			exceededStatus := int(policy.ExceededStatus)
+			if exceededStatus == 0 {
+				exceededStatus = fasthttp.StatusTooManyRequests
+			}
+			if exceededStatus == fasthttp.StatusTooManyRequests {
+				c.Ctx.Response.Header.Set("Retry-After", strconv.FormatInt(secondsUntil(status.Reset), 10))
+			}
+			return internal.QuotaExceededError(exceededStatus, "%s quota exhausted, %d requests allowed until %s",
+				status.Period, status.Limit, status.Reset.Format(time.RFC3339))
+		}
+
+		// Log only once when the usage reaches warning threshold
+		if status.Warning > 0 && (status.Used-1)*100 < status.Limit*int64(status.Warning) {
+			h.Logger.Log(fmt.Sprintf("%s|quota warning|service: %s, api_key: %s, %d%% of %s quota used\n",
+				time.Now().UTC().Format("2006-01-02 15:04:05"),
+				c.RequestDetail.ServiceNameStr,
+				models.ApiKeyId(c.ApiKey),
+				status.Warning,
+				status.Period,
+			))
+		}
+
+		err = next(c)
+
+		// Set again after forwarded since headers may be overwritten by upstream response
+		setQuotaHeaders(c.Ctx, status)
+		return err
+	}
+}
+
+// setQuotaHeaders sets X-Quota-* headers, and X-Quota-Warning if the usage reached warning threshold
+func setQuotaHeaders(ctx *fasthttp.RequestCtx, status models.QuotaStatus) {
+	ctx.Response.Header.Set("X-Quota-Limit", strconv.FormatInt(status.Limit, 10))
+	ctx.Response.Header.Set("X-Quota-Remaining", strconv.FormatInt(status.Remaining, 10))
+	ctx.Response.Header.Set("X-Quota-Reset", strconv.FormatInt(status.Reset.Unix(), 10))
+	if status.Warning > 0 {
+		ctx.Response.Header.Set("X-Quota-Warning", fmt.Sprintf("%d%% of %s quota used", status.Warning, status.Period))
+	}
+}
+
+// loggableUri returns request uri with the secret key masked
+func loggableUri(c *ProxyContext) string {
+	uri := c.RequestDetail.URI.String()
+	if c.RequestDetail.ApiKeyStr == "" {
+		return uri
+	}
+	return strings.Replace(uri, c.RequestDetail.ApiKeyStr, models.ApiKeyHint(c.RequestDetail.ApiKeyStr), 1)
+}
+
+// meterMiddleware writes access log and increases usage of the service and key.
+// Key id is used in logs and usage report, so the secret key is never leaked.
+func (h *ProxyHandler) meterMiddleware(next ProxyRequestHandler) ProxyRequestHandler {
+	return func(c *ProxyContext) error {
+		requestDetail := c.RequestDetail
+		keyId := models.ApiKeyId(c.ApiKey)
+		h.Logger.Log(fmt.Sprintf("%s|%s: from %s, service: %s, api_key: %s\n",
+			time.Now().UTC().Format("2006-01-02 15:04:05"),
+			loggableUri(c),
+			c.Ctx.RemoteIP().String(),
+			requestDetail.ServiceNameStr,
+			keyId,
+		))
+		h.AggrAccessRecordManager.IncUsage(requestDetail.ServiceNameStr, keyId)
+
+		accessLog := models.AccessLog{
+			Ts:          time.Now().UnixNano() / 1e6,
+			ServiceName: requestDetail.ServiceNameStr,
+			UserKey:     keyId,
+			RequestIp:   c.Ctx.RemoteIP().String(),
+			RequestPath: string(requestDetail.ProxyRequestPath),
+		}
+		accessLogBytes, err := json.Marshal(&accessLog)
+		if err != nil {
+			return internal.InternalError(err)
+		}
+		h.AccessLogChannel <- string(accessLogBytes)
+
+		return next(c)
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/models"
+)
+
+// loadKeyQuotaPolicy loads the key in request path, and the quota policy applied to the key
+func (h *ManagerHandler) loadKeyQuotaPolicy(ctx *fasthttp.RequestCtx) (*models.ApronApiKey, *models.QuotaPolicy, error) {
+	serviceId := ctx.UserValue("service_id").(string)
+	key := ctx.UserValue("key_id").(string)
+
+	binaryService, err := h.storageManager.GetRecord(internal.ServiceBucketName, serviceId)
+	if err != nil {
+		return nil, nil, err
+	}
+	service := &models.ApronService{}
+	if err = proto.Unmarshal([]byte(binaryService), service); err != nil {
+		return nil, nil, internal.InternalError(err)
+	}
+
+	_, apiKey, err := h.loadApiKey(serviceId, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	policy := models.EffectiveQuotaPolicy(service, apiKey)
+	if policy == nil {
+		return nil, nil, internal.NotFoundError("no quota policy declared for key %s in service %s", key, serviceId)
+	}
+	return apiKey, policy, nil
+}
+
+// apiKeyQuotaHandler responds usage and remaining quota of the key in current period
+func (h *ManagerHandler) apiKeyQuotaHandler(ctx *fasthttp.RequestCtx) {
+	apiKey, policy, err := h.loadKeyQuotaPolicy(ctx)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+
+	status, err := h.quotaManager.GetStatus(ctx.UserValue("service_id").(string), apiKey, policy)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+
+	respBody, err := json.Marshal(status)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	ctx.SetContentType("application/json")
+	ctx.Write(respBody)
+}
+
+// resetApiKeyQuotaHandler clears usage of the key in current period.
+// For account scope quota, the quota shared by all keys of the account is reset.
+func (h *ManagerHandler) resetApiKeyQuotaHandler(ctx *fasthttp.RequestCtx) {
+	apiKey, policy, err := h.loadKeyQuotaPolicy(ctx)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+
+	// Usage before and after reset is audited, so the erased usage is kept
+	serviceId := ctx.UserValue("service_id").(string)
+	before, err := h.quotaManager.GetStatus(serviceId, apiKey, policy)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	if err := h.quotaManager.Reset(serviceId, apiKey, policy); err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	after, err := h.quotaManager.GetStatus(serviceId, apiKey, policy)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	beforeSnapshot, _ := json.Marshal(before)
+	afterSnapshot, _ := json.Marshal(after)
+	h.recordAudit(ctx, "key.quota_reset", auditKeyTarget(serviceId, apiKey), string(beforeSnapshot), string(afterSnapshot))
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
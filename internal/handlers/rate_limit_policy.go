@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"apron.network/gateway/internal/handlers/ratelimiter"
+	"apron.network/gateway/internal/models"
+)
+
+// toLimiterPolicies converts policies declared in service or key to policies used by ratelimiter.Limiter
+func toLimiterPolicies(policies []*models.RateLimitPolicy) []ratelimiter.Policy {
+	result := make([]ratelimiter.Policy, 0, len(policies))
+	for _, p := range policies {
+		result = append(result, ratelimiter.Policy{
+			Algorithm: ratelimiter.Algorithm(p.Algorithm),
+			Max:       int(p.Max),
+			Duration:  time.Duration(p.DurationMs) * time.Millisecond,
+			Burst:     int(p.Burst),
+		})
+	}
+	return result
+}
+
+// validateRateLimitPolicies checks algorithm and values of policies before saved
+func validateRateLimitPolicies(policies []*models.RateLimitPolicy) error {
+	for idx, p := range policies {
+		if p == nil {
+			return fmt.Errorf("rate limit policy %d is empty", idx)
+		}
+		if err := toLimiterPolicies([]*models.RateLimitPolicy{p})[0].Validate(); err != nil {
+			return fmt.Errorf("rate limit policy %d should have supported algorithm, positive max and duration_ms: %v", idx, err)
+		}
+	}
+	return nil
+}
@@ -2,5 +2,6 @@ package ratelimiter
 
 type abstractLimiter interface {
 	getLimit(key string, policy ...int) ([]interface{}, error)
+	getAlgorithmLimit(key string, policy Policy, peek bool) ([]interface{}, error)
 	removeLimit(key string) error
 }
@@ -0,0 +1,191 @@
+package ratelimiter
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Algorithm used for counting requests of a policy
+type Algorithm string
+
+const (
+	// FixedWindow counts requests in fixed duration window, multiple policies are escalated after limit exceeded
+	FixedWindow Algorithm = "fixed_window"
+	// TokenBucket refills Max tokens every Duration, and allows bursting up to Burst tokens
+	TokenBucket Algorithm = "token_bucket"
+	// SlidingWindowLog records timestamp of every request in the last Duration
+	SlidingWindowLog Algorithm = "sliding_window_log"
+	// SlidingWindowCounter estimates requests in the last Duration with weighted counter of previous window
+	SlidingWindowCounter Algorithm = "sliding_window_counter"
+	// GCRA (generic cell rate algorithm) spreads Max requests evenly in Duration, and allows bursting up to Burst requests
+	GCRA Algorithm = "gcra"
+)
+
+// Policy declares the limit and the algorithm used to count requests
+type Policy struct {
+	Algorithm Algorithm     // Default is FixedWindow
+	Max       int           // Max requests allowed in Duration
+	Duration  time.Duration // Count duration, should be at least 1 millisecond
+	Burst     int           // Max burst requests for TokenBucket and GCRA, default equals Max
+}
+
+// Validate checks whether the algorithm is supported and values are positive
+func (p Policy) Validate() error {
+	switch p.Algorithm {
+	case "", FixedWindow, TokenBucket, SlidingWindowLog, SlidingWindowCounter, GCRA:
+	default:
+		return fmt.Errorf("ratelimiter: unsupported algorithm %s", p.Algorithm)
+	}
+	if p.Max <= 0 || p.Duration < time.Millisecond || p.Burst < 0 {
+		return errors.New("ratelimiter: must be positive integer")
+	}
+	return nil
+}
+
+func (p Policy) algorithm() Algorithm {
+	if p.Algorithm == "" {
+		return FixedWindow
+	}
+	return p.Algorithm
+}
+
+func (p Policy) burst() int {
+	if p.Burst <= 0 {
+		return p.Max
+	}
+	return p.Burst
+}
+
+// resultTotal returns Result.Total for the policy, which is the bucket size for TokenBucket and GCRA
+func (p Policy) resultTotal() int {
+	switch p.algorithm() {
+	case TokenBucket, GCRA:
+		return p.burst()
+	default:
+		return p.Max
+	}
+}
+
+// algorithmCacheItem saves state of the algorithms except fixed window for memory limiter.
+// All values are integers, so the calculation is exactly the same as the lua scripts of redis limiter.
+type algorithmCacheItem struct {
+	units  int64   // Token bucket, tokens scaled by duration in ms
+	last   int64   // Token bucket, last refill time in ms
+	tat    int64   // GCRA, theoretical arrival time in us
+	log    []int64 // Sliding window log, timestamps of allowed requests in ms
+	start  int64   // Sliding window counter, start time of current window in ms
+	curr   int64   // Sliding window counter, count in current window
+	prev   int64   // Sliding window counter, count in previous window
+	expire time.Time
+}
+
+// take counts a request at now (in ms), and returns remaining count and reset time in ms.
+// The remaining count is -1 if the request is not allowed.
+func (s *algorithmCacheItem) take(p Policy, now int64) (remaining int64, reset int64) {
+	max := int64(p.Max)
+	duration := int64(p.Duration / time.Millisecond)
+
+	switch p.algorithm() {
+	case TokenBucket:
+		// Tokens are scaled by duration, so max units are refilled every ms
+		capacity := int64(p.burst()) * duration
+		if s.last == 0 {
+			s.units, s.last = capacity, now
+		}
+		if now > s.last {
+			s.units += (now - s.last) * max
+			if s.units > capacity {
+				s.units = capacity
+			}
+			s.last = now
+		}
+		if s.units >= duration {
+			s.units -= duration
+			return s.units / duration, now + ceilDiv(capacity-s.units, max)
+		}
+		return -1, now + ceilDiv(duration-s.units, max)
+
+	case GCRA:
+		nowUs := now * 1000
+		interval := duration * 1000 / max
+		if interval < 1 {
+			interval = 1
+		}
+		tolerance := interval * int64(p.burst())
+		tat := s.tat
+		if tat < nowUs {
+			tat = nowUs
+		}
+		newTat := tat + interval
+		allowAt := newTat - tolerance
+		if nowUs < allowAt {
+			return -1, ceilDiv(allowAt, 1000)
+		}
+		s.tat = newTat
+		return (tolerance - (newTat - nowUs)) / interval, ceilDiv(newTat, 1000)
+
+	case SlidingWindowLog:
+		idx := 0
+		for idx < len(s.log) && s.log[idx] <= now-duration {
+			idx++
+		}
+		s.log = s.log[idx:]
+
+		remaining = -1
+		if int64(len(s.log)) < max {
+			s.log = append(s.log, now)
+			remaining = max - int64(len(s.log))
+		}
+		reset = now + duration
+		if len(s.log) > 0 {
+			reset = s.log[0] + duration
+		}
+		return remaining, reset
+
+	case SlidingWindowCounter:
+		windowStart := now - now%duration
+		if s.start == 0 {
+			s.start = windowStart
+		}
+		if windowStart > s.start {
+			if windowStart-s.start == duration {
+				s.prev = s.curr
+			} else {
+				s.prev = 0
+			}
+			s.curr = 0
+			s.start = windowStart
+		}
+
+		weighted := s.prev*(duration-(now-s.start)) + s.curr*duration
+		remaining = -1
+		if weighted+duration <= max*duration {
+			s.curr++
+			weighted += duration
+			remaining = (max*duration - weighted) / duration
+		}
+		return remaining, s.start + duration
+	}
+
+	return -1, now
+}
+
+// ttl returns duration the state should be kept after last request
+func (p Policy) ttl() time.Duration {
+	switch p.algorithm() {
+	case TokenBucket:
+		return time.Duration(p.burst()) * p.Duration / time.Duration(p.Max)
+	case GCRA:
+		return time.Duration(p.burst())*p.Duration/time.Duration(p.Max) + p.Duration
+	default:
+		return 2 * p.Duration
+	}
+}
+
+func ceilDiv(a, b int64) int64 {
+	if a <= 0 {
+		return 0
+	}
+	return (a + b - 1) / b
+}
@@ -2,6 +2,7 @@ package ratelimiter
 
 import (
 	"errors"
+	"strings"
 	"sync"
 	"time"
 )
@@ -19,21 +20,25 @@ type limiterCacheItem struct {
 }
 
 type memoryLimiter struct {
-	max      int
-	duration time.Duration
-	status   map[string]*statusCacheItem
-	store    map[string]*limiterCacheItem
-	ticker   *time.Ticker
-	lock     sync.Mutex
+	max        int
+	duration   time.Duration
+	status     map[string]*statusCacheItem
+	store      map[string]*limiterCacheItem
+	algorithms map[string]*algorithmCacheItem
+	ticker     *time.Ticker
+	lock       sync.Mutex
+	now        func() time.Time
 }
 
 func newMemoryLimiter(opts *Options) *Limiter {
 	m := &memoryLimiter{
-		max:      opts.Max,
-		duration: opts.Duration,
-		store:    make(map[string]*limiterCacheItem),
-		status:   make(map[string]*statusCacheItem),
-		ticker:   time.NewTicker(time.Second),
+		max:        opts.Max,
+		duration:   opts.Duration,
+		store:      make(map[string]*limiterCacheItem),
+		status:     make(map[string]*statusCacheItem),
+		algorithms: make(map[string]*algorithmCacheItem),
+		ticker:     time.NewTicker(time.Second),
+		now:        opts.Clock,
 	}
 	go m.cleanCache()
 	return &Limiter{m}
@@ -61,6 +66,33 @@ func (m *memoryLimiter) getLimit(key string, policy ...int) ([]interface{}, erro
 	return []interface{}{res.remaining, res.total, res.duration, res.expire}, nil
 }
 
+// abstractLimiter interface
+func (m *memoryLimiter) getAlgorithmLimit(key string, policy Policy, peek bool) ([]interface{}, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := m.now()
+	item, ok := m.algorithms[key]
+	if !ok {
+		item = &algorithmCacheItem{}
+		if !peek {
+			m.algorithms[key] = item
+		}
+	}
+	if peek {
+		// Take from a copy, the log is cut to its length so appending never writes to the saved one
+		state := *item
+		state.log = state.log[:len(state.log):len(state.log)]
+		item = &state
+	}
+	remaining, reset := item.take(policy, now.UnixNano()/1e6)
+	if !peek {
+		item.expire = now.Add(policy.ttl())
+	}
+
+	return []interface{}{int(remaining), policy.resultTotal(), policy.Duration, time.Unix(0, reset*1e6)}, nil
+}
+
 // abstractLimiter interface
 func (m *memoryLimiter) removeLimit(key string) error {
 	statusKey := "{" + key + "}:S"
@@ -68,14 +100,24 @@ func (m *memoryLimiter) removeLimit(key string) error {
 	defer m.lock.Unlock()
 	delete(m.store, key)
 	delete(m.status, statusKey)
+	for k := range m.algorithms {
+		if strings.HasPrefix(k, key+":") {
+			delete(m.algorithms, k)
+		}
+	}
 	return nil
 }
 
 func (m *memoryLimiter) clean() {
 	m.lock.Lock()
 	defer m.lock.Unlock()
-	start := time.Now()
-	expireTime := start.Add(time.Millisecond * 100)
+	start := m.now()
+	for key, value := range m.algorithms {
+		if value.expire.Before(start) {
+			delete(m.algorithms, key)
+		}
+	}
+	expireTime := time.Now().Add(time.Millisecond * 100)
 	frequency := 24
 	var expired int
 	for {
@@ -108,27 +150,28 @@ func (m *memoryLimiter) getItem(key string, args ...int) (res *limiterCacheItem)
 
 	m.lock.Lock()
 	defer m.lock.Unlock()
+	now := m.now()
 	var ok bool
 	if res, ok = m.store[key]; !ok {
 		res = &limiterCacheItem{
 			total:     args[0],
 			remaining: args[0] - 1,
 			duration:  time.Duration(args[1]) * time.Millisecond,
-			expire:    time.Now().Add(time.Duration(args[1]) * time.Millisecond),
+			expire:    now.Add(time.Duration(args[1]) * time.Millisecond),
 		}
 		m.store[key] = res
 		return
 	}
-	if res.expire.After(time.Now()) {
+	if res.expire.After(now) {
 		if policyCount > 1 && res.remaining-1 == -1 {
 			statusItem, ok := m.status[statusKey]
 			if ok {
-				statusItem.expire = time.Now().Add(res.duration * 2)
+				statusItem.expire = now.Add(res.duration * 2)
 				statusItem.index++
 			} else {
 				statusItem := &statusCacheItem{
 					index:  2,
-					expire: time.Now().Add(time.Duration(args[1]) * time.Millisecond * 2),
+					expire: now.Add(time.Duration(args[1]) * time.Millisecond * 2),
 				}
 				m.status[statusKey] = statusItem
 			}
@@ -142,7 +185,7 @@ func (m *memoryLimiter) getItem(key string, args ...int) (res *limiterCacheItem)
 		index := 1
 		if policyCount > 1 {
 			if statusItem, ok := m.status[statusKey]; ok {
-				if statusItem.expire.Before(time.Now()) {
+				if statusItem.expire.Before(now) {
 					index = 1
 				} else if statusItem.index > policyCount {
 					index = policyCount
@@ -157,7 +200,7 @@ func (m *memoryLimiter) getItem(key string, args ...int) (res *limiterCacheItem)
 		res.total = total
 		res.remaining = total - 1
 		res.duration = time.Duration(duration) * time.Millisecond
-		res.expire = time.Now().Add(time.Duration(duration) * time.Millisecond)
+		res.expire = now.Add(time.Duration(duration) * time.Millisecond)
 	}
 	return
 }
@@ -2,7 +2,10 @@ package ratelimiter
 
 import (
 	"errors"
+	"fmt"
 	"time"
+
+	"github.com/go-redis/redis/v8"
 )
 
 // Limiter struct.
@@ -12,8 +15,11 @@ type Limiter struct {
 
 // Options for Limiter
 type Options struct {
-	Max      int           // The max count in duration for no policy, default is 100.
-	Duration time.Duration // Count duration for no policy, default is 1 Minute.
+	Max      int              // The max count in duration for no policy, default is 100.
+	Duration time.Duration    // Count duration for no policy, default is 1 Minute.
+	Client   *redis.Client    // Redis client for sharing limits across gateway nodes, memory limiter is used if omit.
+	Prefix   string           // Key prefix for redis limiter, default is "limit".
+	Clock    func() time.Time // Current time used by limiter, default is time.Now.
 }
 
 // Result of limiter.Get
@@ -33,6 +39,15 @@ func New(opts Options) *Limiter {
 	if opts.Duration <= 0 {
 		opts.Duration = time.Minute
 	}
+	if opts.Clock == nil {
+		opts.Clock = time.Now
+	}
+	if opts.Client != nil {
+		if opts.Prefix == "" {
+			opts.Prefix = "limit"
+		}
+		return newRedisLimiter(&opts)
+	}
 	return newMemoryLimiter(&opts)
 }
 
@@ -86,3 +101,89 @@ func (l *Limiter) Get(id string, policy ...int) (Result, error) {
 func (l *Limiter) Remove(id string) error {
 	return l.removeLimit(id)
 }
+
+/*
+GetWithPolicies get a limiter result with policies using different algorithms.
+
+Fixed window policies are escalated in declared order like Get, and every other policy is counted independently.
+All policies are checked before any of them is counted, so a blocked request is not counted by the other policies.
+The request is blocked if any policy is exceeded, and the result is the most restrictive one:
+
+	res, err := limiter.GetWithPolicies(key,
+	    Policy{Algorithm: TokenBucket, Max: 10, Duration: time.Second, Burst: 20},
+	    Policy{Algorithm: SlidingWindowLog, Max: 1000, Duration: time.Hour},
+	)
+*/
+func (l *Limiter) GetWithPolicies(id string, policies ...Policy) (Result, error) {
+	var fixedWindow []int
+	var algorithms []int
+	for idx, p := range policies {
+		if err := p.Validate(); err != nil {
+			return Result{}, err
+		}
+		if p.algorithm() == FixedWindow {
+			fixedWindow = append(fixedWindow, p.Max, int(p.Duration/time.Millisecond))
+			continue
+		}
+		algorithms = append(algorithms, idx)
+	}
+
+	// Check the other algorithms without counting, the first blocking one is returned
+	for _, idx := range algorithms {
+		res, err := l.algorithmResult(id, idx, policies[idx], true)
+		if err != nil {
+			return Result{}, err
+		}
+		if res.Remaining < 0 {
+			return res, nil
+		}
+	}
+
+	var fixedWindowResult *Result
+	if len(fixedWindow) > 0 || len(algorithms) == 0 {
+		res, err := l.Get(id, fixedWindow...)
+		if err != nil {
+			return Result{}, err
+		}
+		if res.Remaining < 0 {
+			return res, nil
+		}
+		fixedWindowResult = &res
+	}
+	var results []Result
+	for _, idx := range algorithms {
+		res, err := l.algorithmResult(id, idx, policies[idx], false)
+		if err != nil {
+			return Result{}, err
+		}
+		results = append(results, res)
+	}
+	if fixedWindowResult != nil {
+		results = append(results, *fixedWindowResult)
+	}
+
+	result := results[0]
+	for _, res := range results[1:] {
+		switch {
+		case res.Remaining < 0 && (result.Remaining >= 0 || res.Reset.After(result.Reset)):
+			result = res
+		case result.Remaining >= 0 && res.Remaining < result.Remaining:
+			result = res
+		}
+	}
+	return result, nil
+}
+
+// algorithmResult counts a request for policy at idx, or only checks it if peek is true
+func (l *Limiter) algorithmResult(id string, idx int, p Policy, peek bool) (Result, error) {
+	res, err := l.getAlgorithmLimit(fmt.Sprintf("%s:%s:%d", id, p.algorithm(), idx), p, peek)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Remaining: res[0].(int),
+		Total:     res[1].(int),
+		Duration:  res[2].(time.Duration),
+		Reset:     res[3].(time.Time),
+	}, nil
+}
@@ -0,0 +1,193 @@
+package ratelimiter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// testLimiters returns memory limiter and redis limiter created with the same options
+func testLimiters(t *testing.T, opts Options) map[string]*Limiter {
+	redisServer, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start redis: %v", err)
+	}
+	t.Cleanup(redisServer.Close)
+
+	redisOpts := opts
+	redisOpts.Client = redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+
+	return map[string]*Limiter{
+		"memory": New(opts),
+		"redis":  New(redisOpts),
+	}
+}
+
+func assertResult(t *testing.T, name string, res Result, err error, remaining, total int, duration time.Duration) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", name, err)
+	}
+	if res.Remaining != remaining || res.Total != total || res.Duration != duration {
+		t.Errorf("%s: expected %d/%d/%s, got %d/%d/%s", name, remaining, total, duration, res.Remaining, res.Total, res.Duration)
+	}
+}
+
+func TestLimiterDefaultPolicy(t *testing.T) {
+	for name, limiter := range testLimiters(t, Options{Max: 3, Duration: time.Minute}) {
+		for i := 2; i >= -1; i-- {
+			res, err := limiter.Get("user-1")
+			assertResult(t, name, res, err, i, 3, time.Minute)
+		}
+		// Exceeded limit keeps remaining at -1
+		res, err := limiter.Get("user-1")
+		assertResult(t, name, res, err, -1, 3, time.Minute)
+
+		// Other ids are not affected
+		res, err = limiter.Get("user-2")
+		assertResult(t, name, res, err, 2, 3, time.Minute)
+
+		if err := limiter.Remove("user-1"); err != nil {
+			t.Fatalf("%s: failed to remove limit: %v", name, err)
+		}
+		res, err = limiter.Get("user-1")
+		assertResult(t, name, res, err, 2, 3, time.Minute)
+	}
+}
+
+func TestLimiterPolicyEscalation(t *testing.T) {
+	policy := []int{2, 100, 1, 200}
+	window := 100 * time.Millisecond
+
+	for name, limiter := range testLimiters(t, Options{}) {
+		res, err := limiter.Get("user-1", policy...)
+		assertResult(t, name, res, err, 1, 2, window)
+		res, err = limiter.Get("user-1", policy...)
+		assertResult(t, name, res, err, 0, 2, window)
+		res, err = limiter.Get("user-1", policy...)
+		assertResult(t, name, res, err, -1, 2, window)
+
+		// Limit exceeded in previous window, so the next policy is applied
+		time.Sleep(window + 20*time.Millisecond)
+		res, err = limiter.Get("user-1", policy...)
+		assertResult(t, name, res, err, 0, 1, 2*window)
+	}
+}
+
+func TestLimiterInvalidPolicy(t *testing.T) {
+	for name, limiter := range testLimiters(t, Options{}) {
+		if _, err := limiter.Get("user-1", 10); err == nil {
+			t.Errorf("%s: expected error for unpaired policy", name)
+		}
+		if _, err := limiter.Get("user-1", 10, -1); err == nil {
+			t.Errorf("%s: expected error for negative policy", name)
+		}
+	}
+}
+
+type fakeClock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestLimiterAlgorithms(t *testing.T) {
+	type step struct {
+		advance   time.Duration
+		remaining int
+	}
+	tests := []struct {
+		policy Policy
+		steps  []step
+	}{
+		{
+			policy: Policy{Algorithm: TokenBucket, Max: 1, Duration: 100 * time.Millisecond, Burst: 3},
+			steps:  []step{{0, 2}, {0, 1}, {0, 0}, {0, -1}, {50 * time.Millisecond, -1}, {50 * time.Millisecond, 0}, {300 * time.Millisecond, 2}},
+		},
+		{
+			policy: Policy{Algorithm: GCRA, Max: 10, Duration: time.Second, Burst: 2},
+			steps:  []step{{0, 1}, {0, 0}, {0, -1}, {100 * time.Millisecond, 0}, {0, -1}, {time.Second, 1}},
+		},
+		{
+			policy: Policy{Algorithm: SlidingWindowLog, Max: 2, Duration: time.Second},
+			steps:  []step{{0, 1}, {500 * time.Millisecond, 0}, {400 * time.Millisecond, -1}, {100 * time.Millisecond, 0}, {0, -1}, {500 * time.Millisecond, 0}},
+		},
+		{
+			policy: Policy{Algorithm: SlidingWindowCounter, Max: 4, Duration: time.Second},
+			steps:  []step{{0, 3}, {0, 2}, {0, 1}, {0, 0}, {0, -1}, {1500 * time.Millisecond, 1}, {0, 0}, {0, -1}, {2 * time.Second, 3}},
+		},
+	}
+
+	for _, test := range tests {
+		clock := &fakeClock{now: time.Unix(1600000000, 0)}
+		limiters := testLimiters(t, Options{Clock: clock.Now})
+		for idx, s := range test.steps {
+			clock.Advance(s.advance)
+			memRes, err := limiters["memory"].GetWithPolicies("user-1", test.policy)
+			assertResult(t, fmt.Sprintf("memory %s step %d", test.policy.Algorithm, idx), memRes, err, s.remaining, test.policy.resultTotal(), test.policy.Duration)
+			redisRes, err := limiters["redis"].GetWithPolicies("user-1", test.policy)
+			assertResult(t, fmt.Sprintf("redis %s step %d", test.policy.Algorithm, idx), redisRes, err, s.remaining, test.policy.resultTotal(), test.policy.Duration)
+			if !memRes.Reset.Equal(redisRes.Reset) {
+				t.Errorf("%s step %d: reset of memory %s and redis %s are different", test.policy.Algorithm, idx, memRes.Reset, redisRes.Reset)
+			}
+		}
+	}
+}
+
+func TestLimiterMultipleAlgorithms(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1600000000, 0)}
+	policies := []Policy{
+		{Max: 3, Duration: time.Minute},
+		{Algorithm: TokenBucket, Max: 1, Duration: time.Second, Burst: 2},
+	}
+
+	for name, limiter := range testLimiters(t, Options{Clock: clock.Now}) {
+		// The policy with least remaining is returned
+		res, err := limiter.GetWithPolicies("user-1", policies...)
+		assertResult(t, name, res, err, 1, 2, time.Second)
+		res, err = limiter.GetWithPolicies("user-1", policies...)
+		assertResult(t, name, res, err, 0, 2, time.Second)
+		res, err = limiter.GetWithPolicies("user-1", policies...)
+		assertResult(t, name, res, err, -1, 2, time.Second)
+
+		// The blocked request is not counted by fixed window, so one more request is allowed after the bucket refilled
+		clock.Advance(time.Second)
+		res, err = limiter.GetWithPolicies("user-1", policies...)
+		assertResult(t, name, res, err, 0, 2, time.Second)
+		res, err = limiter.GetWithPolicies("user-1", policies...)
+		assertResult(t, name, res, err, -1, 2, time.Second)
+
+		// Fixed window blocks the request after the bucket refilled, and the token is not taken
+		clock.Advance(time.Second)
+		res, err = limiter.GetWithPolicies("user-1", policies...)
+		assertResult(t, name, res, err, -1, 3, time.Minute)
+		res, err = limiter.algorithmResult("user-1", 1, policies[1], true)
+		assertResult(t, name, res, err, 0, 2, time.Second)
+
+		// State of all algorithms are removed
+		if err := limiter.Remove("user-1"); err != nil {
+			t.Fatalf("%s: failed to remove limit: %v", name, err)
+		}
+		res, err = limiter.GetWithPolicies("user-1", policies...)
+		assertResult(t, name, res, err, 1, 2, time.Second)
+
+		if _, err := limiter.GetWithPolicies("user-1", Policy{Algorithm: "unknown", Max: 1, Duration: time.Second}); err == nil {
+			t.Errorf("%s: expected error for unsupported algorithm", name)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// limitScript implements the same multi-policy escalation as memoryLimiter.getItem atomically in redis.
+// KEYS[1] is the limit hash and KEYS[2] is the policy status key,
+// ARGV[1] is current timestamp in ms, followed by policy pairs (max, duration in ms).
+var limitScript = redis.NewScript(`
+local limitKey = KEYS[1]
+local statusKey = KEYS[2]
+local now = tonumber(ARGV[1])
+local policyCount = (#ARGV - 1) / 2
+
+local limit = redis.call('hmget', limitKey, 'remaining', 'total', 'duration', 'reset')
+if limit[1] and tonumber(limit[4]) > now then
+  local remaining = tonumber(limit[1])
+  local duration = tonumber(limit[3])
+
+  -- Escalate to next policy if the limit is exceeded in current window
+  if policyCount > 1 and remaining == 0 then
+    local index = redis.call('incr', statusKey)
+    if index == 1 then
+      redis.call('incr', statusKey)
+      redis.call('pexpire', statusKey, tonumber(ARGV[3]) * 2)
+    else
+      redis.call('pexpire', statusKey, duration * 2)
+    end
+  end
+
+  if remaining >= 0 then
+    remaining = redis.call('hincrby', limitKey, 'remaining', -1)
+  else
+    remaining = -1
+  end
+  return {remaining, tonumber(limit[2]), duration, tonumber(limit[4])}
+end
+
+local index = 1
+if policyCount > 1 then
+  index = tonumber(redis.call('get', statusKey)) or 1
+  if index > policyCount then
+    index = policyCount
+    redis.call('set', statusKey, index, 'PX', math.max(redis.call('pttl', statusKey), 1))
+  end
+end
+
+local total = tonumber(ARGV[index * 2])
+local duration = tonumber(ARGV[index * 2 + 1])
+local reset = now + duration
+redis.call('hmset', limitKey, 'remaining', total - 1, 'total', total, 'duration', duration, 'reset', reset)
+redis.call('pexpire', limitKey, duration)
+return {total - 1, total, duration, reset}
+`)
+
+type redisLimiter struct {
+	max      int
+	duration time.Duration
+	prefix   string
+	client   *redis.Client
+	now      func() time.Time
+}
+
+func newRedisLimiter(opts *Options) *Limiter {
+	return &Limiter{&redisLimiter{
+		max:      opts.Max,
+		duration: opts.Duration,
+		prefix:   opts.Prefix,
+		client:   opts.Client,
+		now:      opts.Clock,
+	}}
+}
+
+func (r *redisLimiter) keys(key string) []string {
+	limitKey := "{" + r.prefix + ":" + key + "}"
+	return []string{limitKey, limitKey + ":S"}
+}
+
+// abstractLimiter interface
+func (r *redisLimiter) getLimit(key string, policy ...int) ([]interface{}, error) {
+	args := []interface{}{r.now().UnixNano() / 1e6}
+	if len(policy) == 0 {
+		args = append(args, r.max, int(r.duration/time.Millisecond))
+	} else {
+		for _, val := range policy {
+			if val <= 0 {
+				return nil, errors.New("ratelimiter: must be positive integer")
+			}
+			args = append(args, val)
+		}
+	}
+
+	res, err := limitScript.Run(context.Background(), r.client, r.keys(key), args...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := res.([]interface{})
+	if !ok || len(values) != 4 {
+		return nil, errors.New("ratelimiter: invalid result from redis")
+	}
+	remaining := values[0].(int64)
+	total := values[1].(int64)
+	duration := values[2].(int64)
+	reset := values[3].(int64)
+	return []interface{}{
+		int(remaining),
+		int(total),
+		time.Duration(duration) * time.Millisecond,
+		time.Unix(0, reset*int64(time.Millisecond)),
+	}, nil
+}
+
+// abstractLimiter interface
+func (r *redisLimiter) removeLimit(key string) error {
+	keys := r.keys(key)
+
+	// Algorithm states are saved with key prefix {<prefix>:<key>:
+	iter := r.client.Scan(context.Background(), 0, "{"+escapeScanPattern(r.prefix+":"+key)+":*", 100).Iterator()
+	for iter.Next(context.Background()) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	return r.client.Del(context.Background(), keys...).Err()
+}
+
+func escapeScanPattern(s string) string {
+	return strings.NewReplacer(`*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`).Replace(s)
+}
+
+// algorithmScripts implement the same calculation as algorithmCacheItem.take in redis.
+// KEYS[1] is the state key, ARGV[1] is current timestamp in ms, followed by max, duration in ms and burst.
+// ARGV[5] is a unique member for sliding window log, and state is not saved if ARGV[6] is "1".
+// Numbers are formatted with %.0f before saved, since default number conversion of lua loses precision.
+var algorithmScripts = map[Algorithm]*redis.Script{
+	TokenBucket: redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local max = tonumber(ARGV[2])
+local duration = tonumber(ARGV[3])
+local capacity = tonumber(ARGV[4]) * duration
+
+local state = redis.call('hmget', key, 'units', 'last')
+local units = tonumber(state[1]) or capacity
+local last = tonumber(state[2]) or now
+if now > last then
+  units = math.min(capacity, units + (now - last) * max)
+  last = now
+end
+
+local remaining = -1
+local reset = now + math.ceil((duration - units) / max)
+if units >= duration then
+  units = units - duration
+  remaining = math.floor(units / duration)
+  reset = now + math.ceil((capacity - units) / max)
+end
+
+if ARGV[6] ~= '1' then
+  redis.call('hmset', key, 'units', string.format('%.0f', units), 'last', string.format('%.0f', last))
+  redis.call('pexpire', key, math.ceil(capacity / max) + 1)
+end
+return {remaining, reset}
+`),
+	GCRA: redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1]) * 1000
+local interval = math.max(math.floor(tonumber(ARGV[3]) * 1000 / tonumber(ARGV[2])), 1)
+local tolerance = interval * tonumber(ARGV[4])
+
+local tat = tonumber(redis.call('get', key)) or now
+if tat < now then
+  tat = now
+end
+local newTat = tat + interval
+local allowAt = newTat - tolerance
+if now < allowAt then
+  return {-1, math.ceil(allowAt / 1000)}
+end
+
+if ARGV[6] ~= '1' then
+  redis.call('set', key, string.format('%.0f', newTat), 'PX', math.ceil((newTat - now) / 1000) + tonumber(ARGV[3]))
+end
+return {math.floor((tolerance - (newTat - now)) / interval), math.ceil(newTat / 1000)}
+`),
+	SlidingWindowLog: redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local max = tonumber(ARGV[2])
+local duration = tonumber(ARGV[3])
+
+redis.call('zremrangebyscore', key, '-inf', string.format('%.0f', now - duration))
+local count = redis.call('zcard', key)
+local remaining = -1
+local reset = now + duration
+local oldest = redis.call('zrange', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+  reset = tonumber(oldest[2]) + duration
+end
+if count < max then
+  remaining = max - count - 1
+  if ARGV[6] ~= '1' then
+    redis.call('zadd', key, string.format('%.0f', now), ARGV[5])
+  end
+end
+if ARGV[6] ~= '1' then
+  redis.call('pexpire', key, duration)
+end
+return {remaining, reset}
+`),
+	SlidingWindowCounter: redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local max = tonumber(ARGV[2])
+local duration = tonumber(ARGV[3])
+local windowStart = now - now % duration
+
+local state = redis.call('hmget', key, 'start', 'curr', 'prev')
+local start = tonumber(state[1]) or windowStart
+local curr = tonumber(state[2]) or 0
+local prev = tonumber(state[3]) or 0
+if windowStart > start then
+  if windowStart - start == duration then
+    prev = curr
+  else
+    prev = 0
+  end
+  curr = 0
+  start = windowStart
+end
+
+local weighted = prev * (duration - (now - start)) + curr * duration
+local remaining = -1
+if weighted + duration <= max * duration then
+  curr = curr + 1
+  weighted = weighted + duration
+  remaining = math.floor((max * duration - weighted) / duration)
+end
+
+if ARGV[6] ~= '1' then
+  redis.call('hmset', key, 'start', string.format('%.0f', start), 'curr', curr, 'prev', prev)
+  redis.call('pexpire', key, duration * 2)
+end
+return {remaining, start + duration}
+`),
+}
+
+// abstractLimiter interface
+func (r *redisLimiter) getAlgorithmLimit(key string, policy Policy, peek bool) ([]interface{}, error) {
+	script, ok := algorithmScripts[policy.algorithm()]
+	if !ok {
+		return nil, fmt.Errorf("ratelimiter: unsupported algorithm %s", policy.Algorithm)
+	}
+
+	stateKey := "{" + r.prefix + ":" + key + "}"
+	args := []interface{}{
+		r.now().UnixNano() / 1e6,
+		policy.Max,
+		int64(policy.Duration / time.Millisecond),
+		policy.burst(),
+		uuid.NewString(), // Unique member for sliding window log
+		"0",
+	}
+	if peek {
+		args[5] = "1"
+	}
+	res, err := script.Run(context.Background(), r.client, []string{stateKey}, args...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := res.([]interface{})
+	if !ok || len(values) != 2 {
+		return nil, errors.New("ratelimiter: invalid result from redis")
+	}
+	remaining := values[0].(int64)
+	reset := values[1].(int64)
+	return []interface{}{int(remaining), policy.resultTotal(), policy.Duration, time.Unix(0, reset*1e6)}, nil
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal"
+)
+
+// RecoveryMiddleware recovers panic occurred in next handler and responds it as internal error,
+// so a malformed request will not take down the whole process.
+func RecoveryMiddleware(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		defer recoverPanic(ctx)
+		next(ctx)
+	}
+}
+
+func recoverPanic(ctx *fasthttp.RequestCtx) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	err, ok := r.(error)
+	if !ok {
+		err = fmt.Errorf("%v", r)
+	}
+	log.Printf("Recovered from panic while handling %s %s: %v\n%s", ctx.Method(), ctx.Path(), err, debug.Stack())
+	internal.WriteErrorResponse(ctx, err)
+}
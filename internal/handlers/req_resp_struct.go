@@ -1,10 +1,107 @@
 package handlers
 
-import "apron.network/gateway/internal/models"
+import (
+	"encoding/json"
+
+	"apron.network/gateway/internal/models"
+)
 
 type ListApiKeysResponse struct {
 	ServiceId  string
-	Records    []models.ApronApiKey
+	Records    []*models.ApronApiKey
 	Count      uint
 	NextCursor uint64
 }
+
+type ListAuditEntriesResponse struct {
+	Records []*models.AuditEntry
+	Count   uint
+	// Sequence number to start next page, 0 if no more entries
+	NextSeq uint64
+}
+
+type NewApiKeyRequest struct {
+	AccountId         string                    `json:"account_id"`
+	RateLimitPolicies []*models.RateLimitPolicy `json:"rate_limit_policies"`
+	QuotaPolicy       *models.QuotaPolicy       `json:"quota_policy"`
+	ConcurrencyPolicy *models.ConcurrencyPolicy `json:"concurrency_policy"`
+	Scopes            []*models.ApiKeyScope     `json:"scopes"`
+	AllowedIps        []string                  `json:"allowed_ips"`
+	AllowedOrigins    []string                  `json:"allowed_origins"`
+	AllowedReferers   []string                  `json:"allowed_referers"`
+	// bearer (default) or hmac, the key of hmac mode is used as secret for signing requests
+	AuthMode string `json:"auth_mode"`
+	// Unix timestamp in seconds after which the key is rejected, 0 means never expire
+	ExpiredAt int64 `json:"expired_at"`
+	// Seconds from now after which the key is rejected, overrides ExpiredAt if set
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+// UpdateApiKeyRequest contains mutable fields of key, only fields present in request are updated
+type UpdateApiKeyRequest struct {
+	RateLimitPolicies []*models.RateLimitPolicy `json:"rate_limit_policies"`
+	// Scopes are replaced if set, and an empty array removes all scopes
+	Scopes []*models.ApiKeyScope `json:"scopes"`
+	// Client restrictions are replaced if set, and an empty array removes the restriction
+	AllowedIps      []string `json:"allowed_ips"`
+	AllowedOrigins  []string `json:"allowed_origins"`
+	AllowedReferers []string `json:"allowed_referers"`
+	// Raw values are kept to distinguish null (remove the policy) from missing field
+	QuotaPolicy       json.RawMessage `json:"quota_policy"`
+	ConcurrencyPolicy json.RawMessage `json:"concurrency_policy"`
+	ExpiredAt         *int64          `json:"expired_at"`
+}
+
+type RotateApiKeyRequest struct {
+	// Seconds the old key keeps working, default is 24 hours
+	GracePeriodSeconds *int64 `json:"grace_period_seconds"`
+	// Seconds from now after which the new key is rejected, never expire if omit
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+type WalletChallengeRequest struct {
+	// SS58 address or 0x prefixed hex account id
+	Account string `json:"account"`
+	// Id of service the access token is requested for, which should allow access tokens
+	Service string `json:"service"`
+}
+
+type WalletChallengeResponse struct {
+	Account string `json:"account"`
+	Service string `json:"service"`
+	Nonce   string `json:"nonce"`
+	// Message to be signed by wallet, which contains service, account and nonce
+	Message   string `json:"message"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+type WalletTokenRequest struct {
+	Account string `json:"account"`
+	// Service of the challenge, the token is only accepted by this service
+	Service string `json:"service"`
+	Nonce   string `json:"nonce"`
+	// ed25519 (default) or secp256k1
+	KeyType string `json:"key_type"`
+	// Hex encoded public key, required for secp256k1 since account id is hash of public key
+	PublicKey string `json:"public_key"`
+	// Hex encoded signature of challenge message, the message wrapped with <Bytes></Bytes> is also accepted
+	Signature string `json:"signature"`
+}
+
+type WalletTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Account     string `json:"account"`
+	Service     string `json:"service"`
+}
+
+type NewAdminTokenRequest struct {
+	Name string `json:"name"`
+	// superadmin, provider or auditor
+	Role string `json:"role"`
+	// Required for provider token, which can only manage services with the same service_provider_account
+	Account string `json:"account"`
+	// Seconds from now after which the token is rejected, never expire if omit
+	ExpiresIn int64 `json:"expires_in"`
+}
@@ -0,0 +1,85 @@
+package retry
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Backoff returns delay before the retry of attempt, which is the first retry if attempt is 1.
+// The delay grows exponentially from base and is capped at max, and full jitter is applied,
+// so retries of concurrent requests are spread instead of hitting upstream at the same time.
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if max > 0 && delay > max {
+		delay = max
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// BudgetPolicy limits retries of a key relative to requests, so retries won't multiply load of a failing upstream
+type BudgetPolicy struct {
+	Percent    int           // Max retries in window as percentage of requests
+	MinRetries int           // Retries always allowed in window, so keys with few requests can still retry
+	Window     time.Duration // Length of fixed window requests and retries are counted in
+}
+
+// Budget counts requests and retries of keys, such as services
+type Budget struct {
+	lock     sync.Mutex
+	counters map[string]*counter
+	now      func() time.Time
+}
+
+type counter struct {
+	windowStart       time.Time
+	requests, retries int
+}
+
+func NewBudget() *Budget {
+	return &Budget{counters: make(map[string]*counter), now: time.Now}
+}
+
+// RecordRequest counts an original request of key, retries should not be recorded
+func (b *Budget) RecordRequest(key string, policy BudgetPolicy) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.counter(key, policy).requests++
+}
+
+// AllowRetry takes a retry from budget of key, false is returned if budget exhausted
+func (b *Budget) AllowRetry(key string, policy BudgetPolicy) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	c := b.counter(key, policy)
+	allowed := c.requests * policy.Percent / 100
+	if allowed < policy.MinRetries {
+		allowed = policy.MinRetries
+	}
+	if c.retries >= allowed {
+		return false
+	}
+	c.retries++
+	return true
+}
+
+// counter returns counter of key in current window
+func (b *Budget) counter(key string, policy BudgetPolicy) *counter {
+	now := b.now()
+	c, ok := b.counters[key]
+	if !ok {
+		c = &counter{}
+		b.counters[key] = c
+	}
+	if now.Sub(c.windowStart) >= policy.Window {
+		c.windowStart = now
+		c.requests, c.retries = 0, 0
+	}
+	return c
+}
@@ -0,0 +1,55 @@
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	for attempt := 1; attempt <= 6; attempt++ {
+		max := 10 * time.Millisecond << (attempt - 1)
+		if max > 100*time.Millisecond {
+			max = 100 * time.Millisecond
+		}
+		for i := 0; i < 100; i++ {
+			if d := Backoff(attempt, 10*time.Millisecond, 100*time.Millisecond); d < 0 || d > max {
+				t.Fatalf("attempt %d: expected backoff in [0, %s], got %s", attempt, max, d)
+			}
+		}
+	}
+	if d := Backoff(3, 0, time.Second); d != 0 {
+		t.Errorf("expected no backoff without base, got %s", d)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	b := NewBudget()
+	b.now = func() time.Time { return now }
+	policy := BudgetPolicy{Percent: 20, MinRetries: 2, Window: 10 * time.Second}
+
+	retries := func() int {
+		n := 0
+		for b.AllowRetry("service", policy) {
+			n++
+		}
+		return n
+	}
+
+	// Min retries are allowed before enough requests recorded
+	b.RecordRequest("service", policy)
+	if n := retries(); n != 2 {
+		t.Errorf("expected min retries allowed, got %d", n)
+	}
+	for i := 0; i < 19; i++ {
+		b.RecordRequest("service", policy)
+	}
+	if n := retries(); n != 2 {
+		t.Errorf("expected retries up to 20%% of 20 requests, got %d", n)
+	}
+
+	now = now.Add(10 * time.Second)
+	if n := retries(); n != 2 {
+		t.Errorf("expected budget reset in new window, got %d", n)
+	}
+}
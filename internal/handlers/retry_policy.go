@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal/handlers/retry"
+	"apron.network/gateway/internal/models"
+)
+
+// retryBudgetWindow is the window requests and retries of a service are counted in for retry budget
+const retryBudgetWindow = 10 * time.Second
+
+// toBudgetPolicy converts budget declared in retry policy to policy used by retry.Budget
+func toBudgetPolicy(p *models.RetryPolicy) retry.BudgetPolicy {
+	policy := retry.BudgetPolicy{Percent: 20, MinRetries: 10, Window: retryBudgetWindow}
+	if p.BudgetPercent > 0 {
+		policy.Percent = int(p.BudgetPercent)
+	}
+	if p.BudgetMinRetries > 0 {
+		policy.MinRetries = int(p.BudgetMinRetries)
+	}
+	return policy
+}
+
+// retryBackoff returns delay before the retry of attempt declared in retry policy
+func retryBackoff(p *models.RetryPolicy, attempt int) time.Duration {
+	base, max := 25*time.Millisecond, 250*time.Millisecond
+	if p.BaseBackoffMs > 0 {
+		base = time.Duration(p.BaseBackoffMs) * time.Millisecond
+	}
+	if p.MaxBackoffMs > 0 {
+		max = time.Duration(p.MaxBackoffMs) * time.Millisecond
+	}
+	return retry.Backoff(attempt, base, max)
+}
+
+// upstreamErrorClass classifies error returned while requesting upstream, empty string is returned for other errors
+func upstreamErrorClass(err error) string {
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, fasthttp.ErrTimeout), errors.Is(err, fasthttp.ErrDialTimeout):
+		return models.RetryOnTimeout
+	case errors.As(err, &opErr) && opErr.Op == "dial", errors.Is(err, syscall.ECONNREFUSED):
+		return models.RetryOnConnectFailure
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE), errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, fasthttp.ErrConnectionClosed):
+		return models.RetryOnReset
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return models.RetryOnTimeout
+	}
+	return ""
+}
+
+// isRetryable checks whether the failed attempt, with error or response status, is retried by retry policy
+func isRetryable(p *models.RetryPolicy, err error, status int) bool {
+	if err != nil {
+		class := upstreamErrorClass(err)
+		for _, retryOn := range models.RetryOnErrors(p) {
+			if class != "" && class == retryOn {
+				return true
+			}
+		}
+		return false
+	}
+	for _, retryOn := range models.RetryOnStatuses(p) {
+		if status == int(retryOn) {
+			return true
+		}
+	}
+	return false
+}
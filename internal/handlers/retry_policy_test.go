@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/models"
+)
+
+// startFlakyUpstream starts a http server which responds 503 to the first failures requests
+func startFlakyUpstream(t *testing.T, failures int32) (string, *int32) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen upstream: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	hits := new(int32)
+	go fasthttp.Serve(ln, func(ctx *fasthttp.RequestCtx) {
+		if atomic.AddInt32(hits, 1) <= failures {
+			ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(ctx, "ok")
+	})
+	return ln.Addr().String(), hits
+}
+
+func TestProxyHandlerRetryPolicy(t *testing.T) {
+	storageManager := models.NewMemoryStorageManager()
+	flaky, hits := startFlakyUpstream(t, 2)
+	alive := startEchoUpstream(t)
+	dead := closedAddr(t)
+
+	retryPolicy := &models.RetryPolicy{MaxAttempts: 3, BaseBackoffMs: 1, MaxBackoffMs: 5}
+	for _, service := range []*models.ApronService{
+		{Id: "flaky_service", Schema: "http", BaseUrl: flaky, RetryPolicy: retryPolicy},
+		{Id: "mixed_service", Schema: "http", Upstreams: []*models.UpstreamTarget{{BaseUrl: alive}, {BaseUrl: dead}}, RetryPolicy: retryPolicy},
+	} {
+		if err := validateServiceSettings(service); err != nil {
+			t.Fatal(err)
+		}
+		binaryService, _ := proto.Marshal(service)
+		storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+		binaryKey, _ := proto.Marshal(&models.ApronApiKey{Key: "key_0", ServiceId: service.Id})
+		storageManager.SaveBinaryKeyData(internal.ServiceApiKeyStorageBucketName(service.Id), models.HashApiKey("key_0"), binaryKey)
+	}
+	proxy := newTestProxyHandler(t, storageManager)
+
+	// Non-idempotent requests are not retried by default
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(fasthttp.MethodPost)
+	ctx.Request.SetRequestURI("/v1/flaky_service/key_0/anything")
+	proxy.InternalHandler(ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusServiceUnavailable || atomic.LoadInt32(hits) != 1 {
+		t.Fatalf("expected POST not retried, got %d after %d attempts", ctx.Response.StatusCode(), atomic.LoadInt32(hits))
+	}
+
+	// The GET request succeeds at the second attempt, since the first failure is consumed by POST
+	if ctx := serveProxy(proxy, "flaky_service", "key_0", "/anything"); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("expected retried request succeeded, got %d", ctx.Response.StatusCode())
+	}
+	if n := atomic.LoadInt32(hits); n != 3 {
+		t.Errorf("expected 3 requests to upstream, got %d", n)
+	}
+
+	// Connection failures are retried on the other target
+	for i := 0; i < 4; i++ {
+		if ctx := serveProxy(proxy, "mixed_service", "key_0", "/anything"); ctx.Response.StatusCode() != fasthttp.StatusOK {
+			t.Fatalf("expected request retried on alive target, got %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+	}
+
+	// Retries are not counted in usage
+	records, _ := proxy.AggrAccessRecordManager.ExportAllUsage()
+	if len(records) != 2 {
+		t.Fatalf("expected usage of 2 services, got %d", len(records))
+	}
+	for _, record := range records {
+		expected := map[string]uint64{"flaky_service": 2, "mixed_service": 4}[record.ServiceUuid]
+		if record.Usage != expected {
+			t.Errorf("expected usage %d of %s, got %d", expected, record.ServiceUuid, record.Usage)
+		}
+	}
+
+	if !isRetryable(retryPolicy, &net.OpError{Op: "dial", Err: fmt.Errorf("connection refused")}, 0) {
+		t.Error("expected connect failure retryable")
+	}
+	if isRetryable(retryPolicy, fasthttp.ErrTimeout, 0) {
+		t.Error("expected timeout not retryable by default")
+	}
+	if err := models.ValidateRetryPolicy(&models.RetryPolicy{RetryOn: []string{"always"}}); err == nil {
+		t.Error("expected unknown error class rejected")
+	}
+}
+
+func TestProxyHandlerRetryBackoffDeadline(t *testing.T) {
+	storageManager := models.NewMemoryStorageManager()
+	flaky, _ := startFlakyUpstream(t, 1000)
+
+	service := &models.ApronService{
+		Id:          "slow_retry_service",
+		Schema:      "http",
+		BaseUrl:     flaky,
+		RetryPolicy: &models.RetryPolicy{MaxAttempts: 10, BaseBackoffMs: models.MaxRetryBackoffMs, MaxBackoffMs: models.MaxRetryBackoffMs},
+		Timeouts:    &models.UpstreamTimeouts{TotalMs: 200},
+	}
+	if err := validateServiceSettings(service); err != nil {
+		t.Fatal(err)
+	}
+	binaryService, _ := proto.Marshal(service)
+	storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+	binaryKey, _ := proto.Marshal(&models.ApronApiKey{Key: "key_0", ServiceId: service.Id})
+	storageManager.SaveBinaryKeyData(internal.ServiceApiKeyStorageBucketName(service.Id), models.HashApiKey("key_0"), binaryKey)
+	proxy := newTestProxyHandler(t, storageManager)
+
+	// Backoff never sleeps past the total deadline
+	start := time.Now()
+	ctx := serveProxy(proxy, service.Id, "key_0", "/anything")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected retries limited by total deadline, took %v", elapsed)
+	}
+	if ctx.Response.StatusCode() != fasthttp.StatusServiceUnavailable {
+		t.Errorf("expected failure of the last attempt, got %d", ctx.Response.StatusCode())
+	}
+
+	if err := models.ValidateRetryPolicy(&models.RetryPolicy{MaxBackoffMs: models.MaxRetryBackoffMs + 1}); err == nil {
+		t.Error("expected too long max_backoff_ms rejected")
+	}
+}
@@ -14,6 +14,7 @@ import (
 func (h *ManagerHandler) listServiceHandler(ctx *fasthttp.RequestCtx) {
 	var cursor uint64
 	rslt := make([]*models.ApronService, 0, 100)
+	account := providerAccount(ctx)
 
 	// TODO: Refactor this scanall with function
 	for {
@@ -23,12 +24,21 @@ func (h *ManagerHandler) listServiceHandler(ctx *fasthttp.RequestCtx) {
 			"",
 			100,
 		)
-		internal.CheckError(err)
+		if err != nil {
+			internal.WriteErrorResponse(ctx, err)
+			return
+		}
 
 		for _, v := range scanResultMap {
 			tmpRcd := &models.ApronService{}
-			err := proto.Unmarshal([]byte(v), tmpRcd)
-			internal.CheckError(err)
+			if err := proto.Unmarshal([]byte(v), tmpRcd); err != nil {
+				internal.WriteErrorResponse(ctx, err)
+				return
+			}
+			// Provider tokens only see their own services
+			if account != "" && tmpRcd.ServiceProviderAccount != account {
+				continue
+			}
 			rslt = append(rslt, tmpRcd)
 		}
 
@@ -40,7 +50,10 @@ func (h *ManagerHandler) listServiceHandler(ctx *fasthttp.RequestCtx) {
 	}
 
 	respBody, err := json.Marshal(rslt)
-	internal.CheckError(err)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
 	ctx.Write(respBody)
 }
 
@@ -51,19 +64,49 @@ func (h *ManagerHandler) listServiceHandler(ctx *fasthttp.RequestCtx) {
 // An error will be respond if service with same name already existing.
 func (h *ManagerHandler) newServiceHandler(ctx *fasthttp.RequestCtx) {
 	detail, err := models.ExtractCtxRequestDetail(ctx)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError("invalid request: %v", err))
+		return
+	}
 
 	service := models.ApronService{}
-	err = json.Unmarshal(detail.RequestBody, &service)
-	internal.CheckError(err)
+	if err = json.Unmarshal(detail.RequestBody, &service); err != nil {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError("invalid service: %v", err))
+		return
+	}
+	if err = h.checkJwksFile(ctx, &service, ""); err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	if err = validateServiceSettings(&service); err != nil {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError(err.Error()))
+		return
+	}
+	if account := providerAccount(ctx); account != "" {
+		// Services created by provider are owned by the account of token
+		service.ServiceProviderAccount = account
+	}
+
+	existing, err := h.storageManager.IsKeyExistingInBucket(internal.ServiceBucketName, service.Id)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
 
-	if h.storageManager.IsKeyExistingInBucket(internal.ServiceBucketName, service.Id) {
-		ctx.SetStatusCode(fasthttp.StatusBadRequest)
-		ctx.WriteString("duplicated service name")
+	if existing {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError("duplicated service name"))
 	} else {
 		binaryService, err := proto.Marshal(&service)
-		internal.CheckError(err)
-		err = h.storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
-		internal.CheckError(err)
+		if err != nil {
+			internal.WriteErrorResponse(ctx, err)
+			return
+		}
+		if err = h.storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService); err != nil {
+			internal.WriteErrorResponse(ctx, err)
+			return
+		}
+		h.notifyRecordChanged(internal.ServiceBucketName, service.Id)
+		h.recordAudit(ctx, "service.create", auditServiceTarget(service.Id), "", auditSnapshot(&service))
 
 		ctx.SetStatusCode(fasthttp.StatusCreated)
 	}
@@ -71,8 +114,58 @@ func (h *ManagerHandler) newServiceHandler(ctx *fasthttp.RequestCtx) {
 func (h *ManagerHandler) serviceDetailHandler(ctx *fasthttp.RequestCtx) {
 	fmt.Fprintf(ctx, "Service Detail")
 }
+
+// updateServiceHandler updates fields present in request body to existing service,
+// while service id can not be changed.
 func (h *ManagerHandler) updateServiceHandler(ctx *fasthttp.RequestCtx) {
-	fmt.Fprintf(ctx, "Update Service")
+	serviceId := ctx.UserValue("service_name").(string)
+
+	binaryService, err := h.storageManager.GetRecord(internal.ServiceBucketName, serviceId)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	service := models.ApronService{}
+	if err = proto.Unmarshal([]byte(binaryService), &service); err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	before := auditSnapshot(&service)
+	previousJwksFile := service.GetJwtAuth().GetJwksFile()
+
+	// Only fields present in body are overwritten
+	if err = json.Unmarshal(ctx.PostBody(), &service); err != nil {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError("invalid service: %v", err))
+		return
+	}
+	if err = h.checkJwksFile(ctx, &service, previousJwksFile); err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	if err = validateServiceSettings(&service); err != nil {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError(err.Error()))
+		return
+	}
+	service.Id = serviceId
+	if account := providerAccount(ctx); account != "" {
+		// Provider can not transfer the service to other accounts
+		service.ServiceProviderAccount = account
+	}
+
+	updatedBinaryService, err := proto.Marshal(&service)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	if err = h.storageManager.SaveBinaryKeyData(internal.ServiceBucketName, serviceId, updatedBinaryService); err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+	h.notifyRecordChanged(internal.ServiceBucketName, serviceId)
+	h.recordAudit(ctx, "service.update", auditServiceTarget(serviceId), before, auditSnapshot(&service))
+
+	respBody, _ := json.Marshal(&service)
+	ctx.Write(respBody)
 }
 
 func (h *ManagerHandler) deleteServiceHandler(ctx *fasthttp.RequestCtx) {
@@ -83,9 +176,43 @@ func (h *ManagerHandler) serviceUsageReportHandler(ctx *fasthttp.RequestCtx) {
 	keyId := ctx.UserValue("key_id").(string)
 	rslt, err := h.AggrAccessRecordManager.ExportUsage(serviceId, keyId)
 	if err != nil {
-		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
-		ctx.SetBodyString(err.Error())
+		internal.WriteErrorResponse(ctx, err)
 	} else {
 		ctx.SetBodyString(rslt)
 	}
 }
+
+// validateServiceSettings checks policies, key locations, upstreams and resilience settings declared in service
+func validateServiceSettings(service *models.ApronService) error {
+	if err := validateRateLimitPolicies(service.RateLimitPolicies); err != nil {
+		return err
+	}
+	if err := models.ValidateQuotaPolicy(service.QuotaPolicy); err != nil {
+		return err
+	}
+	if err := models.ValidateConcurrencyPolicy(service.ConcurrencyPolicy); err != nil {
+		return err
+	}
+	if err := models.ValidateConcurrencyPolicy(service.KeyConcurrencyPolicy); err != nil {
+		return err
+	}
+	if err := models.ValidateApiKeyLocations(service.KeyLocations); err != nil {
+		return err
+	}
+	if err := models.ValidateUpstreams(service); err != nil {
+		return err
+	}
+	if err := models.ValidateHealthCheck(service); err != nil {
+		return err
+	}
+	if err := models.ValidateCircuitBreaker(service.CircuitBreaker); err != nil {
+		return err
+	}
+	if err := models.ValidateRetryPolicy(service.RetryPolicy); err != nil {
+		return err
+	}
+	if err := models.ValidateUpstreamTimeouts(service.Timeouts); err != nil {
+		return err
+	}
+	return validateAuthMode(service)
+}
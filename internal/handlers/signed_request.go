@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/url"
+	"time"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/models"
+)
+
+// defaultSignatureMaxSkew is the max difference between timestamp of signed request and gateway clock
+const defaultSignatureMaxSkew = 5 * time.Minute
+
+// isSignedRequest checks whether the request is signed with hmac key instead of sending key
+func isSignedRequest(c *ProxyContext) bool {
+	return models.IsHmacAuthorization(string(c.Ctx.Request.Header.Peek("Authorization")))
+}
+
+// validateSignedRequest verifies signature of request signed with hmac key. Stale timestamps are rejected,
+// and nonces are saved in storage during the allowed skew, so a signed request can't be replayed on any gateway node.
+func (h *ProxyHandler) validateSignedRequest(c *ProxyContext) error {
+	serviceName := c.RequestDetail.ServiceNameStr
+	invalidSignatureErr := internal.UnauthorizedError("invalid signature for service %s", serviceName)
+
+	header := &c.Ctx.Request.Header
+	auth, err := models.ParseHmacAuthorization(string(header.Peek("Authorization")))
+	if err != nil {
+		return internal.UnauthorizedError("invalid authorization: %v", err)
+	}
+	// Signature is not forwarded to upstream service
+	header.Del("Authorization")
+	delete(c.RequestDetail.Headers, "Authorization")
+
+	keyHash, err := h.RecordCache.GetApiKeyHash(serviceName, auth.KeyId)
+	if err != nil {
+		if internal.ToGatewayError(err).Code == internal.ErrCodeNotFound {
+			return invalidSignatureErr
+		}
+		return err
+	}
+	apiKey, err := h.RecordCache.GetApiKey(serviceName, keyHash)
+	if err != nil {
+		if internal.ToGatewayError(err).Code == internal.ErrCodeNotFound {
+			return invalidSignatureErr
+		}
+		return err
+	}
+	if apiKey.AuthMode != models.ApiKeyAuthModeHmac || apiKey.HmacSecret == "" {
+		return internal.UnauthorizedError("key %s does not support signed request", auth.KeyId)
+	}
+
+	skew := time.Since(time.Unix(auth.Timestamp, 0))
+	if skew > h.SignatureMaxSkew || skew < -h.SignatureMaxSkew {
+		return internal.UnauthorizedError("timestamp %d is not within %s of gateway time", auth.Timestamp, h.SignatureMaxSkew)
+	}
+
+	detail := c.RequestDetail
+	stringToSign := models.HmacStringToSign(detail.Method, string(detail.Path), url.Values(detail.QueryParams), detail.RequestBody, auth.Timestamp, auth.Nonce)
+	if !models.VerifyHmac(apiKey.HmacSecret, stringToSign, auth.Signature) {
+		return invalidSignatureErr
+	}
+
+	// Nonce is saved after signature verified, so forged requests can't occupy nonces
+	saved, err := h.StorageManager.SaveKeyIfAbsent(internal.HmacNonceBucketName, auth.KeyId+":"+auth.Nonce, 2*h.SignatureMaxSkew)
+	if err != nil {
+		return err
+	}
+	if !saved {
+		return internal.UnauthorizedError("nonce %s is already used", auth.Nonce)
+	}
+
+	return h.authorizeApiKey(c, apiKey)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"fmt"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/handlers/balancer"
+	"apron.network/gateway/internal/handlers/breaker"
+	"apron.network/gateway/internal/models"
+)
+
+// toBalancerStrategy converts load balancer declared in service to strategy used by balancer.Balancer
+func toBalancerStrategy(loadBalancer string) balancer.Strategy {
+	switch loadBalancer {
+	case models.LoadBalancerWeighted:
+		return balancer.Weighted
+	case models.LoadBalancerLeastOutstanding:
+		return balancer.LeastOutstanding
+	case models.LoadBalancerConsistentHash:
+		return balancer.ConsistentHash
+	}
+	return balancer.RoundRobin
+}
+
+// pickUpstream picks a target of service for the request, which is shared by http and websocket forwarding.
+// The target is counted as outstanding until the request finished or the websocket session closed.
+// Targets tried by previous attempts are avoided if other targets available, and the result of the request
+// should be reported with ProxyContext.reportUpstream if circuit breaker declared.
+func (h *ProxyHandler) pickUpstream(c *ProxyContext) error {
+	upstreams := models.UpstreamTargets(c.Service)
+	targets := make([]balancer.Target, len(upstreams))
+	for idx, upstream := range upstreams {
+		targets[idx] = balancer.Target{Addr: upstream.BaseUrl, Weight: models.UpstreamWeight(upstream)}
+	}
+
+	var breakerConfig breaker.Config
+	if c.Service.CircuitBreaker != nil {
+		breakerConfig = toBreakerConfig(c.Service.CircuitBreaker)
+	}
+
+	// Targets marked unhealthy by active health check, or with open circuit are skipped
+	circuitOpen := false
+	available := func(t balancer.Target) bool {
+		if !h.HealthChecker.IsHealthy(c.Service.Id, t.Addr) {
+			return false
+		}
+		if c.Service.CircuitBreaker != nil && !h.Breakers.Available(c.Service.Id, t.Addr, breakerConfig) {
+			circuitOpen = true
+			return false
+		}
+		return true
+	}
+
+	// Requests of the same key stick to the same target with consistent hash
+	pick := func(skipTried bool) (balancer.Target, func(), error) {
+		return h.Balancer.Pick(c.Service.Id, toBalancerStrategy(c.Service.LoadBalancer), targets, models.ApiKeyId(c.ApiKey), func(t balancer.Target) bool {
+			return !(skipTried && c.triedTargets[t.Addr]) && available(t)
+		})
+	}
+	target, release, err := pick(true)
+	if err == balancer.ErrNoTarget && len(c.triedTargets) > 0 {
+		// Retried on a tried target if no other target left
+		target, release, err = pick(false)
+	}
+	if err == balancer.ErrNoTarget {
+		if circuitOpen {
+			return internal.CircuitOpenError("circuits of upstream targets of service %s are open", c.Service.Id)
+		}
+		return internal.UpstreamUnavailableError("no healthy upstream target for service %s", c.Service.Id)
+	} else if err != nil {
+		return internal.UpstreamError(fmt.Errorf("no upstream target available for service %s: %v", c.Service.Id, err))
+	}
+
+	if c.Service.CircuitBreaker != nil {
+		// The last half-open probe may be taken by other requests after picked
+		done, err := h.Breakers.Allow(c.Service.Id, target.Addr, breakerConfig)
+		if err != nil {
+			release()
+			return internal.CircuitOpenError("circuit of upstream target of service %s is open", c.Service.Id)
+		}
+		c.upstreamDone = done
+	}
+	c.Target = target.Addr
+	c.releaseTarget = release
+	c.OnSessionClose(release)
+	return nil
+}
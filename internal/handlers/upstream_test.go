@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/models"
+)
+
+func TestProxyHandlerUpstreams(t *testing.T) {
+	storageManager := models.NewMemoryStorageManager()
+	upstream := startEchoUpstream(t)
+	// Targets are told apart by path prefix echoed by upstream
+	upstreams := []*models.UpstreamTarget{{BaseUrl: upstream + "/a/"}, {BaseUrl: upstream + "/b/", Weight: 3}}
+	for _, service := range []*models.ApronService{
+		{Id: "rr_service", Schema: "http", Upstreams: upstreams},
+		{Id: "weighted_service", Schema: "http", Upstreams: upstreams, LoadBalancer: models.LoadBalancerWeighted},
+		{Id: "hash_service", Schema: "http", Upstreams: upstreams, LoadBalancer: models.LoadBalancerConsistentHash},
+	} {
+		if err := validateServiceSettings(service); err != nil {
+			t.Fatal(err)
+		}
+		binaryService, _ := proto.Marshal(service)
+		storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+		for i := 0; i < 32; i++ {
+			key := fmt.Sprintf("key_%d", i)
+			binaryKey, _ := proto.Marshal(&models.ApronApiKey{Key: key, ServiceId: service.Id})
+			storageManager.SaveBinaryKeyData(internal.ServiceApiKeyStorageBucketName(service.Id), models.HashApiKey(key), binaryKey)
+		}
+	}
+	proxy := newTestProxyHandler(t, storageManager)
+
+	request := func(serviceId, key string) string {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.SetRequestURI(fmt.Sprintf("/v1/%s/%s/anything", serviceId, key))
+		proxy.InternalHandler(ctx)
+		if ctx.Response.StatusCode() != fasthttp.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d %q", serviceId, ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+		return strings.Split(string(ctx.Response.Body()), "/")[1]
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 8; i++ {
+		counts[request("rr_service", "key_0")]++
+	}
+	if counts["a"] != 4 || counts["b"] != 4 {
+		t.Errorf("expected requests balanced in turn, got %v", counts)
+	}
+
+	counts = map[string]int{}
+	for i := 0; i < 8; i++ {
+		counts[request("weighted_service", "key_0")]++
+	}
+	if counts["a"] != 2 || counts["b"] != 6 {
+		t.Errorf("expected requests balanced by weight, got %v", counts)
+	}
+
+	// Targets are hashed with random port of upstream, so enough keys are used to reach both targets
+	counts = map[string]int{}
+	for i := 0; i < 32; i++ {
+		key := fmt.Sprintf("key_%d", i)
+		target := request("hash_service", key)
+		if again := request("hash_service", key); again != target {
+			t.Errorf("expected %s sticky to target %s, got %s", key, target, again)
+		}
+		counts[target]++
+	}
+	if counts["a"] == 0 || counts["b"] == 0 {
+		t.Errorf("expected keys spread across targets, got %v", counts)
+	}
+
+	if err := models.ValidateUpstreams(&models.ApronService{LoadBalancer: "random"}); err == nil {
+		t.Errorf("expected unknown load balancer rejected")
+	}
+	if err := models.ValidateUpstreams(&models.ApronService{Upstreams: upstreams[:1], LoadBalancer: models.LoadBalancerWeighted}); err != nil {
+		t.Errorf("expected valid upstreams, got %v", err)
+	}
+	if err := models.ValidateUpstreams(&models.ApronService{Upstreams: append(upstreams, upstreams[0])}); err == nil {
+		t.Errorf("expected duplicated upstream rejected")
+	}
+	if err := models.ValidateUpstreams(&models.ApronService{Upstreams: []*models.UpstreamTarget{{BaseUrl: upstream + "/", Weight: models.MaxUpstreamWeight + 1}}}); err == nil {
+		t.Errorf("expected too large weight rejected")
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/models"
+)
+
+// Causes of upstream timeout, which are responded in error message and written to access log
+const (
+	timeoutCauseConnect = "connect"
+	timeoutCauseRead    = "read"
+	timeoutCauseWrite   = "write"
+	timeoutCauseTotal   = "total"
+)
+
+// upstreamTimeouts is timeouts declared in service with defaults of unset fields, zero Total means not limited
+type upstreamTimeouts struct {
+	Connect, Read, Write, Total time.Duration
+}
+
+func toUpstreamTimeouts(t *models.UpstreamTimeouts) upstreamTimeouts {
+	timeouts := upstreamTimeouts{Connect: 5 * time.Second, Read: 30 * time.Second, Write: 30 * time.Second}
+	if t == nil {
+		return timeouts
+	}
+	if t.ConnectMs > 0 {
+		timeouts.Connect = time.Duration(t.ConnectMs) * time.Millisecond
+	}
+	if t.ReadMs > 0 {
+		timeouts.Read = time.Duration(t.ReadMs) * time.Millisecond
+	}
+	if t.WriteMs > 0 {
+		timeouts.Write = time.Duration(t.WriteMs) * time.Millisecond
+	}
+	timeouts.Total = time.Duration(t.TotalMs) * time.Millisecond
+	return timeouts
+}
+
+// upstreamClients keeps a http client for each combination of timeouts, so connections are pooled across requests
+type upstreamClients struct {
+	lock    sync.Mutex
+	clients map[upstreamTimeouts]*fasthttp.Client
+}
+
+func (u *upstreamClients) get(timeouts upstreamTimeouts) *fasthttp.Client {
+	// Total is applied as deadline of each request
+	timeouts.Total = 0
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if u.clients == nil {
+		u.clients = make(map[upstreamTimeouts]*fasthttp.Client)
+	}
+	client, ok := u.clients[timeouts]
+	if !ok {
+		connectTimeout := timeouts.Connect
+		client = &fasthttp.Client{
+			ReadTimeout:  timeouts.Read,
+			WriteTimeout: timeouts.Write,
+			Dial: func(addr string) (net.Conn, error) {
+				return fasthttp.DialTimeout(addr, connectTimeout)
+			},
+		}
+		u.clients[timeouts] = client
+	}
+	return client
+}
+
+// timeoutCause returns which timeout err is caused by, empty string is returned if err is not a timeout
+func timeoutCause(err error, deadline time.Time) string {
+	var opErr *net.OpError
+	var netErr net.Error
+	isTimeout := errors.Is(err, fasthttp.ErrTimeout) || (errors.As(err, &netErr) && netErr.Timeout())
+
+	switch {
+	case errors.Is(err, fasthttp.ErrDialTimeout), isTimeout && errors.As(err, &opErr) && opErr.Op == "dial":
+		return timeoutCauseConnect
+	case isTimeout && !deadline.IsZero() && !time.Now().Before(deadline):
+		return timeoutCauseTotal
+	case isTimeout && errors.As(err, &opErr) && opErr.Op == "write":
+		return timeoutCauseWrite
+	case isTimeout:
+		return timeoutCauseRead
+	}
+	return ""
+}
+
+// handshakeTimeoutCause returns which timeout err of websocket handshake is caused by. Handshake after connected
+// is limited by total timeout if declared, or the read timeout, so other timeouts are reported as the limiting one.
+func handshakeTimeoutCause(err error, timeouts upstreamTimeouts) string {
+	switch cause := timeoutCause(err, time.Time{}); cause {
+	case "", timeoutCauseConnect:
+		return cause
+	}
+	if timeouts.Total > 0 {
+		return timeoutCauseTotal
+	}
+	return timeoutCauseRead
+}
+
+// upstreamRequestError converts error occurred while requesting upstream to gateway error,
+// and timeouts are written to access log with the cause.
+func (h *ProxyHandler) upstreamRequestError(c *ProxyContext, err error, deadline time.Time) error {
+	return h.upstreamError(c, err, timeoutCause(err, deadline))
+}
+
+// upstreamError converts upstream error to gateway error with the timeout cause, empty cause means not a timeout
+func (h *ProxyHandler) upstreamError(c *ProxyContext, err error, cause string) error {
+	if cause == "" {
+		return internal.UpstreamError(err)
+	}
+
+	h.Logger.Log(fmt.Sprintf("%s|504 timeout|%s: from %s, service: %s, api_key: %s, target: %s, cause: %s\n",
+		time.Now().UTC().Format("2006-01-02 15:04:05"),
+		loggableUri(c),
+		c.Ctx.RemoteIP().String(),
+		c.RequestDetail.ServiceNameStr,
+		models.ApiKeyId(c.ApiKey),
+		c.Target,
+		cause,
+	))
+	return internal.UpstreamTimeoutError(cause, err)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/models"
+)
+
+// startSilentUpstream starts a tcp server which accepts connections but never responds
+func startSilentUpstream(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen upstream: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestProxyHandlerUpstreamTimeouts(t *testing.T) {
+	storageManager := models.NewMemoryStorageManager()
+	silent := startSilentUpstream(t)
+
+	for _, service := range []*models.ApronService{
+		{Id: "read_service", Schema: "http", BaseUrl: silent, Timeouts: &models.UpstreamTimeouts{ReadMs: 50}},
+		{
+			Id: "total_service", Schema: "http", BaseUrl: silent,
+			Timeouts:    &models.UpstreamTimeouts{ReadMs: 40, TotalMs: 100},
+			RetryPolicy: &models.RetryPolicy{MaxAttempts: 10, RetryOn: []string{models.RetryOnTimeout}, BaseBackoffMs: 1},
+		},
+		{Id: "ws_service", Schema: "ws", BaseUrl: silent, Timeouts: &models.UpstreamTimeouts{ReadMs: 50}},
+		{Id: "ws_total_service", Schema: "ws", BaseUrl: silent, Timeouts: &models.UpstreamTimeouts{TotalMs: 50}},
+	} {
+		if err := validateServiceSettings(service); err != nil {
+			t.Fatal(err)
+		}
+		binaryService, _ := proto.Marshal(service)
+		storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+		binaryKey, _ := proto.Marshal(&models.ApronApiKey{Key: "key_0", ServiceId: service.Id})
+		storageManager.SaveBinaryKeyData(internal.ServiceApiKeyStorageBucketName(service.Id), models.HashApiKey("key_0"), binaryKey)
+	}
+	proxy := newTestProxyHandler(t, storageManager)
+
+	expectTimeout := func(ctx *fasthttp.RequestCtx, cause string) {
+		t.Helper()
+		body := string(ctx.Response.Body())
+		if ctx.Response.StatusCode() != fasthttp.StatusGatewayTimeout || !strings.Contains(body, internal.ErrCodeUpstreamTimeout) || !strings.Contains(body, cause) {
+			t.Errorf("expected %s timeout, got %d %q", cause, ctx.Response.StatusCode(), body)
+		}
+	}
+
+	start := time.Now()
+	expectTimeout(serveProxy(proxy, "read_service", "key_0", "/anything"), timeoutCauseRead)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected request failed after read timeout, took %s", elapsed)
+	}
+
+	// Retries stop once total timeout exceeded
+	start = time.Now()
+	expectTimeout(serveProxy(proxy, "total_service", "key_0", "/anything"), timeoutCauseTotal)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected retries limited by total timeout, took %s", elapsed)
+	}
+
+	// Handshake is limited by read timeout, or total timeout if declared
+	serveWebsocket := func(serviceId string) *fasthttp.RequestCtx {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.SetRequestURI("/v1/" + serviceId + "/key_0/anything")
+		ctx.Request.Header.Set("Connection", "Upgrade")
+		ctx.Request.Header.Set("Upgrade", "websocket")
+		ctx.Request.Header.Set("Sec-WebSocket-Version", "13")
+		ctx.Request.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		proxy.InternalHandler(ctx)
+		return ctx
+	}
+	expectTimeout(serveWebsocket("ws_service"), timeoutCauseRead)
+	expectTimeout(serveWebsocket("ws_total_service"), timeoutCauseTotal)
+
+	if cause := timeoutCause(fasthttp.ErrDialTimeout, time.Time{}); cause != timeoutCauseConnect {
+		t.Errorf("expected connect timeout, got %q", cause)
+	}
+	if cause := handshakeTimeoutCause(fasthttp.ErrDialTimeout, upstreamTimeouts{Total: time.Second}); cause != timeoutCauseConnect {
+		t.Errorf("expected connect timeout of handshake, got %q", cause)
+	}
+	if cause := timeoutCause(fasthttp.ErrConnectionClosed, time.Time{}); cause != "" {
+		t.Errorf("expected other errors not treated as timeout, got %q", cause)
+	}
+}
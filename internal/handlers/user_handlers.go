@@ -10,7 +10,13 @@ import (
 )
 
 func (h *ManagerHandler) listAllUsersHandler(ctx *fasthttp.RequestCtx) {
-	if !h.storageManager.IsKeyExisting(internal.UserBucketName) {
+	existing, err := h.storageManager.IsKeyExisting(internal.UserBucketName)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+
+	if !existing {
 		ctx.SetBodyString("[]")
 	} else {
 		var cursor uint64
@@ -24,7 +30,10 @@ func (h *ManagerHandler) listAllUsersHandler(ctx *fasthttp.RequestCtx) {
 				"",
 				100,
 			)
-			internal.CheckError(err)
+			if err != nil {
+				internal.WriteErrorResponse(ctx, err)
+				return
+			}
 
 			for userId, _ := range scanResultMap {
 				rslt = append(rslt, userId)
@@ -38,7 +47,10 @@ func (h *ManagerHandler) listAllUsersHandler(ctx *fasthttp.RequestCtx) {
 		}
 
 		respBody, err := json.Marshal(rslt)
-		internal.CheckError(err)
+		if err != nil {
+			internal.WriteErrorResponse(ctx, err)
+			return
+		}
 		ctx.Write(respBody)
 	}
 }
@@ -46,22 +58,33 @@ func (h *ManagerHandler) listAllUsersHandler(ctx *fasthttp.RequestCtx) {
 func (h *ManagerHandler) updateUserProfileHandler(ctx *fasthttp.RequestCtx) {}
 func (h *ManagerHandler) listAllUserKeysHandler(ctx *fasthttp.RequestCtx) {
 	detail, err := models.ExtractCtxRequestDetail(ctx)
-	internal.CheckError(err)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, internal.BadRequestError("invalid request: %v", err))
+		return
+	}
 
 	accountIdValue := detail.QueryParams["account_id"]
 	if len(accountIdValue) == 0 {
-		ctx.SetStatusCode(fasthttp.StatusBadRequest)
-		ctx.SetBodyString("missing account_id in query params")
+		internal.WriteErrorResponse(ctx, internal.BadRequestError("missing account_id in query params"))
 		return
 	}
 
 	accountId := accountIdValue[0]
 
-	if !h.storageManager.IsKeyExistingInBucket(internal.UserBucketName, accountId) {
+	existing, err := h.storageManager.IsKeyExistingInBucket(internal.UserBucketName, accountId)
+	if err != nil {
+		internal.WriteErrorResponse(ctx, err)
+		return
+	}
+
+	if !existing {
 		ctx.SetBodyString("[]")
 	} else {
 		userKeys, err := h.storageManager.GetRecord(internal.UserBucketName, accountId)
-		internal.CheckError(err)
+		if err != nil {
+			internal.WriteErrorResponse(ctx, err)
+			return
+		}
 		ctx.SetBodyString(userKeys)
 	}
 }
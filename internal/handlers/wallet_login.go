@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/handlers/ratelimiter"
+	"apron.network/gateway/internal/models"
+)
+
+const (
+	// defaultAccessTokenTTL is the lifetime of access tokens issued by wallet login
+	defaultAccessTokenTTL = 15 * time.Minute
+	// walletChallengeTTL is the duration the challenge nonce can be signed in
+	walletChallengeTTL = 5 * time.Minute
+	// maxWalletChallenges is the max challenges issued for an account in walletChallengeTTL,
+	// which limits challenges outstanding for the account
+	maxWalletChallenges = 5
+)
+
+// walletLoginRateLimit limits wallet login requests from the same IP, since they are served without credentials
+var walletLoginRateLimit = ratelimiter.Policy{Algorithm: ratelimiter.SlidingWindowCounter, Max: 30, Duration: time.Minute}
+
+// walletLoginHandler serves wallet login endpoints of proxy service, false is returned if path is not for wallet login
+func (h *ProxyHandler) walletLoginHandler(ctx *fasthttp.RequestCtx) bool {
+	var handler func(ctx *fasthttp.RequestCtx) error
+	switch string(ctx.Path()) {
+	case "/auth/challenge":
+		handler = h.walletChallengeHandler
+	case "/auth/token":
+		handler = h.walletTokenHandler
+	default:
+		return false
+	}
+
+	if !ctx.IsPost() {
+		internal.WriteErrorResponse(ctx, internal.NewGatewayError(fasthttp.StatusMethodNotAllowed, internal.ErrCodeBadRequest, "only POST method allowed"))
+	} else if err := h.limitWalletLogin(ctx, "ip:"+ctx.RemoteIP().String(), walletLoginRateLimit); err != nil {
+		internal.WriteErrorResponse(ctx, err)
+	} else if err := handler(ctx); err != nil {
+		internal.WriteErrorResponse(ctx, err)
+	}
+	return true
+}
+
+// limitWalletLogin counts wallet login request with key, and returns error if policy exceeded.
+// Keys are prefixed with /auth, which can't be a service id, so they are not mixed up with limits of services.
+func (h *ProxyHandler) limitWalletLogin(ctx *fasthttp.RequestCtx, key string, policy ratelimiter.Policy) error {
+	res, err := h.RateLimiter.GetWithPolicies("/auth:"+key, policy)
+	if err != nil {
+		return internal.InternalError(err)
+	}
+	if res.Remaining < 0 {
+		ctx.Response.Header.Set("Retry-After", strconv.FormatInt(secondsUntil(res.Reset), 10))
+		return internal.TooManyRequestsError("too many wallet login requests, %d allowed in %s", res.Total, res.Duration)
+	}
+	return nil
+}
+
+// accessTokenService loads service the access token is requested for, which should accept access tokens
+func (h *ProxyHandler) accessTokenService(serviceId string) (*models.ApronService, error) {
+	if serviceId == "" {
+		return nil, internal.BadRequestError("missing field service")
+	}
+	service, err := h.RecordCache.GetService(serviceId)
+	if err != nil {
+		if internal.ToGatewayError(err).Code == internal.ErrCodeNotFound {
+			return nil, internal.BadRequestError("service %s not found", serviceId)
+		}
+		return nil, err
+	}
+	if !service.AllowAccessTokens {
+		return nil, internal.BadRequestError("service %s does not accept access tokens", serviceId)
+	}
+	return service, nil
+}
+
+// walletChallengeKey returns key of challenge saved in storage, the account never contains colon
+func walletChallengeKey(serviceId, account, nonce string) string {
+	return serviceId + ":" + account + ":" + nonce
+}
+
+// walletChallengeHandler issues a nonce for the account to access the service,
+// which should be signed by the account key within walletChallengeTTL
+func (h *ProxyHandler) walletChallengeHandler(ctx *fasthttp.RequestCtx) error {
+	var req WalletChallengeRequest
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		return internal.BadRequestError("invalid request body: %v", err)
+	}
+	if _, err := models.DecodeAccountId(req.Account); err != nil {
+		return internal.BadRequestError("invalid account: %v", err)
+	}
+	if _, err := h.accessTokenService(req.Service); err != nil {
+		return err
+	}
+	challengeLimit := ratelimiter.Policy{Algorithm: ratelimiter.SlidingWindowLog, Max: maxWalletChallenges, Duration: walletChallengeTTL}
+	if err := h.limitWalletLogin(ctx, "account:"+req.Account, challengeLimit); err != nil {
+		return err
+	}
+
+	nonceBytes := make([]byte, 16)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return internal.InternalError(err)
+	}
+	nonce := hex.EncodeToString(nonceBytes)
+	if _, err := h.StorageManager.SaveKeyIfAbsent(internal.WalletChallengeBucketName, walletChallengeKey(req.Service, req.Account, nonce), walletChallengeTTL); err != nil {
+		return err
+	}
+
+	respBody, _ := json.Marshal(WalletChallengeResponse{
+		Account:   req.Account,
+		Service:   req.Service,
+		Nonce:     nonce,
+		Message:   models.WalletChallengeMessage(req.Service, req.Account, nonce),
+		ExpiresAt: time.Now().Add(walletChallengeTTL).Unix(),
+	})
+	ctx.SetContentType("application/json")
+	ctx.SetBody(respBody)
+	return nil
+}
+
+// walletTokenHandler verifies signature of challenge message and issues access token bound to the account and service.
+// The challenge is consumed before verifying, so each nonce can only be tried once.
+func (h *ProxyHandler) walletTokenHandler(ctx *fasthttp.RequestCtx) error {
+	var req WalletTokenRequest
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		return internal.BadRequestError("invalid request body: %v", err)
+	}
+	if req.KeyType == "" {
+		req.KeyType = models.WalletKeyTypeEd25519
+	}
+	publicKey, err := hex.DecodeString(strings.TrimPrefix(req.PublicKey, "0x"))
+	if err != nil {
+		return internal.BadRequestError("public key should be hex encoded")
+	}
+	signature, err := hex.DecodeString(strings.TrimPrefix(req.Signature, "0x"))
+	if err != nil {
+		return internal.BadRequestError("signature should be hex encoded")
+	}
+	if _, err := h.accessTokenService(req.Service); err != nil {
+		return err
+	}
+
+	consumed, err := h.StorageManager.DeleteExpiringKey(internal.WalletChallengeBucketName, walletChallengeKey(req.Service, req.Account, req.Nonce))
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return internal.UnauthorizedError("challenge %s is not found or expired", req.Nonce)
+	}
+
+	message := models.WalletChallengeMessage(req.Service, req.Account, req.Nonce)
+	// Browser wallets wrap message with <Bytes></Bytes> before signing
+	if models.VerifyWalletSignature(req.KeyType, req.Account, publicKey, []byte("<Bytes>"+message+"</Bytes>"), signature) != nil {
+		if err := models.VerifyWalletSignature(req.KeyType, req.Account, publicKey, []byte(message), signature); err != nil {
+			return internal.UnauthorizedError("signature verification failed: %v", err)
+		}
+	}
+
+	token, err := models.GenerateAccessToken()
+	if err != nil {
+		return internal.InternalError(err)
+	}
+	now := time.Now()
+	tokenHash := models.HashApiKey(token)
+	binaryToken, err := proto.Marshal(&models.ApronApiKey{
+		Id:        req.Account,
+		ServiceId: req.Service,
+		AccountId: req.Account,
+		KeyHash:   tokenHash,
+		KeyHint:   models.AccessTokenHint(token),
+		IssuedAt:  now.Unix(),
+		ExpiredAt: now.Add(h.AccessTokenTTL).Unix(),
+	})
+	if err != nil {
+		return internal.InternalError(err)
+	}
+	if err := h.StorageManager.SaveBinaryKeyData(internal.AccessTokenBucketName, tokenHash, binaryToken); err != nil {
+		return err
+	}
+
+	respBody, _ := json.Marshal(WalletTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(h.AccessTokenTTL / time.Second),
+		Account:     req.Account,
+		Service:     req.Service,
+	})
+	ctx.SetContentType("application/json")
+	ctx.SetBody(respBody)
+	return nil
+}
+
+// validateAccessToken checks access token issued by wallet login, the token is only accepted by the service it is issued for
+// if the service still allows access tokens, and requests are counted by the account bound to the token.
+func (h *ProxyHandler) validateAccessToken(c *ProxyContext) error {
+	if !c.Service.AllowAccessTokens {
+		return internal.UnauthorizedError("service %s does not accept access tokens", c.Service.Id)
+	}
+
+	token, err := h.RecordCache.GetAccessToken(models.HashApiKey(c.RequestDetail.ApiKeyStr))
+	if err != nil {
+		if internal.ToGatewayError(err).Code == internal.ErrCodeNotFound {
+			return internal.UnauthorizedError("invalid access token for service %s", c.Service.Id)
+		}
+		return err
+	}
+	if token.ServiceId != c.Service.Id {
+		return internal.UnauthorizedError("access token is not issued for service %s", c.Service.Id)
+	}
+
+	return h.authorizeApiKey(c, &models.ApronApiKey{
+		Id:        token.AccountId,
+		ServiceId: c.Service.Id,
+		AccountId: token.AccountId,
+		ExpiredAt: token.ExpiredAt,
+	})
+}
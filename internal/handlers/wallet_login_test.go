@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/valyala/fasthttp"
+
+	"apron.network/gateway/internal"
+	"apron.network/gateway/internal/models"
+)
+
+func TestWalletLogin(t *testing.T) {
+	storageManager := models.NewMemoryStorageManager()
+	upstream := startEchoUpstream(t) + "/"
+	for _, service := range []*models.ApronService{
+		{Id: "token_service", BaseUrl: upstream, Schema: "http", AllowAccessTokens: true},
+		{Id: "other_token_service", BaseUrl: upstream, Schema: "http", AllowAccessTokens: true},
+		{Id: "key_service", BaseUrl: upstream, Schema: "http"},
+	} {
+		binaryService, _ := proto.Marshal(service)
+		storageManager.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService)
+	}
+	proxy := newTestProxyHandler(t, storageManager)
+
+	post := func(path string, body interface{}) *fasthttp.RequestCtx {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.Header.SetMethod("POST")
+		ctx.Request.SetRequestURI(path)
+		reqBody, _ := json.Marshal(body)
+		ctx.Request.SetBody(reqBody)
+		proxy.InternalHandler(ctx)
+		return ctx
+	}
+
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	account := "0x" + hex.EncodeToString(publicKey)
+
+	// Challenge is only issued for services allowing access tokens
+	for _, service := range []string{"", "key_service", "unknown_service"} {
+		if ctx := post("/auth/challenge", WalletChallengeRequest{Account: account, Service: service}); ctx.Response.StatusCode() != fasthttp.StatusBadRequest {
+			t.Errorf("expected challenge for service %q rejected, got %d", service, ctx.Response.StatusCode())
+		}
+	}
+
+	ctx := post("/auth/challenge", WalletChallengeRequest{Account: account, Service: "token_service"})
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("expected challenge issued, got %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	challenge := WalletChallengeResponse{}
+	json.Unmarshal(ctx.Response.Body(), &challenge)
+	if challenge.Message != models.WalletChallengeMessage("token_service", account, challenge.Nonce) {
+		t.Fatalf("unexpected challenge message %q", challenge.Message)
+	}
+
+	// Challenge can't be used for another service
+	tokenReq := WalletTokenRequest{
+		Account:   account,
+		Service:   "other_token_service",
+		Nonce:     challenge.Nonce,
+		Signature: hex.EncodeToString(ed25519.Sign(privateKey, []byte(challenge.Message))),
+	}
+	if ctx = post("/auth/token", tokenReq); ctx.Response.StatusCode() != fasthttp.StatusUnauthorized {
+		t.Errorf("expected challenge of another service rejected, got %d", ctx.Response.StatusCode())
+	}
+
+	tokenReq = WalletTokenRequest{
+		Account:   account,
+		Service:   "token_service",
+		Nonce:     challenge.Nonce,
+		Signature: hex.EncodeToString(ed25519.Sign(privateKey, []byte("<Bytes>"+challenge.Message+"</Bytes>"))),
+	}
+	ctx = post("/auth/token", tokenReq)
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("expected token issued, got %d %q", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	token := WalletTokenResponse{}
+	json.Unmarshal(ctx.Response.Body(), &token)
+	if token.Account != account || token.Service != "token_service" || token.ExpiresIn != int64(defaultAccessTokenTTL.Seconds()) {
+		t.Errorf("unexpected token response %q", ctx.Response.Body())
+	}
+
+	// Challenge can only be used once
+	ctx = post("/auth/token", tokenReq)
+	if ctx.Response.StatusCode() != fasthttp.StatusUnauthorized {
+		t.Errorf("expected reused challenge rejected, got %d", ctx.Response.StatusCode())
+	}
+
+	// Challenge is consumed by failed attempt as well
+	ctx = post("/auth/challenge", WalletChallengeRequest{Account: account, Service: "token_service"})
+	json.Unmarshal(ctx.Response.Body(), &challenge)
+	ctx = post("/auth/token", WalletTokenRequest{Account: account, Service: "token_service", Nonce: challenge.Nonce, Signature: hex.EncodeToString(make([]byte, 64))})
+	if ctx.Response.StatusCode() != fasthttp.StatusUnauthorized {
+		t.Errorf("expected invalid signature rejected, got %d", ctx.Response.StatusCode())
+	}
+	tokenReq.Nonce = challenge.Nonce
+	tokenReq.Signature = hex.EncodeToString(ed25519.Sign(privateKey, []byte(challenge.Message)))
+	if ctx = post("/auth/token", tokenReq); ctx.Response.StatusCode() != fasthttp.StatusUnauthorized {
+		t.Errorf("expected consumed challenge rejected, got %d", ctx.Response.StatusCode())
+	}
+
+	testCases := []struct {
+		desc    string
+		service string
+		token   string
+		status  int
+	}{
+		{"allowed", "token_service", token.AccessToken, fasthttp.StatusOK},
+		{"issued for another service", "other_token_service", token.AccessToken, fasthttp.StatusUnauthorized},
+		{"not allowed by service", "key_service", token.AccessToken, fasthttp.StatusUnauthorized},
+		{"unknown token", "token_service", "apron_at_unknown", fasthttp.StatusUnauthorized},
+	}
+	for _, tc := range testCases {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.SetRequestURI("/v1/" + tc.service + "/anything")
+		ctx.Request.Header.Set("Authorization", "Bearer "+tc.token)
+		proxy.InternalHandler(ctx)
+		if ctx.Response.StatusCode() != tc.status {
+			t.Errorf("%s: expected status %d, got %d %q", tc.desc, tc.status, ctx.Response.StatusCode(), ctx.Response.Body())
+		}
+	}
+
+	// Requests with access token are counted by account
+	if _, err := proxy.AggrAccessRecordManager.ExportUsage("token_service", account); err != nil {
+		t.Errorf("expected request counted for account, got %v", err)
+	}
+
+	// Outstanding challenges of an account are limited
+	status := 0
+	for i := 0; i < maxWalletChallenges && status != fasthttp.StatusTooManyRequests; i++ {
+		status = post("/auth/challenge", WalletChallengeRequest{Account: account, Service: "token_service"}).Response.StatusCode()
+	}
+	if status != fasthttp.StatusTooManyRequests {
+		t.Errorf("expected challenges of account limited, got %d", status)
+	}
+
+	// Wallet login requests from an IP are limited
+	for i := 0; i < walletLoginRateLimit.Max; i++ {
+		post("/auth/token", WalletTokenRequest{})
+	}
+	if ctx := post("/auth/token", WalletTokenRequest{}); ctx.Response.StatusCode() != fasthttp.StatusTooManyRequests || len(ctx.Response.Header.Peek("Retry-After")) == 0 {
+		t.Errorf("expected requests from ip limited, got %d", ctx.Response.StatusCode())
+	}
+}
@@ -0,0 +1,45 @@
+package models
+
+import "errors"
+
+// Roles of admin tokens
+const (
+	// AdminRoleSuperadmin can access all admin API, including token management
+	AdminRoleSuperadmin = "superadmin"
+	// AdminRoleProvider can only manage services owned by the account of token
+	AdminRoleProvider = "provider"
+	// AdminRoleAuditor has read-only access to all services, keys and reports
+	AdminRoleAuditor = "auditor"
+)
+
+// AdminTokenPrefix distinguishes admin tokens from api keys, so they won't be mixed up by mistake
+const AdminTokenPrefix = "apron_adm_"
+
+// GenerateAdminToken returns a new admin token, which has the same format with api key except the prefix
+func GenerateAdminToken() (string, error) {
+	return generatePrefixedSecret(AdminTokenPrefix)
+}
+
+// IsWellFormedAdminToken checks prefix, length and checksum of token generated by GenerateAdminToken
+func IsWellFormedAdminToken(token string) bool {
+	return isWellFormedPrefixedSecret(AdminTokenPrefix, token)
+}
+
+// AdminTokenHint returns the non-secret beginning of admin token
+func AdminTokenHint(token string) string {
+	return prefixedSecretHint(AdminTokenPrefix, token)
+}
+
+// ValidateAdminRole checks role of admin token, and provider tokens should be bound to an account
+func ValidateAdminRole(role, account string) error {
+	switch role {
+	case AdminRoleSuperadmin, AdminRoleAuditor:
+		return nil
+	case AdminRoleProvider:
+		if account == "" {
+			return errors.New("account is required for provider token")
+		}
+		return nil
+	}
+	return errors.New("role should be superadmin, provider or auditor")
+}
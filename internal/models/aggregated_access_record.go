@@ -27,31 +27,31 @@ func (r *AggregatedAccessRecord) Reset(startTime time.Time) {
 	r.Usage = 0
 }
 
-func (r *AggregatedAccessRecord) ExportStrAndFlush() string {
+// ExportStrAndFlush returns record as JSON and resets usage, the usage is kept if marshalling failed
+func (r *AggregatedAccessRecord) ExportStrAndFlush() (string, error) {
 	currentTime := time.Now().UTC()
 
 	r.EndTime = uint64(currentTime.Unix())
 
 	strData, err := json.Marshal(r)
-	internal.CheckError(err)
+	if err != nil {
+		return "", internal.InternalError(err)
+	}
 
 	r.Reset(currentTime)
 
-	return string(strData)
+	return string(strData), nil
 }
 
+// ExportObjectAndFlush returns a copy of record and resets usage
 func (r *AggregatedAccessRecord) ExportObjectAndFlush() *AggregatedAccessRecord {
 	currentTime := time.Now().UTC()
 
 	r.EndTime = uint64(currentTime.Unix())
-	rslt := &AggregatedAccessRecord{}
-	tmpData, err := json.Marshal(r)
-	internal.CheckError(err)
+	rslt := *r
 
 	r.Reset(currentTime)
-	err = json.Unmarshal(tmpData, rslt)
-	internal.CheckError(err)
-	return rslt
+	return &rslt
 }
 
 func AccessRecordStorageKeyFrom(serviceUuid, userKey string) string {
@@ -1,34 +1,31 @@
 package models
 
 import (
-	"errors"
-	"fmt"
 	"sync"
 	"time"
+
+	"apron.network/gateway/internal"
 )
 
 type AggregatedAccessRecordManager struct {
 	records map[string]*AggregatedAccessRecord
-	locks   map[string]*sync.Mutex
+	lock    sync.Mutex
 }
 
 func (m *AggregatedAccessRecordManager) Init() {
 	m.records = make(map[string]*AggregatedAccessRecord)
-	m.locks = make(map[string]*sync.Mutex)
 }
 
 func (m *AggregatedAccessRecordManager) IncUsage(serviceId, userKey string) {
 	recordKey := AccessRecordStorageKeyFrom(serviceId, userKey)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	rcd, ok := m.records[recordKey]
 	if ok {
-		m.locks[recordKey].Lock()
-		defer m.locks[recordKey].Unlock()
 		rcd.Usage++
 	} else {
-		m.locks[recordKey] = &sync.Mutex{}
-		m.locks[recordKey].Lock()
-		defer m.locks[recordKey].Unlock()
-
 		currentTs := uint64(time.Now().UTC().Unix())
 		m.records[recordKey] = &AggregatedAccessRecord{
 			Id:          currentTs,
@@ -45,15 +42,22 @@ func (m *AggregatedAccessRecordManager) IncUsage(serviceId, userKey string) {
 
 func (m *AggregatedAccessRecordManager) ExportUsage(serviceId, userKey string) (string, error) {
 	recordKey := AccessRecordStorageKeyFrom(serviceId, userKey)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	rcd, ok := m.records[recordKey]
 	if ok {
-		return rcd.ExportStrAndFlush(), nil
+		return rcd.ExportStrAndFlush()
 	} else {
-		return "", errors.New(fmt.Sprintf("no record found for service %s and user %s", serviceId, userKey))
+		return "", internal.NotFoundError("no record found for service %s and user %s", serviceId, userKey)
 	}
 }
 
 func (m *AggregatedAccessRecordManager) ExportAllUsage() ([]*AggregatedAccessRecord, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	rslt := make([]*AggregatedAccessRecord, len(m.records))
 	i := 0
 	for _, r := range m.records {
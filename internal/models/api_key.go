@@ -0,0 +1,94 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/crc32"
+	"math/big"
+	"strings"
+)
+
+const (
+	// ApiKeyPrefix makes gateway keys recognizable, such as by secret scanners
+	ApiKeyPrefix = "apron_"
+
+	apiKeySecretLength   = 32
+	apiKeyChecksumLength = 6
+	apiKeyHintLength     = len(ApiKeyPrefix) + 4
+	base62Alphabet       = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// GenerateApiKey returns a new key in format apron_<32 random base62 chars><6 chars crc32 checksum>,
+// the checksum allows rejecting mistyped or forged keys without accessing storage.
+func GenerateApiKey() (string, error) {
+	return generatePrefixedSecret(ApiKeyPrefix)
+}
+
+// generatePrefixedSecret returns prefix followed by random base62 chars and checksum,
+// which is the format shared by api keys and tokens issued by gateway
+func generatePrefixedSecret(prefix string) (string, error) {
+	secret := make([]byte, apiKeySecretLength)
+	max := big.NewInt(int64(len(base62Alphabet)))
+	for i := range secret {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		secret[i] = base62Alphabet[n.Int64()]
+	}
+
+	body := prefix + string(secret)
+	return body + apiKeyChecksum(body), nil
+}
+
+func apiKeyChecksum(body string) string {
+	sum := uint64(crc32.ChecksumIEEE([]byte(body)))
+	checksum := make([]byte, apiKeyChecksumLength)
+	for i := apiKeyChecksumLength - 1; i >= 0; i-- {
+		checksum[i] = base62Alphabet[sum%62]
+		sum /= 62
+	}
+	return string(checksum)
+}
+
+// IsWellFormedApiKey checks prefix, length and checksum of key generated by GenerateApiKey
+func IsWellFormedApiKey(key string) bool {
+	return isWellFormedPrefixedSecret(ApiKeyPrefix, key)
+}
+
+func isWellFormedPrefixedSecret(prefix, key string) bool {
+	if !strings.HasPrefix(key, prefix) || len(key) != len(prefix)+apiKeySecretLength+apiKeyChecksumLength {
+		return false
+	}
+	body := key[:len(key)-apiKeyChecksumLength]
+	return apiKeyChecksum(body) == key[len(body):]
+}
+
+// HashApiKey returns hex encoded sha256 of key, which is used as record key in ApronApiKey:<service_id>.
+// Keys are random with high entropy, so salt is not required.
+func HashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ApiKeyHint returns the non-secret beginning of key, which helps users to recognize the key
+func ApiKeyHint(key string) string {
+	return prefixedSecretHint(ApiKeyPrefix, key)
+}
+
+func prefixedSecretHint(prefix, key string) string {
+	hintLength := len(prefix) + apiKeyHintLength - len(ApiKeyPrefix)
+	if len(key) <= hintLength {
+		return key
+	}
+	return key[:hintLength] + "..."
+}
+
+// ApiKeyId returns id of key, legacy keys issued before hashed keys use plaintext key as id until migrated
+func ApiKeyId(apiKey *ApronApiKey) string {
+	if apiKey.Id == "" {
+		return apiKey.Key
+	}
+	return apiKey.Id
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"fmt"
+)
+
+// Locations where api key can be passed in proxy request
+const (
+	// ApiKeyLocationPath passes key as the first segment after service name, such as /v1/<service>/<key>/anything
+	ApiKeyLocationPath = "path"
+	// ApiKeyLocationBearer passes key in Authorization: Bearer <key> header
+	ApiKeyLocationBearer = "bearer"
+	// ApiKeyLocationHeader passes key in X-Apron-Key header
+	ApiKeyLocationHeader = "header"
+	// ApiKeyLocationQuery passes key in query param, the param name is declared in service
+	ApiKeyLocationQuery = "query"
+
+	ApiKeyHeaderName        = "X-Apron-Key"
+	DefaultApiKeyQueryParam = "api_key"
+)
+
+// DefaultApiKeyLocations are used if service declares no key location, path is kept for compatibility
+var DefaultApiKeyLocations = []string{ApiKeyLocationBearer, ApiKeyLocationHeader, ApiKeyLocationPath}
+
+// ApiKeyLocations returns key locations accepted by service in the order of checking
+func ApiKeyLocations(service *ApronService) []string {
+	if len(service.KeyLocations) == 0 {
+		return DefaultApiKeyLocations
+	}
+	return service.KeyLocations
+}
+
+// ApiKeyQueryParam returns query param name of key declared in service
+func ApiKeyQueryParam(service *ApronService) string {
+	if service.KeyQueryParam == "" {
+		return DefaultApiKeyQueryParam
+	}
+	return service.KeyQueryParam
+}
+
+// ValidateApiKeyLocations checks whether all locations are supported
+func ValidateApiKeyLocations(locations []string) error {
+	for _, l := range locations {
+		switch l {
+		case ApiKeyLocationPath, ApiKeyLocationBearer, ApiKeyLocationHeader, ApiKeyLocationQuery:
+		default:
+			return fmt.Errorf("unsupported key location %s, should be one of path, bearer, header and query", l)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// parseAllowedIp parses CIDR or single IP, which is treated as CIDR with full mask
+func parseAllowedIp(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid ip %q", s)
+		}
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 8 * net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, ipNet, err := net.ParseCIDR(s)
+	return ipNet, err
+}
+
+// ValidateClientRestrictions checks allowed ips, origins and referers of the key
+func ValidateClientRestrictions(apiKey *ApronApiKey) error {
+	for _, s := range apiKey.AllowedIps {
+		if _, err := parseAllowedIp(s); err != nil {
+			return fmt.Errorf("invalid allowed ip %q: %v", s, err)
+		}
+	}
+	for _, p := range append(append([]string{}, apiKey.AllowedOrigins...), apiKey.AllowedReferers...) {
+		if _, err := compilePattern(p); err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", p, err)
+		}
+	}
+	return nil
+}
+
+// MatchAllowedOrigin checks whether origin is allowed by the key, and any origin is allowed if not restricted
+func MatchAllowedOrigin(apiKey *ApronApiKey, origin string) bool {
+	if len(apiKey.AllowedOrigins) == 0 {
+		return true
+	}
+	return origin != "" && matchAnyPattern(apiKey.AllowedOrigins, origin)
+}
+
+// CheckClientRestrictions checks source ip, Origin and Referer of request with restrictions declared in key.
+// Origin and Referer headers are required if restricted, since scraped keys are mostly used without them.
+func CheckClientRestrictions(apiKey *ApronApiKey, ip net.IP, origin, referer string) error {
+	if len(apiKey.AllowedIps) > 0 {
+		allowed := false
+		for _, s := range apiKey.AllowedIps {
+			if ipNet, err := parseAllowedIp(s); err == nil && ipNet.Contains(ip) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("source ip %s is not allowed to use the key", ip)
+		}
+	}
+	if !MatchAllowedOrigin(apiKey, origin) {
+		return fmt.Errorf("origin %q is not allowed to use the key", origin)
+	}
+	if len(apiKey.AllowedReferers) > 0 && (referer == "" || !matchAnyPattern(apiKey.AllowedReferers, referer)) {
+		return fmt.Errorf("referer %q is not allowed to use the key", referer)
+	}
+	return nil
+}
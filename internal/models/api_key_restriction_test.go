@@ -0,0 +1,47 @@
+package models
+
+import (
+	"net"
+	"testing"
+)
+
+func TestCheckClientRestrictions(t *testing.T) {
+	apiKey := &ApronApiKey{
+		AllowedIps:      []string{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32"},
+		AllowedOrigins:  []string{"https://*.example.com", "https://dapp.io"},
+		AllowedReferers: []string{"https://*.example.com/**", "re:^https://dapp\\.io/"},
+	}
+	if err := ValidateClientRestrictions(apiKey); err != nil {
+		t.Fatalf("expected valid restrictions, got %v", err)
+	}
+
+	testCases := []struct {
+		ip      string
+		origin  string
+		referer string
+		allowed bool
+	}{
+		{"10.1.2.3", "https://app.example.com", "https://app.example.com/page", true},
+		{"192.168.1.1", "https://dapp.io", "https://dapp.io/swap", true},
+		{"2001:db8::1", "https://dapp.io", "https://dapp.io/", true},
+		{"192.168.1.2", "https://dapp.io", "https://dapp.io/", false},
+		{"10.1.2.3", "https://evil.com", "https://dapp.io/", false},
+		{"10.1.2.3", "https://example.com", "https://dapp.io/", false},
+		{"10.1.2.3", "", "https://dapp.io/", false},
+		{"10.1.2.3", "https://dapp.io", "https://evil.com/https://dapp.io/", false},
+		{"10.1.2.3", "https://dapp.io", "", false},
+	}
+	for _, tc := range testCases {
+		err := CheckClientRestrictions(apiKey, net.ParseIP(tc.ip), tc.origin, tc.referer)
+		if (err == nil) != tc.allowed {
+			t.Errorf("%s %s %s: expected allowed %v, got %v", tc.ip, tc.origin, tc.referer, tc.allowed, err)
+		}
+	}
+
+	if err := CheckClientRestrictions(&ApronApiKey{}, net.ParseIP("1.2.3.4"), "", ""); err != nil {
+		t.Errorf("expected key without restrictions allowed, got %v", err)
+	}
+	if err := ValidateClientRestrictions(&ApronApiKey{AllowedIps: []string{"10.0.0.0/33"}}); err == nil {
+		t.Errorf("expected invalid cidr rejected")
+	}
+}
@@ -0,0 +1,205 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// PatternRegexPrefix marks the pattern as regex instead of glob
+const PatternRegexPrefix = "re:"
+
+// compiledPatterns caches regexes compiled from patterns, since patterns are checked in every request
+var compiledPatterns sync.Map
+
+// compilePattern converts glob or regex pattern to regex, in glob * matches one path segment while ** matches any path
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := compiledPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	var expr string
+	if strings.HasPrefix(pattern, PatternRegexPrefix) {
+		expr = strings.TrimPrefix(pattern, PatternRegexPrefix)
+	} else {
+		glob := pattern
+		sb := strings.Builder{}
+		sb.WriteString("^")
+		for i := 0; i < len(glob); i++ {
+			switch {
+			case strings.HasPrefix(glob[i:], "**"):
+				sb.WriteString(".*")
+				i++
+			case glob[i] == '*':
+				sb.WriteString("[^/]*")
+			case glob[i] == '?':
+				sb.WriteString("[^/]")
+			default:
+				sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			}
+		}
+		sb.WriteString("$")
+		expr = sb.String()
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	compiledPatterns.Store(pattern, re)
+	return re, nil
+}
+
+// matchAnyPattern checks whether s matches any of patterns, invalid patterns are ignored
+func matchAnyPattern(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if re, err := compilePattern(p); err == nil && re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileScopePath compiles scope path, the leading slash is optional in both path and pattern
+func compileScopePath(pattern string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(pattern, PatternRegexPrefix) {
+		return compilePattern(pattern)
+	}
+	return compilePattern(strings.TrimPrefix(pattern, "/"))
+}
+
+// ValidateApiKeyScopes checks whether methods and paths in scopes are valid
+func ValidateApiKeyScopes(scopes []*ApiKeyScope) error {
+	for i, scope := range scopes {
+		if scope == nil {
+			return fmt.Errorf("scope %d is empty", i)
+		}
+		for _, method := range scope.Methods {
+			if method == "" || strings.ToUpper(method) != method {
+				return fmt.Errorf("invalid method %q in scope %d, should be upper case such as GET", method, i)
+			}
+		}
+		for _, path := range scope.Paths {
+			if _, err := compileScopePath(path); err != nil {
+				return fmt.Errorf("invalid path %q in scope %d: %v", path, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *ApiKeyScope) allowMethod(method string) bool {
+	if len(s.Methods) == 0 {
+		return true
+	}
+	for _, m := range s.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ApiKeyScope) allowPath(path string) bool {
+	if len(s.Paths) == 0 {
+		return true
+	}
+	path = strings.TrimPrefix(path, "/")
+	for _, p := range s.Paths {
+		if re, err := compileScopePath(p); err == nil && re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchApiKeyScopes returns scopes allowing the method and path, and the error describes the violated scope if none matched.
+// Nil is returned without error if no scope declared in key.
+func MatchApiKeyScopes(scopes []*ApiKeyScope, method, path string) ([]*ApiKeyScope, error) {
+	if len(scopes) == 0 {
+		return nil, nil
+	}
+
+	methodAllowed := false
+	matched := []*ApiKeyScope{}
+	for _, scope := range scopes {
+		if !scope.allowMethod(method) {
+			continue
+		}
+		methodAllowed = true
+		if scope.allowPath(path) {
+			matched = append(matched, scope)
+		}
+	}
+
+	if !methodAllowed {
+		return nil, fmt.Errorf("method %s is not allowed by key scopes", method)
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("path /%s is not allowed for method %s by key scopes", strings.TrimPrefix(path, "/"), method)
+	}
+	return matched, nil
+}
+
+// WsMessageTypes returns types of websocket message, which is method of JSON-RPC message or type field of JSON message.
+// Types of all messages in batch are returned, and nil is returned if the message is not JSON.
+func WsMessageTypes(msg []byte) []string {
+	type typedMessage struct {
+		Method string `json:"method"`
+		Type   string `json:"type"`
+	}
+
+	messages := []typedMessage{}
+	if err := json.Unmarshal(msg, &messages); err != nil {
+		m := typedMessage{}
+		if err := json.Unmarshal(msg, &m); err != nil {
+			return nil
+		}
+		messages = append(messages, m)
+	}
+
+	types := make([]string, 0, len(messages))
+	for _, m := range messages {
+		if m.Method != "" {
+			types = append(types, m.Method)
+		} else {
+			types = append(types, m.Type)
+		}
+	}
+	return types
+}
+
+// CheckWsMessageScopes checks whether message is allowed by any of matched scopes
+func CheckWsMessageScopes(scopes []*ApiKeyScope, msg []byte) error {
+	restricted := false
+	for _, scope := range scopes {
+		if len(scope.WsMessageTypes) == 0 {
+			return nil
+		}
+		restricted = true
+	}
+	if !restricted {
+		return nil
+	}
+
+	types := WsMessageTypes(msg)
+	if len(types) == 0 {
+		return fmt.Errorf("message without type is not allowed by key scopes")
+	}
+	for _, t := range types {
+		allowed := false
+		for _, scope := range scopes {
+			for _, allowedType := range scope.WsMessageTypes {
+				if allowedType == t {
+					allowed = true
+				}
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("message type %q is not allowed by key scopes", t)
+		}
+	}
+	return nil
+}
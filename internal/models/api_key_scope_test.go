@@ -0,0 +1,80 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestMatchApiKeyScopes(t *testing.T) {
+	scopes := []*ApiKeyScope{
+		{Methods: []string{"GET", "HEAD"}},
+		{Methods: []string{"POST"}, Paths: []string{"/users/*/profile", "orders/**", `re:^items/\d+$`}},
+	}
+
+	testCases := []struct {
+		method  string
+		path    string
+		matched int
+		allowed bool
+	}{
+		{"GET", "anything/foo", 1, true},
+		{"HEAD", "", 1, true},
+		{"POST", "users/alice/profile", 1, true},
+		{"POST", "users/alice/bob/profile", 0, false},
+		{"POST", "orders/1/items/2", 1, true},
+		{"POST", "items/42", 1, true},
+		{"POST", "items/abc", 0, false},
+		{"DELETE", "users/alice/profile", 0, false},
+	}
+
+	for _, tc := range testCases {
+		matched, err := MatchApiKeyScopes(scopes, tc.method, tc.path)
+		if (err == nil) != tc.allowed || len(matched) != tc.matched {
+			t.Errorf("%s %s: expected allowed %v with %d scopes, got %d scopes, err %v", tc.method, tc.path, tc.allowed, tc.matched, len(matched), err)
+		}
+	}
+
+	if matched, err := MatchApiKeyScopes(nil, "DELETE", "anything"); err != nil || matched != nil {
+		t.Errorf("expected key without scopes unrestricted, got %v, %v", matched, err)
+	}
+}
+
+func TestValidateApiKeyScopes(t *testing.T) {
+	invalidScopes := [][]*ApiKeyScope{
+		{nil},
+		{{Methods: []string{"get"}}},
+		{{Paths: []string{"re:(unclosed"}}},
+	}
+	for _, scopes := range invalidScopes {
+		if err := ValidateApiKeyScopes(scopes); err == nil {
+			t.Errorf("expected %v to be invalid", scopes)
+		}
+	}
+	if err := ValidateApiKeyScopes([]*ApiKeyScope{{Methods: []string{"GET"}, Paths: []string{"users/**"}}}); err != nil {
+		t.Errorf("expected valid scopes, got %v", err)
+	}
+}
+
+func TestCheckWsMessageScopes(t *testing.T) {
+	scopes := []*ApiKeyScope{{WsMessageTypes: []string{"chain_getBlock", "subscribe"}}}
+
+	testCases := []struct {
+		msg     string
+		allowed bool
+	}{
+		{`{"jsonrpc": "2.0", "id": 1, "method": "chain_getBlock", "params": []}`, true},
+		{`{"type": "subscribe", "channel": "blocks"}`, true},
+		{`[{"id": 1, "method": "chain_getBlock"}, {"id": 2, "method": "author_submitExtrinsic"}]`, false},
+		{`{"jsonrpc": "2.0", "id": 1, "method": "author_submitExtrinsic"}`, false},
+		{`not json`, false},
+	}
+	for _, tc := range testCases {
+		if err := CheckWsMessageScopes(scopes, []byte(tc.msg)); (err == nil) != tc.allowed {
+			t.Errorf("%s: expected allowed %v, got %v", tc.msg, tc.allowed, err)
+		}
+	}
+
+	// Scopes without message types allow all messages
+	if err := CheckWsMessageScopes([]*ApiKeyScope{{Methods: []string{"GET"}}}, []byte("not json")); err != nil {
+		t.Errorf("expected unrestricted messages, got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateApiKey(t *testing.T) {
+	key1, err := GenerateApiKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	key2, _ := GenerateApiKey()
+	if key1 == key2 {
+		t.Errorf("expected random keys, got %s twice", key1)
+	}
+	if !strings.HasPrefix(key1, ApiKeyPrefix) || !IsWellFormedApiKey(key1) {
+		t.Errorf("expected well formed key, got %s", key1)
+	}
+
+	// Mistyped key fails checksum
+	mistyped := []byte(key1)
+	if mistyped[10] == 'a' {
+		mistyped[10] = 'b'
+	} else {
+		mistyped[10] = 'a'
+	}
+	for _, k := range []string{string(mistyped), key1[:len(key1)-1], "test_account_id", strings.Replace(key1, ApiKeyPrefix, "other_", 1)} {
+		if IsWellFormedApiKey(k) {
+			t.Errorf("expected %s not well formed", k)
+		}
+	}
+
+	if HashApiKey(key1) == HashApiKey(key2) || len(HashApiKey(key1)) != 64 {
+		t.Errorf("unexpected hash %s", HashApiKey(key1))
+	}
+	if hint := ApiKeyHint(key1); hint != key1[:10]+"..." {
+		t.Errorf("unexpected hint %s", hint)
+	}
+}
@@ -0,0 +1,220 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"apron.network/gateway/internal"
+)
+
+const (
+	// auditLogHeadKey saves sequence number of the last entry in audit log bucket
+	auditLogHeadKey = "head"
+	// auditClaimTTL is the max duration between claiming a sequence number and saving the entry,
+	// the number can be claimed again afterwards in case the claiming node crashed
+	auditClaimTTL = 10 * time.Second
+	// auditAppendRetries and auditAppendRetryInterval limit waiting for entries being appended by other nodes
+	auditAppendRetries       = 100
+	auditAppendRetryInterval = 10 * time.Millisecond
+)
+
+// AuditEntryHash returns hash of entry, fields are length prefixed so values can't be shifted between fields
+func AuditEntryHash(entry *AuditEntry) string {
+	h := sha256.New()
+	for _, field := range []string{
+		strconv.FormatUint(entry.Seq, 10),
+		strconv.FormatInt(entry.Timestamp, 10),
+		entry.Actor,
+		entry.ActorRole,
+		entry.ActorIp,
+		entry.Action,
+		entry.Target,
+		entry.Before,
+		entry.After,
+		entry.PrevHash,
+	} {
+		fmt.Fprintf(h, "%d:%s\n", len(field), field)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// AuditVerifyResult is the result of verifying hash chain of audit log
+type AuditVerifyResult struct {
+	Valid    bool   `json:"valid"`
+	HeadSeq  uint64 `json:"head_seq"`
+	HeadHash string `json:"head_hash"`
+	// Sequence number of the first broken entry, and the reason
+	BrokenSeq uint64 `json:"broken_seq,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// AuditLog appends entries hash-chained to the previous entry. Gateway nodes sharing storage can append concurrently,
+// since the sequence number of next entry is claimed in storage before the entry is saved.
+type AuditLog struct {
+	StorageManager StorageManager
+
+	lock sync.Mutex
+}
+
+func NewAuditLog(storageManager StorageManager) *AuditLog {
+	return &AuditLog{StorageManager: storageManager}
+}
+
+// Append fills sequence number, timestamp and hashes of entry, and saves it after the last entry.
+// The last entry is loaded from storage every time, and appending is retried if another node claimed the next number.
+func (l *AuditLog) Append(entry *AuditEntry) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		head, err := l.loadHead()
+		if err != nil {
+			return err
+		}
+		entry.Seq = 1
+		entry.PrevHash = ""
+		if head != nil {
+			entry.Seq = head.Seq + 1
+			entry.PrevHash = head.Hash
+		}
+
+		claimed, err := l.StorageManager.SaveKeyIfAbsent(internal.AuditLogClaimBucketName, strconv.FormatUint(entry.Seq, 10), auditClaimTTL)
+		if err != nil {
+			return err
+		}
+		if claimed {
+			break
+		}
+		if attempt >= auditAppendRetries {
+			return internal.InternalError(fmt.Errorf("audit entry %d is being appended by another node", entry.Seq))
+		}
+		time.Sleep(auditAppendRetryInterval)
+	}
+
+	entry.Timestamp = time.Now().UnixNano() / int64(time.Millisecond)
+	entry.Hash = AuditEntryHash(entry)
+	binaryEntry, err := proto.Marshal(entry)
+	if err != nil {
+		return internal.InternalError(err)
+	}
+	if err := l.StorageManager.SaveBinaryKeyData(internal.AuditLogBucketName, strconv.FormatUint(entry.Seq, 10), binaryEntry); err != nil {
+		return err
+	}
+	return l.StorageManager.SaveBinaryKeyData(internal.AuditLogBucketName, auditLogHeadKey, []byte(strconv.FormatUint(entry.Seq, 10)))
+}
+
+// loadHead loads the last entry, nil is returned if audit log is empty
+func (l *AuditLog) loadHead() (*AuditEntry, error) {
+	seq, err := l.HeadSeq()
+	if err != nil || seq == 0 {
+		return nil, err
+	}
+	return l.Get(seq)
+}
+
+// HeadSeq returns sequence number of the last entry, 0 if audit log is empty.
+// Saved head may fall behind if the appending node crashed, or nodes saved it out of order,
+// so entries saved after it are counted as well.
+func (l *AuditLog) HeadSeq() (uint64, error) {
+	var seq uint64
+	content, err := l.StorageManager.GetRecord(internal.AuditLogBucketName, auditLogHeadKey)
+	if err == nil {
+		if seq, err = strconv.ParseUint(content, 10, 64); err != nil {
+			return 0, internal.InternalError(fmt.Errorf("invalid audit log head %q", content))
+		}
+	} else if internal.ToGatewayError(err).Code != internal.ErrCodeNotFound {
+		return 0, err
+	}
+
+	for {
+		exists, err := l.StorageManager.IsKeyExistingInBucket(internal.AuditLogBucketName, strconv.FormatUint(seq+1, 10))
+		if err != nil {
+			return 0, err
+		}
+		if !exists {
+			return seq, nil
+		}
+		seq++
+	}
+}
+
+// Get returns entry with sequence number, not found error will be returned if the entry not existing
+func (l *AuditLog) Get(seq uint64) (*AuditEntry, error) {
+	content, err := l.StorageManager.GetRecord(internal.AuditLogBucketName, strconv.FormatUint(seq, 10))
+	if err != nil {
+		if internal.ToGatewayError(err).Code == internal.ErrCodeNotFound {
+			return nil, internal.NotFoundError("audit entry %d not found", seq)
+		}
+		return nil, err
+	}
+	entry := &AuditEntry{}
+	if err := proto.Unmarshal([]byte(content), entry); err != nil {
+		return nil, internal.InternalError(err)
+	}
+	return entry, nil
+}
+
+// Page returns at most count entries starting from sequence number start
+func (l *AuditLog) Page(start uint64, count int) ([]*AuditEntry, error) {
+	headSeq, err := l.HeadSeq()
+	if err != nil {
+		return nil, err
+	}
+	if start == 0 {
+		start = 1
+	}
+
+	entries := make([]*AuditEntry, 0, count)
+	for seq := start; seq <= headSeq && len(entries) < count; seq++ {
+		entry, err := l.Get(seq)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Verify walks through all entries, and checks hash of every entry and the link to previous entry.
+// Removing entries from the tail can't be detected by the chain itself, so head hash should be recorded elsewhere.
+func (l *AuditLog) Verify() (*AuditVerifyResult, error) {
+	headSeq, err := l.HeadSeq()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AuditVerifyResult{Valid: true, HeadSeq: headSeq}
+	prevHash := ""
+	for seq := uint64(1); seq <= headSeq; seq++ {
+		entry, err := l.Get(seq)
+		if err != nil {
+			if internal.ToGatewayError(err).Code != internal.ErrCodeNotFound {
+				return nil, err
+			}
+			result.Valid, result.BrokenSeq, result.Reason = false, seq, "entry is missing"
+			return result, nil
+		}
+
+		switch {
+		case entry.Seq != seq:
+			result.Reason = fmt.Sprintf("entry is saved with sequence number %d", entry.Seq)
+		case entry.PrevHash != prevHash:
+			result.Reason = "previous hash mismatches"
+		case entry.Hash != AuditEntryHash(entry):
+			result.Reason = "entry hash mismatches"
+		}
+		if result.Reason != "" {
+			result.Valid, result.BrokenSeq = false, seq
+			return result, nil
+		}
+		prevHash = entry.Hash
+	}
+	result.HeadHash = prevHash
+	return result, nil
+}
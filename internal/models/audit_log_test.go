@@ -0,0 +1,98 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"apron.network/gateway/internal"
+)
+
+func TestAuditLog(t *testing.T) {
+	storageManager := NewMemoryStorageManager()
+	auditLog := NewAuditLog(storageManager)
+
+	for _, action := range []string{"service.create", "key.create", "key.delete"} {
+		if err := auditLog.Append(&AuditEntry{Actor: "admin", Action: action, Target: "service/test"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := auditLog.Page(2, 10)
+	if err != nil || len(entries) != 2 || entries[0].Seq != 2 || entries[0].PrevHash == "" {
+		t.Fatalf("expected entries 2 and 3, got %v %v", entries, err)
+	}
+
+	result, err := auditLog.Verify()
+	if err != nil || !result.Valid || result.HeadSeq != 3 || result.HeadHash != entries[1].Hash {
+		t.Fatalf("expected valid audit log, got %+v %v", result, err)
+	}
+
+	// Head not updated after entry saved is recovered by a new instance
+	storageManager.SaveBinaryKeyData(internal.AuditLogBucketName, auditLogHeadKey, []byte("2"))
+	auditLog = NewAuditLog(storageManager)
+	if err := auditLog.Append(&AuditEntry{Actor: "admin", Action: "key.update"}); err != nil {
+		t.Fatal(err)
+	}
+	if entry, _ := auditLog.Get(4); entry == nil || entry.PrevHash != entries[1].Hash {
+		t.Errorf("expected entry 4 chained to entry 3, got %v", entry)
+	}
+
+	// Modified entry breaks the chain
+	entry, _ := auditLog.Get(2)
+	entry.Target = "service/other"
+	binaryEntry, _ := proto.Marshal(entry)
+	storageManager.SaveBinaryKeyData(internal.AuditLogBucketName, "2", binaryEntry)
+	if result, _ := auditLog.Verify(); result.Valid || result.BrokenSeq != 2 {
+		t.Errorf("expected entry 2 broken, got %+v", result)
+	}
+
+	// Rehashed entry breaks the link of next entry
+	entry.Hash = AuditEntryHash(entry)
+	binaryEntry, _ = proto.Marshal(entry)
+	storageManager.SaveBinaryKeyData(internal.AuditLogBucketName, "2", binaryEntry)
+	if result, _ := auditLog.Verify(); result.Valid || result.BrokenSeq != 3 {
+		t.Errorf("expected entry 3 broken, got %+v", result)
+	}
+
+	storageManager.DeleteKey(internal.AuditLogBucketName, "1")
+	if result, _ := auditLog.Verify(); result.Valid || result.BrokenSeq != 1 || result.Reason != "entry is missing" {
+		t.Errorf("expected entry 1 missing, got %+v", result)
+	}
+}
+
+func TestAuditLogConcurrentNodes(t *testing.T) {
+	storageManager := NewMemoryStorageManager()
+
+	// Each audit log instance acts as a gateway node sharing the storage
+	var wg sync.WaitGroup
+	for node := 0; node < 4; node++ {
+		auditLog := NewAuditLog(storageManager)
+		wg.Add(1)
+		go func(node int) {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				if err := auditLog.Append(&AuditEntry{Actor: fmt.Sprintf("node-%d", node), Action: "key.create"}); err != nil {
+					t.Error(err)
+				}
+			}
+		}(node)
+	}
+	wg.Wait()
+
+	result, err := NewAuditLog(storageManager).Verify()
+	if err != nil || !result.Valid || result.HeadSeq != 40 {
+		t.Errorf("expected 40 chained entries, got %+v %v", result, err)
+	}
+
+	// Number claimed by another node is not used until the claim expires
+	storageManager.SaveKeyIfAbsent(internal.AuditLogClaimBucketName, "41", time.Minute)
+	if err := NewAuditLog(storageManager).Append(&AuditEntry{Actor: "admin"}); err == nil {
+		t.Errorf("expected claimed number not overwritten")
+	}
+	if _, err := NewAuditLog(storageManager).Get(41); internal.ToGatewayError(err).Code != internal.ErrCodeNotFound {
+		t.Errorf("expected entry 41 not saved, got %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+package models
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"apron.network/gateway/internal"
+)
+
+// BoltStorageManager saves records in a single bolt db file, each table is saved as a bucket.
+// It is used for single node deployment without redis.
+type BoltStorageManager struct {
+	db *bolt.DB
+
+	expiringKeyCount uint64
+}
+
+// NewBoltStorageManager opens or creates the db file located at dbPath
+func NewBoltStorageManager(dbPath string) (*BoltStorageManager, error) {
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, internal.StorageUnavailableError(err)
+	}
+	return &BoltStorageManager{db: db}, nil
+}
+
+func (s *BoltStorageManager) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStorageManager) IsKeyExisting(key string) (bool, error) {
+	existing := false
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(key)); b != nil {
+			k, _ := b.Cursor().First()
+			existing = k != nil
+		}
+		return nil
+	})
+	if err != nil {
+		return false, internal.StorageUnavailableError(err)
+	}
+	return existing, nil
+}
+
+func (s *BoltStorageManager) IsKeyExistingInBucket(table, keyValue string) (bool, error) {
+	existing := false
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(table)); b != nil {
+			existing = b.Get([]byte(keyValue)) != nil
+		}
+		return nil
+	})
+	if err != nil {
+		return false, internal.StorageUnavailableError(err)
+	}
+	return existing, nil
+}
+
+func (s *BoltStorageManager) SaveBinaryKeyData(table, keyVal string, content []byte) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(table))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(keyVal), content)
+	})
+	if err != nil {
+		return internal.StorageUnavailableError(err)
+	}
+	return nil
+}
+
+func (s *BoltStorageManager) DeleteKey(table, keyVal string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(table)); b != nil {
+			return b.Delete([]byte(keyVal))
+		}
+		return nil
+	})
+	if err != nil {
+		return internal.StorageUnavailableError(err)
+	}
+	return nil
+}
+
+func (s *BoltStorageManager) FetchRecords(table string, startIdx int, pattern string, size int) (map[string]string, uint64, uint, error) {
+	rslt := make(map[string]string)
+	var cursor uint64
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(table))
+		if b == nil {
+			return nil
+		}
+
+		keys := make([]string, 0, b.Stats().KeyN)
+		b.ForEach(func(k, v []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+
+		var pageKeys []string
+		pageKeys, cursor = paginateSortedKeys(keys, startIdx, pattern, size)
+		for _, k := range pageKeys {
+			rslt[k] = string(b.Get([]byte(k)))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, 0, internal.StorageUnavailableError(err)
+	}
+	return rslt, cursor, uint(len(rslt)), nil
+}
+
+func (s *BoltStorageManager) GetRecord(table, key string) (string, error) {
+	var rslt []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(table)); b != nil {
+			// Value returned by bolt is only valid in transaction, so it has to be copied
+			if v := b.Get([]byte(key)); v != nil {
+				rslt = append([]byte{}, v...)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", internal.StorageUnavailableError(err)
+	}
+	if rslt == nil {
+		return "", internal.NotFoundError("record %s not found in %s", key, table)
+	}
+	return string(rslt), nil
+}
+
+func (s *BoltStorageManager) IncrementCounter(table, key string, delta int64) (int64, error) {
+	var value int64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(table))
+		if err != nil {
+			return err
+		}
+		if current := b.Get([]byte(key)); current != nil {
+			if value, err = strconv.ParseInt(string(current), 10, 64); err != nil {
+				return internal.BadRequestError("record %s in %s is not integer", key, table)
+			}
+		}
+		value += delta
+		return b.Put([]byte(key), []byte(strconv.FormatInt(value, 10)))
+	})
+	if gatewayErr, ok := err.(*internal.GatewayError); ok {
+		return 0, gatewayErr
+	} else if err != nil {
+		return 0, internal.StorageUnavailableError(err)
+	}
+	return value, nil
+}
+
+func (s *BoltStorageManager) SaveKeyIfAbsent(table, key string, ttl time.Duration) (bool, error) {
+	saved := false
+	purge := atomic.AddUint64(&s.expiringKeyCount, 1)%expiringKeyPurgeInterval == 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(table))
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if current := b.Get([]byte(key)); current != nil && isExpiringKeyAlive(string(current), now) {
+			return nil
+		}
+		if err := b.Put([]byte(key), []byte(strconv.FormatInt(now.Add(ttl).UnixNano(), 10))); err != nil {
+			return err
+		}
+		saved = true
+
+		if purge {
+			expiredKeys := [][]byte{}
+			b.ForEach(func(k, v []byte) error {
+				if !isExpiringKeyAlive(string(v), now) {
+					expiredKeys = append(expiredKeys, append([]byte{}, k...))
+				}
+				return nil
+			})
+			for _, k := range expiredKeys {
+				if err := b.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, internal.StorageUnavailableError(err)
+	}
+	return saved, nil
+}
+
+func (s *BoltStorageManager) DeleteExpiringKey(table, key string) (bool, error) {
+	deleted := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(table))
+		if b == nil {
+			return nil
+		}
+		current := b.Get([]byte(key))
+		if current == nil {
+			return nil
+		}
+		deleted = isExpiringKeyAlive(string(current), time.Now())
+		return b.Delete([]byte(key))
+	})
+	if err != nil {
+		return false, internal.StorageUnavailableError(err)
+	}
+	return deleted, nil
+}
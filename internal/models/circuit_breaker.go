@@ -0,0 +1,34 @@
+package models
+
+import "fmt"
+
+// CircuitBreakerDisabled disables consecutive_failures or error_rate_percent trip condition, since 0 means default
+const CircuitBreakerDisabled = -1
+
+// ValidateCircuitBreaker checks circuit breaker declared in service, nil breaker means circuits are never tripped
+func ValidateCircuitBreaker(b *CircuitBreaker) error {
+	if b == nil {
+		return nil
+	}
+	if b.ConsecutiveFailures < CircuitBreakerDisabled || b.ErrorRatePercent < CircuitBreakerDisabled {
+		return fmt.Errorf("circuit breaker consecutive_failures and error_rate_percent should be -1 to disable, or not negative")
+	}
+	if b.MinRequests < 0 || b.HalfOpenRequests < 0 {
+		return fmt.Errorf("circuit breaker min_requests and half_open_requests should not be negative")
+	}
+	if b.WindowMs < 0 || b.OpenMs < 0 || b.MaxOpenMs < 0 {
+		return fmt.Errorf("circuit breaker window_ms, open_ms and max_open_ms should not be negative")
+	}
+	if b.ErrorRatePercent > 100 {
+		return fmt.Errorf("circuit breaker percentages should be between 0 and 100")
+	}
+	for _, percent := range []int32{b.OutlierErrorRatePercent, b.MaxEjectionPercent} {
+		if percent < 0 || percent > 100 {
+			return fmt.Errorf("circuit breaker percentages should be between 0 and 100")
+		}
+	}
+	if b.MaxOpenMs != 0 && b.OpenMs > b.MaxOpenMs {
+		return fmt.Errorf("circuit breaker open_ms should not be longer than max_open_ms")
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"fmt"
+)
+
+// EffectiveKeyConcurrencyPolicy returns concurrency policy declared in api key, or key policy of service if key has no policy
+func EffectiveKeyConcurrencyPolicy(service *ApronService, apiKey *ApronApiKey) *ConcurrencyPolicy {
+	if apiKey != nil && apiKey.ConcurrencyPolicy != nil {
+		return apiKey.ConcurrencyPolicy
+	}
+	if service != nil {
+		return service.KeyConcurrencyPolicy
+	}
+	return nil
+}
+
+// ValidateConcurrencyPolicy checks values of policy, nil policy means no limit
+func ValidateConcurrencyPolicy(p *ConcurrencyPolicy) error {
+	if p == nil {
+		return nil
+	}
+	if p.MaxInFlight <= 0 {
+		return fmt.Errorf("concurrency max_in_flight should be positive")
+	}
+	if p.MaxQueue < 0 || p.QueueTimeoutMs < 0 {
+		return fmt.Errorf("concurrency max_queue and queue_timeout_ms should not be negative")
+	}
+	if p.MaxQueue > 0 && p.QueueTimeoutMs == 0 {
+		return fmt.Errorf("concurrency queue_timeout_ms is required if max_queue set")
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Types of active health check
+const (
+	HealthCheckTypeHttp      = "http"
+	HealthCheckTypeWebsocket = "websocket"
+	HealthCheckTypeJsonRpc   = "jsonrpc"
+)
+
+const (
+	defaultHealthCheckInterval           = 10 * time.Second
+	defaultHealthCheckTimeout            = 2 * time.Second
+	defaultHealthCheckExpectedStatus     = 200
+	defaultHealthCheckJsonRpcMethod      = "system_health"
+	defaultHealthCheckHealthyThreshold   = 2
+	defaultHealthCheckUnhealthyThreshold = 3
+)
+
+// HealthCheckType returns type of health check, which is http if not set
+func HealthCheckType(check *HealthCheck) string {
+	if check.Type == "" {
+		return HealthCheckTypeHttp
+	}
+	return check.Type
+}
+
+// HealthCheckInterval returns interval between checks, default is 10s
+func HealthCheckInterval(check *HealthCheck) time.Duration {
+	if check.IntervalMs == 0 {
+		return defaultHealthCheckInterval
+	}
+	return time.Duration(check.IntervalMs) * time.Millisecond
+}
+
+// HealthCheckTimeout returns timeout of each check, default is 2s
+func HealthCheckTimeout(check *HealthCheck) time.Duration {
+	if check.TimeoutMs == 0 {
+		return defaultHealthCheckTimeout
+	}
+	return time.Duration(check.TimeoutMs) * time.Millisecond
+}
+
+// HealthCheckExpectedStatus returns expected status of http check, default is 200
+func HealthCheckExpectedStatus(check *HealthCheck) int {
+	if check.ExpectedStatus == 0 {
+		return defaultHealthCheckExpectedStatus
+	}
+	return int(check.ExpectedStatus)
+}
+
+// HealthCheckJsonRpcMethod returns method called by jsonrpc check, default is system_health
+func HealthCheckJsonRpcMethod(check *HealthCheck) string {
+	if check.JsonrpcMethod == "" {
+		return defaultHealthCheckJsonRpcMethod
+	}
+	return check.JsonrpcMethod
+}
+
+// HealthCheckThresholds returns consecutive successes and failures to change health of target
+func HealthCheckThresholds(check *HealthCheck) (healthy, unhealthy int) {
+	healthy, unhealthy = defaultHealthCheckHealthyThreshold, defaultHealthCheckUnhealthyThreshold
+	if check.HealthyThreshold > 0 {
+		healthy = int(check.HealthyThreshold)
+	}
+	if check.UnhealthyThreshold > 0 {
+		unhealthy = int(check.UnhealthyThreshold)
+	}
+	return healthy, unhealthy
+}
+
+// ValidateHealthCheck checks health check declared in service, nil check means no active health check
+func ValidateHealthCheck(service *ApronService) error {
+	check := service.HealthCheck
+	if check == nil {
+		return nil
+	}
+
+	switch HealthCheckType(check) {
+	case HealthCheckTypeHttp, HealthCheckTypeJsonRpc:
+	case HealthCheckTypeWebsocket:
+		if service.Schema != "ws" && service.Schema != "wss" {
+			return fmt.Errorf("websocket health check requires ws or wss service")
+		}
+	default:
+		return fmt.Errorf("health check type should be http, websocket or jsonrpc")
+	}
+	if check.IntervalMs < 0 || check.TimeoutMs < 0 || check.HealthyThreshold < 0 || check.UnhealthyThreshold < 0 {
+		return fmt.Errorf("health check interval, timeout and thresholds should not be negative")
+	}
+	if HealthCheckTimeout(check) > HealthCheckInterval(check) {
+		return fmt.Errorf("health check timeout should not be longer than interval")
+	}
+	if check.ExpectedStatus != 0 && (check.ExpectedStatus < 100 || check.ExpectedStatus > 599) {
+		return fmt.Errorf("health check expected_status should be a valid http status")
+	}
+	return nil
+}
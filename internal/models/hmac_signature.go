@@ -0,0 +1,109 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Auth modes of api key
+const (
+	// ApiKeyAuthModeBearer sends the key in request, which is the default mode
+	ApiKeyAuthModeBearer = "bearer"
+	// ApiKeyAuthModeHmac signs request with key secret, and the secret is never sent
+	ApiKeyAuthModeHmac = "hmac"
+)
+
+// HmacAuthScheme is the scheme of Authorization header of signed request, such as
+// APRON-HMAC-SHA256 KeyId=<key_id>, Timestamp=<unix_seconds>, Nonce=<nonce>, Signature=<hex_signature>
+const HmacAuthScheme = "APRON-HMAC-SHA256"
+
+// HmacAuthorization contains fields of Authorization header of signed request
+type HmacAuthorization struct {
+	KeyId     string
+	Timestamp int64
+	Nonce     string
+	Signature string
+}
+
+// IsHmacAuthorization checks whether the Authorization header uses hmac scheme
+func IsHmacAuthorization(header string) bool {
+	return strings.HasPrefix(header, HmacAuthScheme+" ")
+}
+
+// ParseHmacAuthorization parses Authorization header of signed request
+func ParseHmacAuthorization(header string) (*HmacAuthorization, error) {
+	if !IsHmacAuthorization(header) {
+		return nil, fmt.Errorf("authorization scheme should be %s", HmacAuthScheme)
+	}
+
+	auth := &HmacAuthorization{}
+	for _, field := range strings.Split(strings.TrimPrefix(header, HmacAuthScheme+" "), ",") {
+		kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid authorization field %q", field)
+		}
+		switch kv[0] {
+		case "KeyId":
+			auth.KeyId = kv[1]
+		case "Timestamp":
+			timestamp, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timestamp %q", kv[1])
+			}
+			auth.Timestamp = timestamp
+		case "Nonce":
+			auth.Nonce = kv[1]
+		case "Signature":
+			auth.Signature = kv[1]
+		}
+	}
+
+	if auth.KeyId == "" || auth.Timestamp == 0 || auth.Signature == "" {
+		return nil, fmt.Errorf("KeyId, Timestamp and Signature are required in authorization")
+	}
+	if len(auth.Nonce) < 8 || len(auth.Nonce) > 128 {
+		return nil, fmt.Errorf("nonce should be 8 to 128 characters")
+	}
+	return auth, nil
+}
+
+// String formats the Authorization header value
+func (a *HmacAuthorization) String() string {
+	return fmt.Sprintf("%s KeyId=%s, Timestamp=%d, Nonce=%s, Signature=%s", HmacAuthScheme, a.KeyId, a.Timestamp, a.Nonce, a.Signature)
+}
+
+// HmacStringToSign builds the string signed by client, which is lines of method, path, query sorted by param name,
+// hex encoded sha256 of body, timestamp and nonce.
+func HmacStringToSign(method, path string, query url.Values, body []byte, timestamp int64, nonce string) string {
+	bodyHash := sha256.Sum256(body)
+	return strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		query.Encode(),
+		hex.EncodeToString(bodyHash[:]),
+		strconv.FormatInt(timestamp, 10),
+		nonce,
+	}, "\n")
+}
+
+// SignHmac returns hex encoded HMAC-SHA256 of stringToSign
+func SignHmac(secret, stringToSign string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(stringToSign))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyHmac checks signature in constant time
+func VerifyHmac(secret, stringToSign, signature string) bool {
+	expected, _ := hex.DecodeString(SignHmac(secret, stringToSign))
+	actual, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(expected, actual)
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestHmacAuthorization(t *testing.T) {
+	query := url.Values{"b": {"2"}, "a": {"1"}}
+	stringToSign := HmacStringToSign("post", "/v1/test_service/anything", query, []byte(`{"foo":"bar"}`), 1617235200, "nonce-123")
+	expected := "POST\n/v1/test_service/anything\na=1&b=2\n7a38bf81f383f69433ad6e900d35b3e2385593f76a7b7ab5d4355b8ba41ee24b\n1617235200\nnonce-123"
+	if stringToSign != expected {
+		t.Errorf("unexpected string to sign %q", stringToSign)
+	}
+
+	auth := &HmacAuthorization{KeyId: "key-id", Timestamp: 1617235200, Nonce: "nonce-123", Signature: SignHmac("secret", stringToSign)}
+	parsed, err := ParseHmacAuthorization(auth.String())
+	if err != nil || *parsed != *auth {
+		t.Fatalf("expected %+v parsed, got %+v, %v", auth, parsed, err)
+	}
+	if !VerifyHmac("secret", stringToSign, parsed.Signature) || VerifyHmac("other", stringToSign, parsed.Signature) {
+		t.Errorf("signature should only be verified with the same secret")
+	}
+
+	for _, header := range []string{
+		"Bearer key",
+		HmacAuthScheme + " KeyId=key-id, Timestamp=1617235200, Signature=abcd",
+		HmacAuthScheme + " KeyId=key-id, Timestamp=now, Nonce=nonce-123, Signature=abcd",
+	} {
+		if _, err := ParseHmacAuthorization(header); err == nil {
+			t.Errorf("expected %q rejected", header)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+
+	"apron.network/gateway/internal"
+)
+
+const invalidationChannelName = "ApronRecordInvalidation"
+
+// InvalidationEvent notifies that record Key in Table has been changed,
+// an empty Key means all records in the table should be invalidated.
+type InvalidationEvent struct {
+	Table string `json:"table"`
+	Key   string `json:"key"`
+}
+
+// InvalidationBus broadcasts record changes to all gateway nodes, so cached records can be refreshed
+type InvalidationBus interface {
+	Publish(evt InvalidationEvent) error
+	Subscribe(handler func(evt InvalidationEvent))
+}
+
+// LocalInvalidationBus delivers events to handlers in the same process,
+// which is used for single node deployment with memory or bolt storage.
+type LocalInvalidationBus struct {
+	handlers []func(evt InvalidationEvent)
+	lock     sync.RWMutex
+}
+
+func NewLocalInvalidationBus() *LocalInvalidationBus {
+	return &LocalInvalidationBus{}
+}
+
+func (b *LocalInvalidationBus) Publish(evt InvalidationEvent) error {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	for _, handler := range b.handlers {
+		handler(evt)
+	}
+	return nil
+}
+
+func (b *LocalInvalidationBus) Subscribe(handler func(evt InvalidationEvent)) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// RedisInvalidationBus delivers events to all gateway nodes connected to the same redis via pub/sub
+type RedisInvalidationBus struct {
+	RedisClient *redis.Client
+
+	handlers []func(evt InvalidationEvent)
+	lock     sync.RWMutex
+	pubsub   *redis.PubSub
+}
+
+// NewRedisInvalidationBus subscribes invalidation channel and starts goroutine dispatching received events
+func NewRedisInvalidationBus(client *redis.Client) *RedisInvalidationBus {
+	b := &RedisInvalidationBus{
+		RedisClient: client,
+		pubsub:      client.Subscribe(internal.Ctx(), invalidationChannelName),
+	}
+
+	// Wait for subscription confirmed, otherwise events published right after may be lost
+	if _, err := b.pubsub.Receive(internal.Ctx()); err != nil {
+		log.Printf("Failed to subscribe invalidation channel: %v\n", err)
+	}
+
+	go func() {
+		for msg := range b.pubsub.Channel() {
+			evt := InvalidationEvent{}
+			if err := json.Unmarshal([]byte(msg.Payload), &evt); err != nil {
+				log.Printf("Invalid invalidation event %s: %v\n", msg.Payload, err)
+				continue
+			}
+
+			b.lock.RLock()
+			for _, handler := range b.handlers {
+				handler(evt)
+			}
+			b.lock.RUnlock()
+		}
+	}()
+
+	return b
+}
+
+func (b *RedisInvalidationBus) Publish(evt InvalidationEvent) error {
+	evtBytes, _ := json.Marshal(evt)
+	if err := b.RedisClient.Publish(internal.Ctx(), invalidationChannelName, evtBytes).Err(); err != nil {
+		return internal.StorageUnavailableError(err)
+	}
+	return nil
+}
+
+func (b *RedisInvalidationBus) Subscribe(handler func(evt InvalidationEvent)) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Close unsubscribes the invalidation channel
+func (b *RedisInvalidationBus) Close() error {
+	return b.pubsub.Close()
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// Auth modes of service
+const (
+	// ServiceAuthModeApiKey authenticates requests with api keys issued by gateway, which is the default mode
+	ServiceAuthModeApiKey = "api_key"
+	// ServiceAuthModeJwt authenticates requests with bearer tokens issued by service provider
+	ServiceAuthModeJwt = "jwt"
+
+	DefaultJwtAccountClaim = "sub"
+	DefaultJwtLeeway       = time.Minute
+	// JwtLeewayDisabled checks exp and nbf without clock skew, since 0 means the default leeway
+	JwtLeewayDisabled = -1
+)
+
+// JwtAccountClaim returns the claim used as account id
+func JwtAccountClaim(config *JwtAuthConfig) string {
+	if config.AccountClaim == "" {
+		return DefaultJwtAccountClaim
+	}
+	return config.AccountClaim
+}
+
+// JwtLeeway returns allowed clock skew while checking exp and nbf
+func JwtLeeway(config *JwtAuthConfig) time.Duration {
+	switch config.LeewaySeconds {
+	case 0:
+		return DefaultJwtLeeway
+	case JwtLeewayDisabled:
+		return 0
+	}
+	return time.Duration(config.LeewaySeconds) * time.Second
+}
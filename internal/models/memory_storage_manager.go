@@ -0,0 +1,141 @@
+package models
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"apron.network/gateway/internal"
+)
+
+// MemoryStorageManager keeps all records in memory, which is used for dev mode and tests.
+// All data will be lost after process exits.
+type MemoryStorageManager struct {
+	tables map[string]map[string]string
+	lock   sync.RWMutex
+
+	expiringKeyCount int
+}
+
+func NewMemoryStorageManager() *MemoryStorageManager {
+	return &MemoryStorageManager{
+		tables: make(map[string]map[string]string),
+	}
+}
+
+func (s *MemoryStorageManager) IsKeyExisting(key string) (bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return len(s.tables[key]) > 0, nil
+}
+
+func (s *MemoryStorageManager) IsKeyExistingInBucket(table, keyValue string) (bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	_, ok := s.tables[table][keyValue]
+	return ok, nil
+}
+
+func (s *MemoryStorageManager) SaveBinaryKeyData(table, keyVal string, content []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.tables[table]; !ok {
+		s.tables[table] = make(map[string]string)
+	}
+	s.tables[table][keyVal] = string(content)
+	return nil
+}
+
+func (s *MemoryStorageManager) DeleteKey(table, keyVal string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.tables[table], keyVal)
+	if len(s.tables[table]) == 0 {
+		delete(s.tables, table)
+	}
+	return nil
+}
+
+func (s *MemoryStorageManager) FetchRecords(table string, startIdx int, pattern string, size int) (map[string]string, uint64, uint, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	keys := make([]string, 0, len(s.tables[table]))
+	for k := range s.tables[table] {
+		keys = append(keys, k)
+	}
+
+	pageKeys, cursor := paginateSortedKeys(keys, startIdx, pattern, size)
+	rslt := make(map[string]string, len(pageKeys))
+	for _, k := range pageKeys {
+		rslt[k] = s.tables[table][k]
+	}
+	return rslt, cursor, uint(len(rslt)), nil
+}
+
+func (s *MemoryStorageManager) GetRecord(table, key string) (string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	rslt, ok := s.tables[table][key]
+	if !ok {
+		return "", internal.NotFoundError("record %s not found in %s", key, table)
+	}
+	return rslt, nil
+}
+
+func (s *MemoryStorageManager) IncrementCounter(table, key string, delta int64) (int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.tables[table]; !ok {
+		s.tables[table] = make(map[string]string)
+	}
+
+	var value int64
+	if current, ok := s.tables[table][key]; ok {
+		var err error
+		if value, err = strconv.ParseInt(current, 10, 64); err != nil {
+			return 0, internal.BadRequestError("record %s in %s is not integer", key, table)
+		}
+	}
+	value += delta
+	s.tables[table][key] = strconv.FormatInt(value, 10)
+	return value, nil
+}
+
+func (s *MemoryStorageManager) SaveKeyIfAbsent(table, key string, ttl time.Duration) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.tables[table]; !ok {
+		s.tables[table] = make(map[string]string)
+	}
+
+	now := time.Now()
+	if current, ok := s.tables[table][key]; ok && isExpiringKeyAlive(current, now) {
+		return false, nil
+	}
+	s.tables[table][key] = strconv.FormatInt(now.Add(ttl).UnixNano(), 10)
+
+	s.expiringKeyCount++
+	if s.expiringKeyCount%expiringKeyPurgeInterval == 0 {
+		for k, v := range s.tables[table] {
+			if !isExpiringKeyAlive(v, now) {
+				delete(s.tables[table], k)
+			}
+		}
+	}
+	return true, nil
+}
+
+func (s *MemoryStorageManager) DeleteExpiringKey(table, key string) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	current, ok := s.tables[table][key]
+	if !ok {
+		return false, nil
+	}
+	delete(s.tables[table], key)
+	if len(s.tables[table]) == 0 {
+		delete(s.tables, table)
+	}
+	return isExpiringKeyAlive(current, time.Now()), nil
+}
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"apron.network/gateway/internal"
+)
+
+const (
+	QuotaPeriodDaily   = "daily"
+	QuotaPeriodMonthly = "monthly"
+
+	QuotaScopeKey     = "key"
+	QuotaScopeAccount = "account"
+)
+
+// EffectiveQuotaPolicy returns quota policy declared in api key, or policy of service if key has no policy
+func EffectiveQuotaPolicy(service *ApronService, apiKey *ApronApiKey) *QuotaPolicy {
+	if apiKey != nil && apiKey.QuotaPolicy != nil {
+		return apiKey.QuotaPolicy
+	}
+	if service != nil {
+		return service.QuotaPolicy
+	}
+	return nil
+}
+
+// ValidateQuotaPolicy checks period, scope and limit values, nil policy means no quota
+func ValidateQuotaPolicy(p *QuotaPolicy) error {
+	if p == nil {
+		return nil
+	}
+	if p.Period != QuotaPeriodDaily && p.Period != QuotaPeriodMonthly {
+		return fmt.Errorf("quota period should be %s or %s", QuotaPeriodDaily, QuotaPeriodMonthly)
+	}
+	if p.Scope != "" && p.Scope != QuotaScopeKey && p.Scope != QuotaScopeAccount {
+		return fmt.Errorf("quota scope should be %s or %s", QuotaScopeKey, QuotaScopeAccount)
+	}
+	if p.Limit <= 0 {
+		return fmt.Errorf("quota limit should be positive")
+	}
+	for _, t := range p.WarningThresholds {
+		if t <= 0 || t >= 100 {
+			return fmt.Errorf("quota warning threshold should be percentage between 1 and 99")
+		}
+	}
+	if p.ExceededStatus != 0 && p.ExceededStatus != 429 && p.ExceededStatus != 402 {
+		return fmt.Errorf("quota exceeded status should be 429 or 402")
+	}
+	return nil
+}
+
+// QuotaPeriod returns start and end time of the calendar period (UTC) containing t
+func QuotaPeriod(period string, t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	if period == QuotaPeriodMonthly {
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	}
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// QuotaStatus is the usage of quota in current period
+type QuotaStatus struct {
+	Period    string    `json:"period"`
+	Scope     string    `json:"scope"`
+	Limit     int64     `json:"limit"`
+	Used      int64     `json:"used"`
+	Remaining int64     `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+	// Warning is the highest warning threshold reached, 0 if no threshold reached
+	Warning  int32 `json:"warning,omitempty"`
+	Exceeded bool  `json:"exceeded"`
+}
+
+// QuotaManager counts usage of quota in storage, so the quota is shared by all gateway nodes and kept after restart.
+// Counters are saved in table ApronQuota:<service_id>, with key <scope>:<key or account>:<period start>.
+type QuotaManager struct {
+	StorageManager StorageManager
+	Now            func() time.Time
+}
+
+func NewQuotaManager(storageManager StorageManager) *QuotaManager {
+	return &QuotaManager{StorageManager: storageManager, Now: time.Now}
+}
+
+func quotaScope(p *QuotaPolicy) string {
+	if p.Scope == "" {
+		return QuotaScopeKey
+	}
+	return p.Scope
+}
+
+// quotaSubject returns who the quota is counted for, and rotated keys share quota with the first key of rotation
+func quotaSubject(p *QuotaPolicy, apiKey *ApronApiKey) string {
+	if quotaScope(p) == QuotaScopeAccount {
+		return QuotaScopeAccount + ":" + apiKey.AccountId
+	}
+	if apiKey.RootKeyId != "" {
+		return QuotaScopeKey + ":" + apiKey.RootKeyId
+	}
+	return QuotaScopeKey + ":" + ApiKeyId(apiKey)
+}
+
+func quotaCounterKey(p *QuotaPolicy, apiKey *ApronApiKey, start time.Time) string {
+	return fmt.Sprintf("%s:%d", quotaSubject(p, apiKey), start.Unix())
+}
+
+func (m *QuotaManager) newStatus(p *QuotaPolicy, used int64, reset time.Time) QuotaStatus {
+	status := QuotaStatus{
+		Period:    p.Period,
+		Scope:     quotaScope(p),
+		Limit:     p.Limit,
+		Used:      used,
+		Remaining: p.Limit - used,
+		Reset:     reset,
+		Exceeded:  used > p.Limit,
+	}
+	if status.Remaining < 0 {
+		status.Remaining = 0
+	}
+
+	thresholds := append([]int32{}, p.WarningThresholds...)
+	sort.Slice(thresholds, func(i, j int) bool { return thresholds[i] > thresholds[j] })
+	for _, t := range thresholds {
+		if used*100 >= p.Limit*int64(t) {
+			status.Warning = t
+			break
+		}
+	}
+	return status
+}
+
+// Consume counts a request in current period, and the request is not counted if quota exhausted
+func (m *QuotaManager) Consume(serviceId string, apiKey *ApronApiKey, p *QuotaPolicy) (QuotaStatus, error) {
+	table := internal.QuotaStorageBucketName(serviceId)
+	start, reset := QuotaPeriod(p.Period, m.Now())
+
+	used, err := m.StorageManager.IncrementCounter(table, quotaCounterKey(p, apiKey, start), 1)
+	if err != nil {
+		return QuotaStatus{}, err
+	}
+	if used == 1 {
+		// Counter of previous period is useless after new period started
+		prevStart, _ := QuotaPeriod(p.Period, start.Add(-time.Second))
+		if err := m.StorageManager.DeleteKey(table, quotaCounterKey(p, apiKey, prevStart)); err != nil {
+			return QuotaStatus{}, err
+		}
+	}
+
+	if used > p.Limit {
+		// Rejected request should not consume quota
+		if used, err = m.StorageManager.IncrementCounter(table, quotaCounterKey(p, apiKey, start), -1); err != nil {
+			return QuotaStatus{}, err
+		}
+		status := m.newStatus(p, used, reset)
+		status.Exceeded = true
+		return status, nil
+	}
+	return m.newStatus(p, used, reset), nil
+}
+
+// GetStatus returns quota usage in current period without counting
+func (m *QuotaManager) GetStatus(serviceId string, apiKey *ApronApiKey, p *QuotaPolicy) (QuotaStatus, error) {
+	start, reset := QuotaPeriod(p.Period, m.Now())
+	rcd, err := m.StorageManager.GetRecord(internal.QuotaStorageBucketName(serviceId), quotaCounterKey(p, apiKey, start))
+	if err != nil {
+		if internal.ToGatewayError(err).Code == internal.ErrCodeNotFound {
+			return m.newStatus(p, 0, reset), nil
+		}
+		return QuotaStatus{}, err
+	}
+
+	used, err := strconv.ParseInt(rcd, 10, 64)
+	if err != nil {
+		return QuotaStatus{}, internal.InternalError(err)
+	}
+	status := m.newStatus(p, used, reset)
+	status.Exceeded = used >= p.Limit
+	return status, nil
+}
+
+// Reset clears usage in current period, which restores the full quota
+func (m *QuotaManager) Reset(serviceId string, apiKey *ApronApiKey, p *QuotaPolicy) error {
+	start, _ := QuotaPeriod(p.Period, m.Now())
+	return m.StorageManager.DeleteKey(internal.QuotaStorageBucketName(serviceId), quotaCounterKey(p, apiKey, start))
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuotaPeriod(t *testing.T) {
+	now := time.Date(2021, 2, 28, 23, 59, 59, 0, time.FixedZone("UTC+8", 8*3600))
+
+	start, reset := QuotaPeriod(QuotaPeriodDaily, now)
+	if !start.Equal(time.Date(2021, 2, 28, 0, 0, 0, 0, time.UTC)) || !reset.Equal(time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected daily period %s - %s", start, reset)
+	}
+	start, reset = QuotaPeriod(QuotaPeriodMonthly, now)
+	if !start.Equal(time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)) || !reset.Equal(time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected monthly period %s - %s", start, reset)
+	}
+}
+
+func TestQuotaManagerConsume(t *testing.T) {
+	now := time.Date(2021, 3, 31, 12, 0, 0, 0, time.UTC)
+	m := NewQuotaManager(NewMemoryStorageManager())
+	m.Now = func() time.Time { return now }
+
+	policy := &QuotaPolicy{Period: QuotaPeriodMonthly, Limit: 4, Scope: QuotaScopeAccount, WarningThresholds: []int32{50, 75}}
+	key1 := &ApronApiKey{Key: "key1", AccountId: "account1"}
+	key2 := &ApronApiKey{Key: "key2", AccountId: "account1"}
+
+	// Keys of the same account share the quota
+	for i, expected := range []struct {
+		key       *ApronApiKey
+		remaining int64
+		warning   int32
+		exceeded  bool
+	}{
+		{key1, 3, 0, false},
+		{key2, 2, 50, false},
+		{key1, 1, 75, false},
+		{key2, 0, 75, false},
+		{key1, 0, 75, true},
+	} {
+		status, err := m.Consume("service1", expected.key, policy)
+		if err != nil {
+			t.Fatalf("failed to consume quota: %v", err)
+		}
+		if status.Remaining != expected.remaining || status.Warning != expected.warning || status.Exceeded != expected.exceeded {
+			t.Errorf("request %d: unexpected status %+v", i, status)
+		}
+		if !status.Reset.Equal(time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("request %d: unexpected reset %s", i, status.Reset)
+		}
+	}
+
+	// Rejected request is not counted
+	if status, err := m.GetStatus("service1", key2, policy); err != nil || status.Used != 4 || !status.Exceeded {
+		t.Errorf("unexpected status %+v, %v", status, err)
+	}
+
+	// Quota restored in next period
+	now = now.AddDate(0, 0, 1)
+	if status, err := m.Consume("service1", key1, policy); err != nil || status.Remaining != 3 {
+		t.Errorf("expected quota restored in next period, got %+v, %v", status, err)
+	}
+
+	if err := m.Reset("service1", key1, policy); err != nil {
+		t.Fatalf("failed to reset quota: %v", err)
+	}
+	if status, err := m.GetStatus("service1", key1, policy); err != nil || status.Used != 0 || status.Remaining != 4 {
+		t.Errorf("expected quota reset, got %+v, %v", status, err)
+	}
+}
+
+func TestValidateQuotaPolicy(t *testing.T) {
+	for _, p := range []*QuotaPolicy{
+		{Period: "weekly", Limit: 1},
+		{Period: QuotaPeriodDaily, Limit: 0},
+		{Period: QuotaPeriodDaily, Limit: 1, Scope: "user"},
+		{Period: QuotaPeriodDaily, Limit: 1, WarningThresholds: []int32{100}},
+		{Period: QuotaPeriodDaily, Limit: 1, ExceededStatus: 403},
+	} {
+		if err := ValidateQuotaPolicy(p); err == nil {
+			t.Errorf("expected error for invalid policy %+v", p)
+		}
+	}
+	if err := ValidateQuotaPolicy(&QuotaPolicy{Period: QuotaPeriodMonthly, Limit: 1000000, ExceededStatus: 402}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,12 @@
+package models
+
+// EffectiveRateLimitPolicies returns policies declared in api key, or policies of service if key has no policy
+func EffectiveRateLimitPolicies(service *ApronService, apiKey *ApronApiKey) []*RateLimitPolicy {
+	if apiKey != nil && len(apiKey.RateLimitPolicies) > 0 {
+		return apiKey.RateLimitPolicies
+	}
+	if service != nil {
+		return service.RateLimitPolicies
+	}
+	return nil
+}
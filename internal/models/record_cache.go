@@ -0,0 +1,199 @@
+package models
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"apron.network/gateway/internal"
+)
+
+const (
+	defaultRecordCacheTTL       = 5 * time.Minute
+	defaultNegativeCacheTTL     = 30 * time.Second
+	defaultMaxNegativeCacheSize = 100000
+)
+
+type recordCacheItem struct {
+	record interface{} // nil means record not existing in storage
+	expire time.Time
+}
+
+// RecordCache keeps in memory snapshot of services, api keys and key id indexes loaded from storage,
+// and also caches not found result for unknown keys, so invalid requests will not hit storage every time.
+// Cached records are invalidated by events from InvalidationBus, and also expire after TTL in case event lost.
+// Records returned are shared by all requests and must not be modified.
+type RecordCache struct {
+	StorageManager       StorageManager
+	TTL                  time.Duration
+	NegativeTTL          time.Duration
+	MaxNegativeCacheSize int
+
+	items         map[string]*recordCacheItem
+	negativeCount int
+	generation    uint64 // Increased on every invalidation, to drop records loaded before invalidation
+	lock          sync.RWMutex
+}
+
+func NewRecordCache(storageManager StorageManager) *RecordCache {
+	return &RecordCache{
+		StorageManager:       storageManager,
+		TTL:                  defaultRecordCacheTTL,
+		NegativeTTL:          defaultNegativeCacheTTL,
+		MaxNegativeCacheSize: defaultMaxNegativeCacheSize,
+		items:                make(map[string]*recordCacheItem),
+	}
+}
+
+func recordCacheKey(table, key string) string {
+	return table + "\x00" + key
+}
+
+// GetService returns service with serviceId, not found error will be returned if the service not existing
+func (c *RecordCache) GetService(serviceId string) (*ApronService, error) {
+	rcd, err := c.get(internal.ServiceBucketName, serviceId, protoDecoder(func() proto.Message { return &ApronService{} }))
+	if err != nil {
+		return nil, err
+	}
+	return rcd.(*ApronService), nil
+}
+
+// GetApiKey returns api key record saved in service key bucket,
+// not found error will be returned if the key not existing
+func (c *RecordCache) GetApiKey(serviceId, key string) (*ApronApiKey, error) {
+	rcd, err := c.get(internal.ServiceApiKeyStorageBucketName(serviceId), key, protoDecoder(func() proto.Message { return &ApronApiKey{} }))
+	if err != nil {
+		return nil, err
+	}
+	return rcd.(*ApronApiKey), nil
+}
+
+// GetApiKeyHash returns hash of key with keyId saved in service key id bucket, which is the record key of GetApiKey,
+// not found error will be returned if the key id not existing
+func (c *RecordCache) GetApiKeyHash(serviceId, keyId string) (string, error) {
+	rcd, err := c.get(internal.ServiceApiKeyIdStorageBucketName(serviceId), keyId, func(content string) (interface{}, error) {
+		return content, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return rcd.(string), nil
+}
+
+// GetAccessToken returns record of access token issued by wallet login with token hash,
+// not found error will be returned if the token not existing
+func (c *RecordCache) GetAccessToken(tokenHash string) (*ApronApiKey, error) {
+	rcd, err := c.get(internal.AccessTokenBucketName, tokenHash, protoDecoder(func() proto.Message { return &ApronApiKey{} }))
+	if err != nil {
+		return nil, err
+	}
+	return rcd.(*ApronApiKey), nil
+}
+
+// Invalidate removes cached record of the event, or all records in table if event key is empty
+func (c *RecordCache) Invalidate(evt InvalidationEvent) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.generation++
+	if evt.Key != "" {
+		c.remove(recordCacheKey(evt.Table, evt.Key))
+		return
+	}
+
+	prefix := recordCacheKey(evt.Table, "")
+	for k := range c.items {
+		if strings.HasPrefix(k, prefix) {
+			c.remove(k)
+		}
+	}
+}
+
+// protoDecoder returns decode func of get for protobuf serialized records
+func protoDecoder(newRecord func() proto.Message) func(content string) (interface{}, error) {
+	return func(content string) (interface{}, error) {
+		rcd := newRecord()
+		if err := proto.Unmarshal([]byte(content), rcd); err != nil {
+			return nil, internal.InternalError(err)
+		}
+		return rcd, nil
+	}
+}
+
+func (c *RecordCache) get(table, key string, decode func(content string) (interface{}, error)) (interface{}, error) {
+	cacheKey := recordCacheKey(table, key)
+
+	c.lock.RLock()
+	item, ok := c.items[cacheKey]
+	generation := c.generation
+	c.lock.RUnlock()
+
+	if ok && item.expire.After(time.Now()) {
+		if item.record == nil {
+			return nil, internal.NotFoundError("record %s not found in %s", key, table)
+		}
+		return item.record, nil
+	}
+
+	content, err := c.StorageManager.GetRecord(table, key)
+	if err != nil {
+		if internal.ToGatewayError(err).Code == internal.ErrCodeNotFound {
+			c.set(cacheKey, nil, generation)
+		}
+		return nil, err
+	}
+
+	rcd, err := decode(content)
+	if err != nil {
+		return nil, err
+	}
+	c.set(cacheKey, rcd, generation)
+	return rcd, nil
+}
+
+func (c *RecordCache) set(cacheKey string, rcd interface{}, generation uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// Record may be changed while loading from storage, do not cache it
+	if generation != c.generation {
+		return
+	}
+
+	c.remove(cacheKey)
+	if rcd == nil {
+		// Negative cache is bounded since unknown keys can be generated by clients without limit
+		if c.negativeCount >= c.MaxNegativeCacheSize {
+			c.purgeExpired()
+			if c.negativeCount >= c.MaxNegativeCacheSize {
+				return
+			}
+		}
+		c.items[cacheKey] = &recordCacheItem{expire: time.Now().Add(c.NegativeTTL)}
+		c.negativeCount++
+	} else {
+		c.items[cacheKey] = &recordCacheItem{record: rcd, expire: time.Now().Add(c.TTL)}
+	}
+}
+
+// remove deletes cached item, lock should be held by caller
+func (c *RecordCache) remove(cacheKey string) {
+	if item, ok := c.items[cacheKey]; ok {
+		if item.record == nil {
+			c.negativeCount--
+		}
+		delete(c.items, cacheKey)
+	}
+}
+
+// purgeExpired deletes all expired items, lock should be held by caller
+func (c *RecordCache) purgeExpired() {
+	now := time.Now()
+	for k, item := range c.items {
+		if item.expire.Before(now) {
+			c.remove(k)
+		}
+	}
+}
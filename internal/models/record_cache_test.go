@@ -0,0 +1,122 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/protobuf/proto"
+
+	"apron.network/gateway/internal"
+)
+
+func saveTestService(t *testing.T, s StorageManager, service *ApronService) {
+	binaryService, _ := proto.Marshal(service)
+	if err := s.SaveBinaryKeyData(internal.ServiceBucketName, service.Id, binaryService); err != nil {
+		t.Fatalf("failed to save service: %v", err)
+	}
+}
+
+func TestRecordCacheInvalidation(t *testing.T) {
+	storageManager := NewMemoryStorageManager()
+	bus := NewLocalInvalidationBus()
+	cache := NewRecordCache(storageManager)
+	bus.Subscribe(cache.Invalidate)
+
+	// Unknown record is cached as not found until invalidated
+	if _, err := cache.GetService("test_service"); internal.ToGatewayError(err).Code != internal.ErrCodeNotFound {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	saveTestService(t, storageManager, &ApronService{Id: "test_service", BaseUrl: "v1"})
+	if _, err := cache.GetService("test_service"); internal.ToGatewayError(err).Code != internal.ErrCodeNotFound {
+		t.Errorf("expected cached not found error, got %v", err)
+	}
+
+	bus.Publish(InvalidationEvent{Table: internal.ServiceBucketName, Key: "test_service"})
+	service, err := cache.GetService("test_service")
+	if err != nil || service.BaseUrl != "v1" {
+		t.Fatalf("expected service loaded after invalidation, got %v, %v", service, err)
+	}
+
+	// Loaded record is served from cache until invalidated
+	saveTestService(t, storageManager, &ApronService{Id: "test_service", BaseUrl: "v2"})
+	if service, _ := cache.GetService("test_service"); service.BaseUrl != "v1" {
+		t.Errorf("expected cached service v1, got %s", service.BaseUrl)
+	}
+
+	bus.Publish(InvalidationEvent{Table: internal.ServiceBucketName})
+	if service, _ := cache.GetService("test_service"); service.BaseUrl != "v2" {
+		t.Errorf("expected service v2 after table invalidated, got %s", service.BaseUrl)
+	}
+}
+
+func TestRecordCacheApiKeyHash(t *testing.T) {
+	storageManager := NewMemoryStorageManager()
+	cache := NewRecordCache(storageManager)
+	idBucketName := internal.ServiceApiKeyIdStorageBucketName("test_service")
+
+	if _, err := cache.GetApiKeyHash("test_service", "key_id"); internal.ToGatewayError(err).Code != internal.ErrCodeNotFound {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	storageManager.SaveBinaryKeyData(idBucketName, "key_id", []byte("key_hash"))
+	cache.Invalidate(InvalidationEvent{Table: idBucketName, Key: "key_id"})
+	if keyHash, err := cache.GetApiKeyHash("test_service", "key_id"); err != nil || keyHash != "key_hash" {
+		t.Fatalf("expected key hash loaded after invalidation, got %q, %v", keyHash, err)
+	}
+
+	// Removed id is served from cache until invalidated
+	storageManager.DeleteKey(idBucketName, "key_id")
+	if keyHash, _ := cache.GetApiKeyHash("test_service", "key_id"); keyHash != "key_hash" {
+		t.Errorf("expected cached key hash, got %q", keyHash)
+	}
+	cache.Invalidate(InvalidationEvent{Table: idBucketName, Key: "key_id"})
+	if _, err := cache.GetApiKeyHash("test_service", "key_id"); internal.ToGatewayError(err).Code != internal.ErrCodeNotFound {
+		t.Errorf("expected not found error after invalidation, got %v", err)
+	}
+}
+
+func TestRecordCacheNegativeCacheBounded(t *testing.T) {
+	cache := NewRecordCache(NewMemoryStorageManager())
+	cache.MaxNegativeCacheSize = 2
+
+	for _, k := range []string{"k1", "k2", "k3"} {
+		cache.GetApiKey("test_service", k)
+	}
+	if cache.negativeCount != 2 || len(cache.items) != 2 {
+		t.Errorf("expected 2 negative cached items, got %d/%d", cache.negativeCount, len(cache.items))
+	}
+}
+
+func TestRedisInvalidationBus(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start redis: %v", err)
+	}
+	defer redisServer.Close()
+
+	// Two buses simulate two gateway nodes
+	publisherBus := NewRedisInvalidationBus(redis.NewClient(&redis.Options{Addr: redisServer.Addr()}))
+	defer publisherBus.Close()
+	subscriberBus := NewRedisInvalidationBus(redis.NewClient(&redis.Options{Addr: redisServer.Addr()}))
+	defer subscriberBus.Close()
+
+	received := make(chan InvalidationEvent, 1)
+	subscriberBus.Subscribe(func(evt InvalidationEvent) {
+		received <- evt
+	})
+
+	expected := InvalidationEvent{Table: internal.ServiceBucketName, Key: "test_service"}
+	if err := publisherBus.Publish(expected); err != nil {
+		t.Fatalf("failed to publish event: %v", err)
+	}
+
+	select {
+	case evt := <-received:
+		if evt != expected {
+			t.Errorf("expected event %+v, got %+v", expected, evt)
+		}
+	case <-time.After(time.Second):
+		t.Error("invalidation event not received")
+	}
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"apron.network/gateway/internal"
+)
+
+// RedisStorageManager saves records in redis hash, table name is used as hash key
+type RedisStorageManager struct {
+	RedisClient *redis.Client
+}
+
+func NewRedisStorageManager(client *redis.Client) *RedisStorageManager {
+	return &RedisStorageManager{RedisClient: client}
+}
+
+func parseHscanResultToObjectMap(rcds []string) (map[string]string, uint, error) {
+	if len(rcds)%2 != 0 {
+		return nil, 0, errors.New("record length should be even number")
+	}
+
+	resultCount := uint(len(rcds) / 2)
+	rslt := make(map[string]string)
+	for idx := 0; idx < len(rcds); idx += 2 {
+		rslt[rcds[idx]] = rcds[idx+1]
+	}
+
+	return rslt, resultCount, nil
+}
+
+func (s *RedisStorageManager) IsKeyExisting(key string) (bool, error) {
+	existing, err := s.RedisClient.Exists(internal.Ctx(), key).Result()
+	if err != nil {
+		return false, internal.StorageUnavailableError(err)
+	}
+	return existing == 1, nil
+}
+
+func (s *RedisStorageManager) IsKeyExistingInBucket(table, keyValue string) (bool, error) {
+	existing, err := s.RedisClient.HExists(internal.Ctx(), table, keyValue).Result()
+	if err != nil {
+		return false, internal.StorageUnavailableError(err)
+	}
+	return existing, nil
+}
+
+func (s *RedisStorageManager) SaveBinaryKeyData(table, keyVal string, content []byte) error {
+	if _, err := s.RedisClient.HSet(internal.Ctx(), table, keyVal, content).Result(); err != nil {
+		return internal.StorageUnavailableError(err)
+	}
+	return nil
+}
+
+func (s *RedisStorageManager) DeleteKey(table, keyVal string) error {
+	if _, err := s.RedisClient.HDel(internal.Ctx(), table, keyVal).Result(); err != nil {
+		return internal.StorageUnavailableError(err)
+	}
+	return nil
+}
+
+func (s *RedisStorageManager) FetchRecords(table string, startIdx int, pattern string, size int) (map[string]string, uint64, uint, error) {
+	rcds, cursor, err := s.RedisClient.HScan(internal.Ctx(), table, uint64(startIdx), pattern, int64(size)).Result()
+	if err != nil {
+		return nil, 0, 0, internal.StorageUnavailableError(err)
+	}
+	scanResultMap, resultCount, err := parseHscanResultToObjectMap(rcds)
+	return scanResultMap, cursor, resultCount, err
+}
+
+func (s *RedisStorageManager) GetRecord(table, key string) (string, error) {
+	rslt, err := s.RedisClient.HGet(internal.Ctx(), table, key).Result()
+	if err == redis.Nil {
+		return "", internal.NotFoundError("record %s not found in %s", key, table)
+	} else if err != nil {
+		return "", internal.StorageUnavailableError(err)
+	}
+	return rslt, nil
+}
+
+func (s *RedisStorageManager) IncrementCounter(table, key string, delta int64) (int64, error) {
+	rslt, err := s.RedisClient.HIncrBy(internal.Ctx(), table, key, delta).Result()
+	if err != nil {
+		return 0, internal.StorageUnavailableError(err)
+	}
+	return rslt, nil
+}
+
+// SaveKeyIfAbsent saves key as a standalone redis key <table>:<key>, since fields in hash can not expire
+func (s *RedisStorageManager) SaveKeyIfAbsent(table, key string, ttl time.Duration) (bool, error) {
+	saved, err := s.RedisClient.SetNX(internal.Ctx(), table+":"+key, 1, ttl).Result()
+	if err != nil {
+		return false, internal.StorageUnavailableError(err)
+	}
+	return saved, nil
+}
+
+func (s *RedisStorageManager) DeleteExpiringKey(table, key string) (bool, error) {
+	deleted, err := s.RedisClient.Del(internal.Ctx(), table+":"+key).Result()
+	if err != nil {
+		return false, internal.StorageUnavailableError(err)
+	}
+	return deleted > 0, nil
+}
@@ -1,14 +1,19 @@
 package models
 
 import (
+	"bytes"
 	"regexp"
 	"strconv"
 
 	"github.com/valyala/fasthttp"
 )
 
-var ProxyRequestPathPattern = regexp.MustCompile(`(?m)/v([1-9]\d{0,3})/([\w-]+)/([\w-]+)/(.*)`)
+// ProxyRequestPathPattern matches /v<version>/<service_name><service_path>,
+// the service path may start with api key if the key is passed in path.
+var ProxyRequestPathPattern = regexp.MustCompile(`^/v([1-9]\d{0,3})/([\w-]+)(/.*)?$`)
 
+// RequestDetail contains parsed proxy request. ServicePath is the path after service name,
+// and it is split to api key and proxy request path after service loaded, since key locations are declared in service.
 type RequestDetail struct {
 	URI              *fasthttp.URI
 	Host             []byte
@@ -22,8 +27,10 @@ type RequestDetail struct {
 	Version          int
 	ServiceName      []byte
 	ServiceNameStr   string
+	ServicePath      []byte
 	ApiKey           []byte
 	ApiKeyStr        string
+	ApiKeyLocation   string
 	ProxyRequestPath []byte
 }
 
@@ -34,15 +41,14 @@ func ExtractCtxRequestDetail(ctx *fasthttp.RequestCtx) (*RequestDetail, error) {
 	detail.Host = ctx.Host()
 	detail.Path = ctx.URI().Path()
 
-	pathMatchResult := ProxyRequestPathPattern.FindAllSubmatch(detail.Path, -1)
-	if len(pathMatchResult) == 1 && len(pathMatchResult[0]) == 5 {
-		detail.Version, _ = strconv.Atoi(string(pathMatchResult[0][1]))
-		detail.ServiceName = pathMatchResult[0][2]
-		detail.ApiKey = pathMatchResult[0][3]
-		detail.ProxyRequestPath = pathMatchResult[0][4]
+	pathMatchResult := ProxyRequestPathPattern.FindSubmatch(detail.Path)
+	if len(pathMatchResult) == 4 {
+		detail.Version, _ = strconv.Atoi(string(pathMatchResult[1]))
+		detail.ServiceName = pathMatchResult[2]
+		detail.ServicePath = pathMatchResult[3]
+		detail.ProxyRequestPath = bytes.TrimPrefix(detail.ServicePath, []byte("/"))
 
 		detail.ServiceNameStr = string(detail.ServiceName)
-		detail.ApiKeyStr = string(detail.ApiKey)
 	}
 
 	detail.Method = string(ctx.Method())
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Error classes of failed upstream requests, which can be retried by retry policy
+const (
+	RetryOnConnectFailure = "connect_failure"
+	RetryOnReset          = "reset"
+	RetryOnTimeout        = "timeout"
+)
+
+// MaxRetryBackoffMs is the max backoff between attempts allowed in retry policy
+const MaxRetryBackoffMs = 10000
+
+// RetryMaxAttempts returns max attempts including the first request, default is 3
+func RetryMaxAttempts(p *RetryPolicy) int {
+	if p.MaxAttempts == 0 {
+		return 3
+	}
+	return int(p.MaxAttempts)
+}
+
+// RetryOnStatuses returns response statuses retried, default is 502, 503 and 504
+func RetryOnStatuses(p *RetryPolicy) []int32 {
+	if len(p.RetryOnStatuses) == 0 {
+		return []int32{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	}
+	return p.RetryOnStatuses
+}
+
+// RetryOnErrors returns error classes retried, default is connect_failure and reset
+func RetryOnErrors(p *RetryPolicy) []string {
+	if len(p.RetryOn) == 0 {
+		return []string{RetryOnConnectFailure, RetryOnReset}
+	}
+	return p.RetryOn
+}
+
+// IsIdempotentMethod checks whether requests of method can be retried safely, as defined in RFC 7231
+func IsIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// ValidateRetryPolicy checks values of retry policy, nil policy means requests are never retried
+func ValidateRetryPolicy(p *RetryPolicy) error {
+	if p == nil {
+		return nil
+	}
+	if p.MaxAttempts < 0 || p.MaxAttempts > 10 {
+		return fmt.Errorf("retry max_attempts should be between 1 and 10")
+	}
+	for _, status := range p.RetryOnStatuses {
+		if status < 500 || status > 599 {
+			return fmt.Errorf("retry_on_statuses should be 5xx statuses")
+		}
+	}
+	for _, class := range p.RetryOn {
+		if class != RetryOnConnectFailure && class != RetryOnReset && class != RetryOnTimeout {
+			return fmt.Errorf("retry_on should be connect_failure, reset or timeout")
+		}
+	}
+	if p.BaseBackoffMs < 0 || p.MaxBackoffMs < 0 || p.BaseBackoffMs > MaxRetryBackoffMs || p.MaxBackoffMs > MaxRetryBackoffMs {
+		return fmt.Errorf("retry base_backoff_ms and max_backoff_ms should be between 0 and %d", MaxRetryBackoffMs)
+	}
+	if p.MaxBackoffMs != 0 && p.BaseBackoffMs > p.MaxBackoffMs {
+		return fmt.Errorf("retry base_backoff_ms should not be longer than max_backoff_ms")
+	}
+	if p.BudgetPercent < 0 || p.BudgetPercent > 100 || p.BudgetMinRetries < 0 {
+		return fmt.Errorf("retry budget_percent should be between 0 and 100, and budget_min_retries should not be negative")
+	}
+	return nil
+}
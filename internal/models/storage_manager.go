@@ -1,60 +1,68 @@
 package models
 
 import (
-	"errors"
-
-	"github.com/go-redis/redis/v8"
-
-	"apron.network/gateway/internal"
+	"path"
+	"sort"
+	"strconv"
+	"time"
 )
 
-type StorageManager struct {
-	// TODO: more db support will be added
-	RedisClient *redis.Client
+// StorageManager defines operations for saving and loading records, which are grouped by table/bucket.
+// Redis, memory and bolt backends are supported, and the backend is selected by STORAGE_BACKEND env var.
+type StorageManager interface {
+	// IsKeyExisting checks whether the table/bucket exists and contains records
+	IsKeyExisting(key string) (bool, error)
+	IsKeyExistingInBucket(table, keyValue string) (bool, error)
+	SaveBinaryKeyData(table, keyVal string, content []byte) error
+	DeleteKey(table, keyVal string) error
+	// FetchRecords returns records matching pattern starts from cursor startIdx,
+	// the returned cursor should be passed to next call, and 0 means all records fetched.
+	FetchRecords(table string, startIdx int, pattern string, size int) (map[string]string, uint64, uint, error)
+	// GetRecord returns content of key in table, a not found error will be returned if key not existing
+	GetRecord(table, key string) (string, error)
+	// IncrementCounter adds delta to the integer value of key atomically and returns the new value,
+	// the value is saved as decimal string and starts from 0 if key not existing.
+	IncrementCounter(table, key string, delta int64) (int64, error)
+	// SaveKeyIfAbsent saves key which expires after ttl, false is returned if the key already exists and not expired.
+	// It is used as replay cache shared by gateway nodes.
+	SaveKeyIfAbsent(table, key string, ttl time.Duration) (bool, error)
+	// DeleteExpiringKey deletes key saved by SaveKeyIfAbsent, false is returned if the key not existing or expired.
+	// It is used to consume one-time challenges.
+	DeleteExpiringKey(table, key string) (bool, error)
 }
 
-func parseHscanResultToObjectMap(rcds []string) (map[string]string, uint, error) {
-	if len(rcds)%2 != 0 {
-		return nil, 0, errors.New("record length should be even number")
-	}
-
-	resultCount := uint(len(rcds) / 2)
-	rslt := make(map[string]string)
-	for idx := 0; idx < len(rcds); idx += 2 {
-		rslt[rcds[idx]] = rcds[idx+1]
-	}
-
-	return rslt, resultCount, nil
-}
-func (s *StorageManager) IsKeyExisting(key string) bool {
-	existing, err := s.RedisClient.Exists(internal.Ctx(), key).Result()
-	internal.CheckError(err)
-	return existing == 1
-}
+// expiringKeyPurgeInterval is the count of saved expiring keys between purging expired keys,
+// which is used by storage backends not supporting ttl natively.
+const expiringKeyPurgeInterval = 1024
 
-func (s *StorageManager) IsKeyExistingInBucket(table, keyValue string) bool {
-	existing, err := s.RedisClient.HExists(internal.Ctx(), table, keyValue).Result()
-	internal.CheckError(err)
-	return existing
+// isExpiringKeyAlive checks whether the expiry time saved as unix nanoseconds is after now
+func isExpiringKeyAlive(value string, now time.Time) bool {
+	expiredAt, err := strconv.ParseInt(value, 10, 64)
+	return err == nil && now.UnixNano() < expiredAt
 }
 
-func (s *StorageManager) SaveBinaryKeyData(table, keyVal string, content []byte) error {
-	_, err := s.RedisClient.HSet(internal.Ctx(), table, keyVal, content).Result()
-	return err
-}
+// paginateSortedKeys filters keys with glob pattern and returns keys in the page, and the cursor for next page.
+// It is used by storage backends which do not support cursor natively.
+func paginateSortedKeys(keys []string, startIdx int, pattern string, size int) ([]string, uint64) {
+	sort.Strings(keys)
 
-func (s *StorageManager) DeleteKey(table, keyVal string) error {
-	_, err := s.RedisClient.HDel(internal.Ctx(), table, keyVal).Result()
-	return err
-}
+	matchedKeys := keys
+	if pattern != "" {
+		matchedKeys = make([]string, 0, len(keys))
+		for _, k := range keys {
+			if matched, _ := path.Match(pattern, k); matched {
+				matchedKeys = append(matchedKeys, k)
+			}
+		}
+	}
 
-func (s *StorageManager) FetchRecords(table string, startIdx int, pattern string, size int) (map[string]string, uint64, uint, error) {
-	rcds, cursor, err := s.RedisClient.HScan(internal.Ctx(), table, uint64(startIdx), pattern, int64(size)).Result()
-	internal.CheckError(err)
-	scanResultMap, resultCount, err := parseHscanResultToObjectMap(rcds)
-	return scanResultMap, cursor, resultCount, err
-}
+	if startIdx >= len(matchedKeys) {
+		return []string{}, 0
+	}
 
-func (s *StorageManager) GetRecord(table, key string) (string, error) {
-	return s.RedisClient.HGet(internal.Ctx(), table, key).Result()
+	endIdx := startIdx + size
+	if size <= 0 || endIdx >= len(matchedKeys) {
+		return matchedKeys[startIdx:], 0
+	}
+	return matchedKeys[startIdx:endIdx], uint64(endIdx)
 }
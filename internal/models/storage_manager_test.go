@@ -0,0 +1,137 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"apron.network/gateway/internal"
+)
+
+// testStorageManager tests common operations of backend, and fastForward is used to wait keys expired
+func testStorageManager(t *testing.T, s StorageManager, fastForward func(time.Duration)) {
+	const table = "TestTable"
+
+	if existing, err := s.IsKeyExisting(table); err != nil || existing {
+		t.Fatalf("expected table not existing, got %v, %v", existing, err)
+	}
+
+	for _, k := range []string{"a1", "a2", "a3", "b1", "b2"} {
+		if err := s.SaveBinaryKeyData(table, k, []byte("value-"+k)); err != nil {
+			t.Fatalf("failed to save %s: %v", k, err)
+		}
+	}
+
+	if existing, err := s.IsKeyExisting(table); err != nil || !existing {
+		t.Errorf("expected table existing, got %v, %v", existing, err)
+	}
+	if existing, err := s.IsKeyExistingInBucket(table, "a1"); err != nil || !existing {
+		t.Errorf("expected key a1 existing, got %v, %v", existing, err)
+	}
+	if v, err := s.GetRecord(table, "b2"); err != nil || v != "value-b2" {
+		t.Errorf("expected value-b2, got %q, %v", v, err)
+	}
+	if _, err := s.GetRecord(table, "c1"); internal.ToGatewayError(err).Code != internal.ErrCodeNotFound {
+		t.Errorf("expected not found error, got %v", err)
+	}
+
+	// Fetch all records page by page
+	fetched := make(map[string]string)
+	cursor := 0
+	for {
+		rcds, nextCursor, count, err := s.FetchRecords(table, cursor, "", 2)
+		if err != nil {
+			t.Fatalf("failed to fetch records: %v", err)
+		}
+		if int(count) != len(rcds) {
+			t.Errorf("count %d mismatches with records %d", count, len(rcds))
+		}
+		for k, v := range rcds {
+			fetched[k] = v
+		}
+		if nextCursor == 0 {
+			break
+		}
+		cursor = int(nextCursor)
+	}
+	if len(fetched) != 5 || fetched["a3"] != "value-a3" {
+		t.Errorf("unexpected fetched records: %v", fetched)
+	}
+
+	rcds, _, _, err := s.FetchRecords(table, 0, "b*", 100)
+	if err != nil || len(rcds) != 2 {
+		t.Errorf("expected 2 records matching b*, got %v, %v", rcds, err)
+	}
+
+	for _, delta := range []int64{1, 5, -2} {
+		if _, err := s.IncrementCounter(table, "counter", delta); err != nil {
+			t.Fatalf("failed to increment counter: %v", err)
+		}
+	}
+	if v, err := s.GetRecord(table, "counter"); err != nil || v != "4" {
+		t.Errorf("expected counter 4, got %q, %v", v, err)
+	}
+	if err := s.DeleteKey(table, "counter"); err != nil {
+		t.Fatalf("failed to delete counter: %v", err)
+	}
+
+	// Expiring key can be saved again only after expired
+	for i, expected := range []bool{true, false} {
+		if saved, err := s.SaveKeyIfAbsent("test_nonces", "nonce", 100*time.Millisecond); err != nil || saved != expected {
+			t.Errorf("save %d: expected saved %v, got %v, %v", i, expected, saved, err)
+		}
+	}
+	fastForward(150 * time.Millisecond)
+	if saved, err := s.SaveKeyIfAbsent("test_nonces", "nonce", time.Minute); err != nil || !saved {
+		t.Errorf("expected expired key saved again, got %v, %v", saved, err)
+	}
+	for i, expected := range []bool{true, false} {
+		if deleted, err := s.DeleteExpiringKey("test_nonces", "nonce"); err != nil || deleted != expected {
+			t.Errorf("delete %d: expected deleted %v, got %v, %v", i, expected, deleted, err)
+		}
+	}
+
+	for k := range fetched {
+		if err := s.DeleteKey(table, k); err != nil {
+			t.Fatalf("failed to delete %s: %v", k, err)
+		}
+	}
+	if existing, err := s.IsKeyExisting(table); err != nil || existing {
+		t.Errorf("expected table not existing after all keys deleted, got %v, %v", existing, err)
+	}
+}
+
+func TestRedisStorageManager(t *testing.T) {
+	redisServer, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start redis: %v", err)
+	}
+	defer redisServer.Close()
+
+	testStorageManager(t, NewRedisStorageManager(redis.NewClient(&redis.Options{Addr: redisServer.Addr()})), redisServer.FastForward)
+}
+
+func TestMemoryStorageManager(t *testing.T) {
+	testStorageManager(t, NewMemoryStorageManager(), time.Sleep)
+}
+
+func TestBoltStorageManager(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bolt_storage_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewBoltStorageManager(filepath.Join(dir, "gateway.db"))
+	if err != nil {
+		t.Fatalf("failed to open bolt db: %v", err)
+	}
+	defer s.Close()
+
+	testStorageManager(t, s, time.Sleep)
+}
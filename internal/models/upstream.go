@@ -0,0 +1,53 @@
+package models
+
+import "fmt"
+
+// Load balancers of services with multiple upstream targets
+const (
+	LoadBalancerRoundRobin       = "round_robin"
+	LoadBalancerWeighted         = "weighted"
+	LoadBalancerLeastOutstanding = "least_outstanding"
+	LoadBalancerConsistentHash   = "consistent_hash"
+)
+
+// UpstreamTargets returns targets of service, base_url is the only target if upstreams not declared
+func UpstreamTargets(service *ApronService) []*UpstreamTarget {
+	if len(service.Upstreams) > 0 {
+		return service.Upstreams
+	}
+	return []*UpstreamTarget{{BaseUrl: service.BaseUrl, Weight: 1}}
+}
+
+// MaxUpstreamWeight is the max weight of upstream target, weights only matter relative to each other
+const MaxUpstreamWeight = 1000
+
+// UpstreamWeight returns weight of target, which is 1 if not set
+func UpstreamWeight(target *UpstreamTarget) int {
+	if target.Weight == 0 {
+		return 1
+	}
+	return int(target.Weight)
+}
+
+// ValidateUpstreams checks upstream targets and load balancer declared in service
+func ValidateUpstreams(service *ApronService) error {
+	seen := make(map[string]bool)
+	for _, target := range service.Upstreams {
+		if target.BaseUrl == "" {
+			return fmt.Errorf("base_url of upstream target is required")
+		}
+		if target.Weight < 0 || target.Weight > MaxUpstreamWeight {
+			return fmt.Errorf("weight of upstream target %s should be within 0..%d", target.BaseUrl, MaxUpstreamWeight)
+		}
+		if seen[target.BaseUrl] {
+			return fmt.Errorf("upstream target %s is duplicated", target.BaseUrl)
+		}
+		seen[target.BaseUrl] = true
+	}
+
+	switch service.LoadBalancer {
+	case "", LoadBalancerRoundRobin, LoadBalancerWeighted, LoadBalancerLeastOutstanding, LoadBalancerConsistentHash:
+		return nil
+	}
+	return fmt.Errorf("load_balancer should be round_robin, weighted, least_outstanding or consistent_hash")
+}
@@ -0,0 +1,17 @@
+package models
+
+import "fmt"
+
+// ValidateUpstreamTimeouts checks timeouts declared in service, nil timeouts means default timeouts are used
+func ValidateUpstreamTimeouts(t *UpstreamTimeouts) error {
+	if t == nil {
+		return nil
+	}
+	if t.ConnectMs < 0 || t.ReadMs < 0 || t.WriteMs < 0 || t.TotalMs < 0 {
+		return fmt.Errorf("timeouts connect_ms, read_ms, write_ms and total_ms should not be negative")
+	}
+	if t.TotalMs != 0 && t.ConnectMs > t.TotalMs {
+		return fmt.Errorf("timeouts connect_ms should not be longer than total_ms")
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dchest/blake2b"
+	"github.com/decred/base58"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// Key types of wallet accounts
+const (
+	WalletKeyTypeEd25519   = "ed25519"
+	WalletKeyTypeSecp256k1 = "secp256k1"
+)
+
+// AccessTokenPrefix distinguishes access tokens issued after wallet login from api keys
+const AccessTokenPrefix = "apron_at_"
+
+// GenerateAccessToken returns a new access token, which has the same format with api key except the prefix
+func GenerateAccessToken() (string, error) {
+	return generatePrefixedSecret(AccessTokenPrefix)
+}
+
+// IsWellFormedAccessToken checks prefix, length and checksum of token generated by GenerateAccessToken
+func IsWellFormedAccessToken(token string) bool {
+	return isWellFormedPrefixedSecret(AccessTokenPrefix, token)
+}
+
+// AccessTokenHint returns the non-secret beginning of access token
+func AccessTokenHint(token string) string {
+	return prefixedSecretHint(AccessTokenPrefix, token)
+}
+
+// WalletChallengeMessage returns the message signed by wallet to login to service
+func WalletChallengeMessage(service, account, nonce string) string {
+	return fmt.Sprintf("Sign in to Apron gateway\nService: %s\nAccount: %s\nNonce: %s", service, account, nonce)
+}
+
+// DecodeAccountId decodes 32 bytes account id from SS58 address or 0x prefixed hex
+func DecodeAccountId(account string) ([]byte, error) {
+	if strings.HasPrefix(account, "0x") {
+		accountId, err := hex.DecodeString(account[2:])
+		if err != nil || len(accountId) != 32 {
+			return nil, errors.New("hex account should be 32 bytes")
+		}
+		return accountId, nil
+	}
+
+	decoded := base58.Decode(account)
+	if len(decoded) < 35 {
+		return nil, errors.New("invalid ss58 address")
+	}
+	// Network prefix is 1 byte if less than 64, otherwise 2 bytes
+	prefixLength := 1
+	if decoded[0] >= 64 {
+		prefixLength = 2
+	}
+	if len(decoded) != prefixLength+32+2 {
+		return nil, errors.New("invalid ss58 address length")
+	}
+
+	payload := decoded[:prefixLength+32]
+	checksum := blake2b.Sum512(append([]byte("SS58PRE"), payload...))
+	if !bytes.Equal(checksum[:2], decoded[prefixLength+32:]) {
+		return nil, errors.New("invalid ss58 address checksum")
+	}
+	return decoded[prefixLength : prefixLength+32], nil
+}
+
+// VerifyWalletSignature checks whether the message is signed by the account.
+// Ed25519 account id is the public key, while secp256k1 account id is blake2b-256 of compressed public key.
+// Secp256k1 signature is r and s of blake2b-256 of message, optionally followed by recovery id.
+func VerifyWalletSignature(keyType, account string, publicKey []byte, message, signature []byte) error {
+	accountId, err := DecodeAccountId(account)
+	if err != nil {
+		return err
+	}
+
+	switch keyType {
+	case WalletKeyTypeEd25519:
+		if len(publicKey) == 0 {
+			publicKey = accountId
+		}
+		if !bytes.Equal(publicKey, accountId) {
+			return errors.New("public key mismatches with account")
+		}
+		if len(signature) != ed25519.SignatureSize || !ed25519.Verify(publicKey, message, signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case WalletKeyTypeSecp256k1:
+		pubKey, err := secp256k1.ParsePubKey(publicKey)
+		if err != nil {
+			return fmt.Errorf("invalid public key: %v", err)
+		}
+		if id := blake2b.Sum256(pubKey.SerializeCompressed()); !bytes.Equal(id[:], accountId) {
+			return errors.New("public key mismatches with account")
+		}
+		if len(signature) != 64 && len(signature) != 65 {
+			return errors.New("signature should be 64 or 65 bytes")
+		}
+		var r, s secp256k1.ModNScalar
+		if r.SetByteSlice(signature[:32]) || s.SetByteSlice(signature[32:64]) || r.IsZero() || s.IsZero() {
+			return errors.New("invalid signature")
+		}
+		hash := blake2b.Sum256(message)
+		if !ecdsa.NewSignature(&r, &s).Verify(hash[:], pubKey) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported key type %s, should be ed25519 or secp256k1", keyType)
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+
+	"github.com/dchest/blake2b"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+func TestDecodeAccountId(t *testing.T) {
+	alice := "d43593c715fdd31c61141abd04a99fd6822c8558854ccde39a5684e7a56da27d"
+	for _, account := range []string{"5GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKutQY", "0x" + alice} {
+		accountId, err := DecodeAccountId(account)
+		if err != nil || hex.EncodeToString(accountId) != alice {
+			t.Errorf("%s: expected %s, got %x %v", account, alice, accountId, err)
+		}
+	}
+	for _, account := range []string{"5GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKutQZ", "0x1234", "not an address"} {
+		if _, err := DecodeAccountId(account); err == nil {
+			t.Errorf("%s: expected invalid account", account)
+		}
+	}
+}
+
+func TestVerifyWalletSignature(t *testing.T) {
+	message := []byte(WalletChallengeMessage("service", "account", "nonce"))
+
+	edPublicKey, edPrivateKey, _ := ed25519.GenerateKey(rand.Reader)
+	edAccount := "0x" + hex.EncodeToString(edPublicKey)
+	edSignature := ed25519.Sign(edPrivateKey, message)
+	if err := VerifyWalletSignature(WalletKeyTypeEd25519, edAccount, nil, message, edSignature); err != nil {
+		t.Errorf("ed25519: expected valid signature, got %v", err)
+	}
+	if err := VerifyWalletSignature(WalletKeyTypeEd25519, edAccount, nil, []byte("other"), edSignature); err == nil {
+		t.Errorf("ed25519: expected invalid signature for other message")
+	}
+
+	secpPrivateKey, _ := secp256k1.GeneratePrivateKey()
+	secpPublicKey := secpPrivateKey.PubKey().SerializeCompressed()
+	secpAccountId := blake2b.Sum256(secpPublicKey)
+	secpAccount := "0x" + hex.EncodeToString(secpAccountId[:])
+	hash := blake2b.Sum256(message)
+	compact := ecdsa.SignCompact(secpPrivateKey, hash[:], true)
+	// Compact signature is recovery code followed by r and s, while wallets sign r, s and recovery id
+	secpSignature := append(compact[1:], compact[0]-31)
+	if err := VerifyWalletSignature(WalletKeyTypeSecp256k1, secpAccount, secpPublicKey, message, secpSignature); err != nil {
+		t.Errorf("secp256k1: expected valid signature, got %v", err)
+	}
+	if err := VerifyWalletSignature(WalletKeyTypeSecp256k1, secpAccount, secpPublicKey, message, secpSignature[:64]); err != nil {
+		t.Errorf("secp256k1: expected valid signature without recovery id, got %v", err)
+	}
+	if err := VerifyWalletSignature(WalletKeyTypeSecp256k1, edAccount, secpPublicKey, message, secpSignature); err == nil {
+		t.Errorf("secp256k1: expected public key mismatching with account")
+	}
+	if err := VerifyWalletSignature("sr25519", edAccount, nil, message, edSignature); err == nil {
+		t.Errorf("expected unsupported key type")
+	}
+}
+
+func TestGenerateAccessToken(t *testing.T) {
+	token, err := GenerateAccessToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsWellFormedAccessToken(token) || IsWellFormedApiKey(token) {
+		t.Errorf("expected well formed access token, got %s", token)
+	}
+	tampered := token[:len(token)-1] + "0"
+	if tampered == token {
+		tampered = token[:len(token)-1] + "1"
+	}
+	if IsWellFormedAccessToken(tampered) {
+		t.Errorf("expected checksum mismatch")
+	}
+}
@@ -34,6 +34,16 @@ func ServiceApiKeyStorageBucketName(service_id string) string {
 	return fmt.Sprintf("ApronApiKey:%s", service_id)
 }
 
+// ServiceApiKeyIdStorageBucketName returns table name saving mapping from key id to key hash of service
+func ServiceApiKeyIdStorageBucketName(service_id string) string {
+	return fmt.Sprintf("ApronApiKeyId:%s", service_id)
+}
+
+// QuotaStorageBucketName returns table name saving quota usage counters of service
+func QuotaStorageBucketName(service_id string) string {
+	return fmt.Sprintf("ApronQuota:%s", service_id)
+}
+
 // GenTimestamp ...
 func GenTimestamp() string {
 	time := time.Now().UnixNano() / 1e6
@@ -42,3 +52,24 @@ func GenTimestamp() string {
 
 const ServiceBucketName = "ApronService"
 const UserBucketName = "ApronUser"
+
+// HmacNonceBucketName saves nonces of signed requests, which expire after the allowed time skew
+const HmacNonceBucketName = "ApronHmacNonce"
+
+// WalletChallengeBucketName saves nonces issued for wallet login, which are consumed once signed
+const WalletChallengeBucketName = "ApronWalletChallenge"
+
+// AccessTokenBucketName saves hash of access tokens issued by wallet login, shared by all services
+const AccessTokenBucketName = "ApronAccessToken"
+
+// AdminTokenBucketName saves admin tokens with hash of token as key
+const AdminTokenBucketName = "ApronAdminToken"
+
+// AdminTokenIdBucketName saves mapping from admin token id to token hash
+const AdminTokenIdBucketName = "ApronAdminTokenId"
+
+// AuditLogBucketName saves hash-chained audit entries with sequence number as key
+const AuditLogBucketName = "ApronAuditLog"
+
+// AuditLogClaimBucketName saves sequence numbers of audit entries being appended, so each number is used by one writer
+const AuditLogClaimBucketName = "ApronAuditLogClaim"
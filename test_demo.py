@@ -1,4 +1,5 @@
 import json
+import os
 import time
 import typing
 import uuid
@@ -7,6 +8,7 @@ import requests
 
 api_url = 'http://localhost:8082'
 proxy_url = 'http://localhost:8080'
+admin_headers = {'Authorization': f'Bearer {os.environ.get("ADMIN_BOOTSTRAP_TOKEN", "")}'}
 
 
 def create_service(service_id, service_name: str, base_url: str, schema: str):
@@ -25,13 +27,13 @@ def create_service(service_id, service_name: str, base_url: str, schema: str):
         'service_price_plan': 'price',
         'service_declaimer': 'declaimer',
     }
-    r = requests.post(url, json=payload)
+    r = requests.post(url, json=payload, headers=admin_headers)
     assert r.status_code == 201
 
 
 def create_key(service_id: str) -> str:
     url = f'{api_url}/service/{service_id}/keys/'
-    r = requests.post(url, json={'account_id': 'foobar'})
+    r = requests.post(url, json={'account_id': 'foobar'}, headers=admin_headers)
     assert r.status_code == 200
 
     rslt = r.json()
@@ -48,7 +50,7 @@ def send_request(service_id: str, user_key: str, payload: typing.Dict):
 
 def fetch_usage_report():
     url = f'{api_url}/service/report/'
-    r = requests.get(url)
+    r = requests.get(url, headers=admin_headers)
     print(r.content)
     assert r.status_code == 200
     print(json.dumps(r.json(), indent=2))